@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"flag"
+	"os"
 
 	"github.com/HollyEllmo/my-first-go-project/internal/app"
 	"github.com/HollyEllmo/my-first-go-project/internal/config"
@@ -21,6 +23,14 @@ func main() {
    
 	ctx = logging.ContextWithLogger(ctx, logging.NewLogger())
 
+	// Flags of config are parsed, the rest is subcommand
+	if flag.Arg(0) == migrateCommand {
+		if err := runMigrate(ctx, cfg, flag.Args()[1:], os.Stdout); err != nil {
+			logging.Fatalln(ctx, err)
+		}
+		return
+	}
+
 	a, err := app.NewApp(ctx, cfg)
 	if err != nil {
 		logging.Fatalln(ctx, err)
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/internal/app"
+	"github.com/HollyEllmo/my-first-go-project/internal/config"
+	"github.com/HollyEllmo/my-first-go-project/pkg/migrate"
+)
+
+const migrateCommand = "migrate"
+
+const migrateUsage = `usage: app [-config path] migrate [-dry-run] <command>
+
+commands:
+  up [n]           apply n or all pending migrations
+  down [n]         revert n newest migrations, 1 by default
+  to <version>     apply or revert migrations so that version is the newest applied, 0 reverts all
+  status           list migrations and their state
+  force <version>  record migrations up to version as applied without running them, clears dirty state
+
+flags:
+`
+
+var errMigrateUsage = errors.New("bad migrate command")
+
+// runMigrate runs migrate subcommand, flags may follow command, e.g. `migrate up -dry-run`
+func runMigrate(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet(migrateCommand, flag.ContinueOnError)
+	flags.SetOutput(out)
+	dryRun := flags.Bool("dry-run", false, "print SQL of migrations instead of applying them")
+	flags.Usage = func() {
+		fmt.Fprint(out, migrateUsage)
+		flags.PrintDefaults()
+	}
+
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if args = flags.Args(); len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if len(positional) == 0 || len(positional) > 2 {
+		flags.Usage()
+		return errMigrateUsage
+	}
+	command, arg := positional[0], ""
+	if len(positional) == 2 {
+		arg = positional[1]
+	}
+
+	runner, closeConn, err := app.NewMigrationRunner(ctx, cfg, out)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+	runner.DryRun(*dryRun)
+
+	switch {
+	case command == "up" || command == "down":
+		steps := 0
+		if command == "down" {
+			steps = 1
+		}
+		if arg != "" {
+			if steps, err = strconv.Atoi(arg); err != nil || steps <= 0 {
+				return fmt.Errorf("%w: number of migrations must be positive, got `%s`", errMigrateUsage, arg)
+			}
+		}
+		if command == "up" {
+			return runner.Up(ctx, steps)
+		}
+		return runner.Down(ctx, steps)
+	case command == "to" || command == "force":
+		version, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %s requires version, got `%s`", errMigrateUsage, command, arg)
+		}
+		if command == "to" {
+			return runner.To(ctx, version)
+		}
+		return runner.Force(ctx, version)
+	case command == "status" && arg == "":
+		return printMigrateStatus(ctx, runner, out)
+	default:
+		flags.Usage()
+		return errMigrateUsage
+	}
+}
+
+func printMigrateStatus(ctx context.Context, runner *migrate.Runner, out io.Writer) error {
+	statuses, err := runner.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "-"
+		if !s.AppliedAt.IsZero() {
+			appliedAt = s.AppliedAt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%05d\t%s\t%s\t%s\n", s.Version, s.Name, s.State, appliedAt)
+	}
+	return w.Flush()
+}
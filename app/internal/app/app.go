@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"net"
 	"net/http"
-	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
@@ -14,25 +13,45 @@ import (
 	_ "github.com/HollyEllmo/my-first-go-project/docs"
 	"github.com/HollyEllmo/my-first-go-project/internal/config"
 	"github.com/HollyEllmo/my-first-go-project/internal/controller/grpc/v1/product"
+	adminHTTP "github.com/HollyEllmo/my-first-go-project/internal/controller/http/v1/admin"
+	productHTTP "github.com/HollyEllmo/my-first-go-project/internal/controller/http/v1/product"
+	promotionHTTP "github.com/HollyEllmo/my-first-go-project/internal/controller/http/v1/promotion"
+	promotionDAO "github.com/HollyEllmo/my-first-go-project/internal/domain/promotion/dao"
+	promotionService "github.com/HollyEllmo/my-first-go-project/internal/domain/promotion/service"
 	"github.com/HollyEllmo/my-first-go-project/internal/domain/pruduct/dao"
 	"github.com/HollyEllmo/my-first-go-project/internal/domain/pruduct/policy"
+	"github.com/HollyEllmo/my-first-go-project/internal/domain/pruduct/scheduler"
 	"github.com/HollyEllmo/my-first-go-project/internal/domain/pruduct/service"
+	"github.com/HollyEllmo/my-first-go-project/migrations"
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/jwt"
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/locale"
 	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
 	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
 	"github.com/HollyEllmo/my-first-go-project/pkg/metric"
+	"github.com/HollyEllmo/my-first-go-project/pkg/migrate"
+	"github.com/HollyEllmo/my-first-go-project/pkg/queue"
 	pb_prod_products "github.com/HollyEllmo/my-proto-repo/gen/go/prod_service/products/v1"
+	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
+	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
 	"github.com/julienschmidt/httprouter"
 	"github.com/rs/cors"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"golang.org/x/sync/errgroup"
 )
 
+// purgeJobsType is recurring job deleting finished jobs after retention period
+const purgeJobsType = "jobs.purge"
+
 type App struct {
 	cfg *config.Config
 	router *httprouter.Router
 	httpServer *http.Server
 	grpcServer *grpc.Server
 	pgClient postgresql.Client
+	locales *locale.Negotiator
+	authInterceptor *jwt.AuthInterceptor
+	productScheduler *scheduler.Scheduler
+	jobQueue *queue.Queue
 
 	productServiceServer pb_prod_products.ProductServiceServer
 }
@@ -49,24 +68,131 @@ func NewApp(ctx context.Context, config *config.Config) (App, error) {
 	metricHandler := metric.Handler{}
 	metricHandler.Register(router)
 
+	queryStats := postgresql.NewQueryStats()
 	pgConfig := postgresql.NewPgConfig(
 		config.PostgreSQL.Username, config.PostgreSQL.Password,
 		config.PostgreSQL.Host, config.PostgreSQL.Port, config.PostgreSQL.Database,
-	)
+	).
+		WithPool(postgresql.PoolConfig(config.PostgreSQL.Pool)).
+		WithTLS(postgresql.TLSConfig(config.PostgreSQL.TLS)).
+		WithConnect(postgresql.ConnectConfig(config.PostgreSQL.Connect)).
+		WithSession(postgresql.SessionConfig{
+			ApplicationName:  config.PostgreSQL.ApplicationName,
+			SearchPath:       config.PostgreSQL.SearchPath,
+			StatementTimeout: config.PostgreSQL.StatementTimeout,
+		}).
+		WithQueryHooks(queryStats)
+	if config.PostgreSQL.SlowQueryThreshold > 0 {
+		pgConfig.WithQueryHooks(postgresql.SlowQueryLog(config.PostgreSQL.SlowQueryThreshold))
+	}
+	if config.PostgreSQL.TraceQueries {
+		pgConfig.WithQueryHooks(postgresql.TraceQueries())
+	}
 
-	pgClient, err := postgresql.NewClient(ctx, 5, time.Second*5, pgConfig)
+	primaryClient, err := postgresql.NewClient(ctx, pgConfig)
 	if err != nil {
-		logging.WithError(ctx, err).Fatalln("failed to connect to PostgreSQL")
+		return App{}, err
+	}
+
+	// Schema of primary is compared with embedded migrations and columns expected by storages
+	knownMigrations, err := migrate.Load(migrations.FS)
+	if err != nil {
+		return App{}, err
+	}
+	schemaChecker := migrate.NewChecker(primaryClient, knownMigrations, dao.ProductSchema())
+	if err = checkSchema(ctx, config, schemaChecker); err != nil {
+		return App{}, err
+	}
+
+	replicas := make([]postgresql.Replica, 0, len(config.PostgreSQL.Replication.Replicas))
+	for _, addr := range config.PostgreSQL.Replication.Replicas {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return App{}, fmt.Errorf("bad PostgreSQL replica address %q: %w", addr, err)
+		}
+		replicaClient, err := postgresql.NewClient(ctx, pgConfig.Replica(host, port))
+		if err != nil {
+			return App{}, err
+		}
+		replicas = append(replicas, postgresql.Replica{Name: addr, Client: replicaClient})
+	}
+
+	// Reads marked by postgresql.ReadReplica go to replicas, user's reads stay on primary for a while after the user's writes.
+	// User is actor of token, reads parse token when request has one (see jwt.OptionalMiddleware).
+	pgClient := postgresql.NewReplicatedClient(ctx, primaryClient, replicas, postgresql.RoutingOptions{
+		ReadYourWrites:    config.PostgreSQL.Replication.ReadYourWrites,
+		HealthCheckPeriod: config.PostgreSQL.Replication.HealthCheckPeriod,
+		SessionKey: func(ctx context.Context) string {
+			return jwt.ActorFromContext(ctx, "")
+		},
+	})
+
+	// Transient errors are retried per statement outside transactions and per whole transaction inside
+	retryPolicy := postgresql.RetryPolicy(config.PostgreSQL.Retry)
+	retryStats := postgresql.NewRetryStats()
+
+	// Storages run queries in transaction of context started by txManager
+	txClient := postgresql.WithContextTx(postgresql.WithRetry(pgClient, retryPolicy, retryStats))
+	txManager := postgresql.NewTxManager(pgClient).WithRetry(retryPolicy, retryStats)
+
+	// Background jobs are enqueued in transaction of context, workers are started by Run
+	jobQueue := queue.NewQueue(txClient, queue.Options{
+		Workers:       config.AppConfig.Jobs.Workers,
+		PollInterval:  config.AppConfig.Jobs.PollInterval,
+		Timeout:       config.AppConfig.Jobs.Timeout,
+		MaxAttempts:   config.AppConfig.Jobs.MaxAttempts,
+		RetryDelay:    config.AppConfig.Jobs.RetryDelay,
+		MaxRetryDelay: config.AppConfig.Jobs.MaxRetryDelay,
+	})
+	if err = jobQueue.Register(queue.Type{
+		Name: purgeJobsType,
+		Cron: config.AppConfig.Jobs.PurgeCron,
+		Handle: func(ctx context.Context, job queue.Job) error {
+			n, err := jobQueue.Purge(ctx, config.AppConfig.Jobs.Retention)
+			if n > 0 {
+				logging.Infof(ctx, "purged %d finished jobs", n)
+			}
+			return err
+		},
+	}); err != nil {
+		return App{}, err
 	}
 
 	// Create the storage layer
-	productStorage := dao.NewProductStorage(pgClient)
+	productStorage := dao.NewProductStorage(txClient)
 
 	// Create the service layer
-	productService := service.NewProductService(productStorage)
+	productService := service.NewProductService(productStorage, txManager)
+
+	promotionStorage := promotionDAO.NewPromotionStorage(txClient)
+	pricingService := promotionService.NewPromotionService(promotionStorage, txManager)
+
+	locales := locale.NewNegotiator(config.AppConfig.Locale.Supported, config.AppConfig.Locale.Default)
 
 	// Create the policy layer
-	productPolicy := policy.NewProductPolicy(productService)
+	productPolicy := policy.NewProductPolicy(productService, pricingService, locales, config.AppConfig.EditorRoles)
+
+	logging.Infoln(ctx, "product HTTP handler initializing")
+	productHandler := productHTTP.NewHandler(productPolicy, config.AppConfig.JWT.Secret, config.AppConfig.EditorRoles)
+	productHandler.Register(router)
+
+	logging.Infoln(ctx, "promotion HTTP handler initializing")
+	promotionHandler := promotionHTTP.NewHandler(pricingService, config.AppConfig.JWT.Secret, config.AppConfig.EditorRoles)
+	promotionHandler.Register(router)
+
+	logging.Infoln(ctx, "admin HTTP handler initializing")
+	adminHandler := adminHTTP.NewHandler(queryStats, pgClient, retryStats, schemaChecker, jobQueue, config.AppConfig.JWT.Secret, config.AppConfig.EditorRoles)
+	adminHandler.Register(router)
+
+	// No gRPC method requires a role yet, token is parsed to recognize editors
+	authInterceptor := jwt.NewAuthInterceptor(jwt.NewHelper(config.AppConfig.JWT.Secret), map[string][]uint64{})
+
+	productScheduler := scheduler.NewScheduler(
+		config.AppConfig.Scheduler.Interval,
+		config.AppConfig.Scheduler.BatchSize,
+		scheduler.Task{Name: "status", Run: productPolicy.RunDueStatusSchedules},
+		scheduler.Task{Name: "price", Run: productPolicy.RunDuePriceSchedules},
+	)
 
 	// Create the gRPC server
 	productServiceServer := product.NewServer(
@@ -78,6 +204,10 @@ func NewApp(ctx context.Context, config *config.Config) (App, error) {
 		cfg: config,
 		router: router,
 		pgClient: pgClient,
+		locales: locales,
+		authInterceptor: authInterceptor,
+		productScheduler: productScheduler,
+		jobQueue: jobQueue,
 		productServiceServer: productServiceServer,
 	}, nil
 }
@@ -91,6 +221,12 @@ func (a *App) Run(ctx context.Context) error {
 	grp.Go(func() error {
 		return a.StartGRPC(ctx, a.productServiceServer)
 	})
+	grp.Go(func() error {
+		return a.productScheduler.Run(ctx)
+	})
+	grp.Go(func() error {
+		return a.jobQueue.Run(ctx)
+	})
 	return grp.Wait()
 }
 
@@ -107,7 +243,13 @@ func (a *App) StartGRPC(ctx context.Context, server pb_prod_products.ProductServ
 		logger.WithError(err).Fatalln("failed to listen on port")
 	}
 
-	serverOptions := []grpc.ServerOption{}
+	serverOptions := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_auth.UnaryServerInterceptor(a.authInterceptor.AuthorizeHandler),
+			a.locales.UnaryServerInterceptor(),
+		),
+	}
 	a.grpcServer = grpc.NewServer(serverOptions...)
 
 	pb_prod_products.RegisterProductServiceServer(a.grpcServer, server)
@@ -150,7 +292,7 @@ func (a *App) StartHTTP(ctx context.Context) error {
 		Debug:              a.cfg.HTTP.CORS.Debug,
 	})
 
-	handler := c.Handler(a.router)
+	handler := c.Handler(a.locales.Middleware(a.router))
 
 	a.httpServer = &http.Server{
 		Handler: handler,
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/HollyEllmo/my-first-go-project/internal/config"
+	"github.com/HollyEllmo/my-first-go-project/migrations"
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	"github.com/HollyEllmo/my-first-go-project/pkg/migrate"
+)
+
+// Modes of PostgreSQL.Schema.OnDrift
+const (
+	onDriftRefuse  = "refuse"
+	onDriftWarn    = "warn"
+	onDriftMigrate = "migrate"
+)
+
+// NewMigrationRunner opens dedicated connection to primary for embedded migrations, advisory lock is held by it.
+// Statement timeout isn't set because migrations may be long. closeConn releases the connection.
+func NewMigrationRunner(ctx context.Context, cfg *config.Config, out io.Writer) (runner *migrate.Runner, closeConn func(), err error) {
+	list, err := migrate.Load(migrations.FS)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := postgresql.Connect(ctx, postgresql.NewPgConfig(
+		cfg.PostgreSQL.Username, cfg.PostgreSQL.Password,
+		cfg.PostgreSQL.Host, cfg.PostgreSQL.Port, cfg.PostgreSQL.Database,
+	).
+		WithTLS(postgresql.TLSConfig(cfg.PostgreSQL.TLS)).
+		WithConnect(postgresql.ConnectConfig(cfg.PostgreSQL.Connect)).
+		WithSession(postgresql.SessionConfig{
+			ApplicationName: cfg.PostgreSQL.ApplicationName + "-migrate",
+			SearchPath:      cfg.PostgreSQL.SearchPath,
+		}))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return migrate.NewRunner(conn, list, out), func() { conn.Close(context.WithoutCancel(ctx)) }, nil
+}
+
+// checkSchema compares database with binary at startup. Depending on mode drift is logged,
+// fails startup or pending migrations are applied and any other drift fails startup.
+func checkSchema(ctx context.Context, cfg *config.Config, checker *migrate.Checker) error {
+	mode := cfg.PostgreSQL.Schema.OnDrift
+	if mode != onDriftRefuse && mode != onDriftWarn && mode != onDriftMigrate {
+		return fmt.Errorf("unknown schema on-drift mode %q, expected %s, %s or %s", mode, onDriftRefuse, onDriftWarn, onDriftMigrate)
+	}
+
+	drift, err := checker.Check(ctx)
+	if err != nil {
+		return fmt.Errorf("check database schema: %w", err)
+	}
+
+	if mode == onDriftMigrate && drift.Pending() {
+		logging.WithFields(ctx, map[string]interface{}{
+			"version": drift.Version,
+			"latest":  drift.Latest,
+		}).Warn("applying pending migrations")
+
+		if err = applyMigrations(ctx, cfg); err != nil {
+			return err
+		}
+		if drift, err = checker.Check(ctx); err != nil {
+			return fmt.Errorf("check database schema: %w", err)
+		}
+	}
+
+	if drift.Clean() {
+		logging.WithField(ctx, "version", drift.Version).Info("database schema is up to date")
+		return nil
+	}
+	if mode == onDriftWarn {
+		logging.WithError(ctx, drift.Err()).Warn("database schema drift, queries may fail")
+		return nil
+	}
+	return fmt.Errorf("database schema drift: %w", drift.Err())
+}
+
+func applyMigrations(ctx context.Context, cfg *config.Config) error {
+	out := logging.WithField(ctx, "component", "migrate").Writer()
+	defer out.Close()
+
+	runner, closeConn, err := NewMigrationRunner(ctx, cfg, out)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	return runner.Up(ctx, 0)
+}
@@ -39,6 +39,28 @@ type Config struct {
 			Email    string `yaml:"email" env:"ADMIN_EMAIL" env-default:"admin"`
 			Password string `yaml:"password" env:"ADMIN_PASSWORD" env-default:"admin"`
 		} `yaml:"admin"`
+		Locale struct {
+			Default   string   `yaml:"default" env:"LOCALE_DEFAULT" env-default:"ru"`
+			Supported []string `yaml:"supported" env:"LOCALE_SUPPORTED" env-default:"ru,en"`
+		} `yaml:"locale"`
+		JWT struct {
+			Secret string `yaml:"secret" env:"JWT_SECRET"`
+		} `yaml:"jwt"`
+		EditorRoles []uint64 `yaml:"editor-roles" env:"EDITOR_ROLES" env-description:"Role IDs allowed to see and change products in any status"`
+		Scheduler struct {
+			Interval time.Duration `yaml:"interval" env:"SCHEDULER_INTERVAL" env-default:"30s"`
+			BatchSize uint64 `yaml:"batch-size" env:"SCHEDULER_BATCH_SIZE" env-default:"100"`
+		} `yaml:"scheduler"`
+		Jobs struct {
+			Workers int `yaml:"workers" env:"JOBS_WORKERS" env-default:"4"`
+			PollInterval time.Duration `yaml:"poll-interval" env:"JOBS_POLL_INTERVAL" env-default:"1s" env-description:"Idle workers look for due jobs with this period"`
+			Timeout time.Duration `yaml:"timeout" env:"JOBS_TIMEOUT" env-default:"5m" env-description:"Default timeout of job handler"`
+			MaxAttempts int `yaml:"max-attempts" env:"JOBS_MAX_ATTEMPTS" env-default:"5"`
+			RetryDelay time.Duration `yaml:"retry-delay" env:"JOBS_RETRY_DELAY" env-default:"10s" env-description:"Delay after the first failed attempt, doubled by every next one"`
+			MaxRetryDelay time.Duration `yaml:"max-retry-delay" env:"JOBS_MAX_RETRY_DELAY" env-default:"1h"`
+			Retention time.Duration `yaml:"retention" env:"JOBS_RETENTION" env-default:"168h" env-description:"Finished jobs are purged after this period"`
+			PurgeCron string `yaml:"purge-cron" env:"JOBS_PURGE_CRON" env-default:"@hourly"`
+		} `yaml:"jobs"`
 	} `yaml:"app"`
 	PostgreSQL struct {
 		Username string `yaml:"username" env:"PSQL_USERNAME" env-required:"true"`
@@ -46,6 +68,43 @@ type Config struct {
 		Host     string `yaml:"host" env:"PSQL_HOST" env-required:"true"`
 		Port     string `yaml:"port" env:"PSQL_PORT" env-required:"true"`
 		Database string `yaml:"database" env:"PSQL_DATABASE" env-required:"true"`
+		SlowQueryThreshold time.Duration `yaml:"slow-query-threshold" env:"PSQL_SLOW_QUERY_THRESHOLD" env-default:"200ms" env-description:"Statements taking longer are logged, 0 disables the log"`
+		TraceQueries bool `yaml:"trace-queries" env:"PSQL_TRACE_QUERIES" env-default:"false" env-description:"Log every statement at trace level"`
+		ApplicationName string `yaml:"application-name" env:"PSQL_APPLICATION_NAME" env-default:"prod-service"`
+		SearchPath string `yaml:"search-path" env:"PSQL_SEARCH_PATH" env-description:"Schemas of search_path, server default when empty"`
+		StatementTimeout time.Duration `yaml:"statement-timeout" env:"PSQL_STATEMENT_TIMEOUT" env-default:"0s" env-description:"Default statement_timeout of connections, 0 disables it"`
+		Pool struct {
+			MinConns int32 `yaml:"min-conns" env:"PSQL_POOL_MIN_CONNS" env-default:"0"`
+			MaxConns int32 `yaml:"max-conns" env:"PSQL_POOL_MAX_CONNS" env-default:"10"`
+			MaxConnLifetime time.Duration `yaml:"max-conn-lifetime" env:"PSQL_POOL_MAX_CONN_LIFETIME" env-default:"1h"`
+			MaxConnIdleTime time.Duration `yaml:"max-conn-idle-time" env:"PSQL_POOL_MAX_CONN_IDLE_TIME" env-default:"30m"`
+			HealthCheckPeriod time.Duration `yaml:"health-check-period" env:"PSQL_POOL_HEALTH_CHECK_PERIOD" env-default:"1m"`
+		} `yaml:"pool"`
+		TLS struct {
+			Mode string `yaml:"mode" env:"PSQL_SSLMODE" env-default:"prefer" env-description:"libpq sslmode: disable, allow, prefer, require, verify-ca, verify-full"`
+			RootCert string `yaml:"root-cert" env:"PSQL_SSLROOTCERT"`
+			Cert string `yaml:"cert" env:"PSQL_SSLCERT"`
+			Key string `yaml:"key" env:"PSQL_SSLKEY"`
+		} `yaml:"tls"`
+		Connect struct {
+			Attempts int `yaml:"attempts" env:"PSQL_CONNECT_ATTEMPTS" env-default:"5"`
+			Timeout time.Duration `yaml:"timeout" env:"PSQL_CONNECT_TIMEOUT" env-default:"5s" env-description:"Timeout of a single connection attempt"`
+			InitialDelay time.Duration `yaml:"initial-delay" env:"PSQL_CONNECT_INITIAL_DELAY" env-default:"500ms"`
+			MaxDelay time.Duration `yaml:"max-delay" env:"PSQL_CONNECT_MAX_DELAY" env-default:"5s"`
+		} `yaml:"connect"`
+		Replication struct {
+			Replicas []string `yaml:"replicas" env:"PSQL_REPLICAS" env-description:"host:port of read replicas, credentials and database are the same as primary"`
+			ReadYourWrites time.Duration `yaml:"read-your-writes" env:"PSQL_READ_YOUR_WRITES" env-default:"5s" env-description:"Reads of user go to primary for this period after the user changed data"`
+			HealthCheckPeriod time.Duration `yaml:"health-check-period" env:"PSQL_REPLICA_HEALTH_CHECK_PERIOD" env-default:"5s"`
+		} `yaml:"replication"`
+		Retry struct {
+			Attempts int `yaml:"attempts" env:"PSQL_RETRY_ATTEMPTS" env-default:"3" env-description:"Attempts of statements and transactions failed with serialization failure, deadlock or lost connection, 1 disables retries"`
+			InitialDelay time.Duration `yaml:"initial-delay" env:"PSQL_RETRY_INITIAL_DELAY" env-default:"50ms"`
+			MaxDelay time.Duration `yaml:"max-delay" env:"PSQL_RETRY_MAX_DELAY" env-default:"1s"`
+		} `yaml:"retry"`
+		Schema struct {
+			OnDrift string `yaml:"on-drift" env:"PSQL_SCHEMA_ON_DRIFT" env-default:"warn" env-description:"What to do at startup when database schema differs from expected: refuse, warn or migrate (apply pending migrations, refuse on other drift)"`
+		} `yaml:"schema"`
 	} `yaml:"postgresql"`
 }
 
@@ -70,3 +70,9 @@ func NewUpdateProductDTOFromPB(product *pb_prod_products.UpdateProductRequest) *
 		Specification: spec,
 	}
 }
+
+type ProductTranslationDTO struct {
+	Locale      string
+	Name        string
+	Description string
+}
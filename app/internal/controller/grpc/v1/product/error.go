@@ -0,0 +1,61 @@
+package product
+
+import (
+	"github.com/HollyEllmo/my-first-go-project/internal/domain/pruduct/policy"
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusError converts policy and database errors into gRPC status,
+// constraint violations are returned as BadRequest field violations
+func statusError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	st := status.New(statusCode(err), err.Error())
+
+	violations := policy.Violations(err)
+	if len(violations) == 0 {
+		return st.Err()
+	}
+	badRequest := &errdetails.BadRequest{}
+	for _, v := range violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+	if detailed, detailsErr := st.WithDetails(badRequest); detailsErr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+func statusCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, policy.ErrUnsupportedLocale),
+		errors.Is(err, policy.ErrUnknownStatus),
+		errors.Is(err, policy.ErrScheduleInPast),
+		errors.Is(err, policy.ErrUnsupportedField),
+		errors.Is(err, postgresql.ErrCheckViolation):
+		return codes.InvalidArgument
+	case errors.Is(err, policy.ErrForbiddenTransition),
+		errors.Is(err, postgresql.ErrForeignKeyViolation):
+		return codes.FailedPrecondition
+	case errors.Is(err, policy.ErrPermissionDenied):
+		return codes.PermissionDenied
+	case errors.Is(err, policy.ErrProductNotFound),
+		errors.Is(err, postgresql.ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, postgresql.ErrAlreadyExists):
+		return codes.AlreadyExists
+	case errors.Is(err, postgresql.ErrConflict):
+		return codes.Aborted
+	default:
+		return codes.Internal
+	}
+}
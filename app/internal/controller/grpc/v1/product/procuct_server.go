@@ -5,20 +5,33 @@ import (
 
 	"github.com/HollyEllmo/my-first-go-project/internal/controller/dto"
 	"github.com/HollyEllmo/my-first-go-project/internal/domain/pruduct/model"
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/filter"
 	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
 	pb_prod_products "github.com/HollyEllmo/my-proto-repo/gen/go/prod_service/products/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 
 
 func (s *Server) AllProducts(ctx context.Context, req *pb_prod_products.AllProductsRequest) (*pb_prod_products.AllProductsResponse, error) {
 	logging.GetLogger().Warningf("ITS IS ALIVE !!!")
-	sort := model.ProductsSort(req)
-	filter := model.ProductsFilter(req)
+	sort, err := model.ProductsSort(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	filtering, err := model.ProductsFilter(req)
+	if err != nil {
+		return nil, filter.InvalidArgument(err)
+	}
+	// Query string of metadata is joined with per-field filters of request
+	if err = filter.AddIncomingMetadata(ctx, filtering); err != nil {
+		return nil, filter.InvalidArgument(err)
+	}
 
-	all, err := s.policy.All(ctx, filter, sort)
+	all, err := s.policy.All(ctx, filtering, sort)
 	if err != nil {
-		return  nil, err
+		return  nil, statusError(err)
 	}
 
 	pbProducts := make([]*pb_prod_products.Product, len(all))
@@ -34,7 +47,7 @@ func (s *Server) AllProducts(ctx context.Context, req *pb_prod_products.AllProdu
 func (s *Server) ProductByID(ctx context.Context, req *pb_prod_products.ProductByIDRequest) (*pb_prod_products.ProductByIDResponse, error) {
  one, err := s.policy.One(ctx, req.Id)
 	if err != nil {
-		return nil, err
+		return nil, statusError(err)
 	}
 
 	return &pb_prod_products.ProductByIDResponse{
@@ -47,7 +60,7 @@ func (s *Server) UpdateProduct(ctx context.Context, req *pb_prod_products.Update
 
 	err := s.policy.Update(ctx, req.Id, d)
 	if err != nil {
-		return nil, err
+		return nil, statusError(err)
 	}
 
 	return &pb_prod_products.UpdateProductResponse{}, nil
@@ -56,7 +69,7 @@ func (s *Server) UpdateProduct(ctx context.Context, req *pb_prod_products.Update
 func (s *Server) DeleteProduct(ctx context.Context, req *pb_prod_products.DeleteProductRequest) (*pb_prod_products.DeleteProductResponse, error) {
 	err := s.policy.Delete(ctx, req.Id)
 	if err != nil {
-		return nil, err
+		return nil, statusError(err)
 	}
 
 	return &pb_prod_products.DeleteProductResponse{}, nil
@@ -67,7 +80,7 @@ func (s *Server) CreateProduct(ctx context.Context, req *pb_prod_products.Create
 
 	product, err := s.policy.CreateProduct(ctx, d)
 	if err != nil {
-		return nil, err
+		return nil, statusError(err)
 	}
 
 	return &pb_prod_products.CreateProductResponse{
@@ -0,0 +1,181 @@
+package admin
+
+import (
+	"encoding/json"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	"github.com/HollyEllmo/my-first-go-project/pkg/migrate"
+	"github.com/HollyEllmo/my-first-go-project/pkg/queue"
+)
+
+type queryStatResponse struct {
+	Fingerprint string  `json:"fingerprint"`
+	Query       string  `json:"query"`
+	Calls       uint64  `json:"calls"`
+	Errors      uint64  `json:"errors"`
+	Rows        int64   `json:"rows"`
+	TotalMs     float64 `json:"total_ms"`
+	MeanMs      float64 `json:"mean_ms"`
+	MaxMs       float64 `json:"max_ms"`
+	LastCall    int64   `json:"last_call"`
+}
+
+type queryStatsResponse struct {
+	Queries []queryStatResponse `json:"queries"`
+	// Dropped is number of statements not counted because of fingerprints limit
+	Dropped uint64 `json:"dropped"`
+}
+
+func newQueryStatResponse(s postgresql.QueryStat) queryStatResponse {
+	return queryStatResponse{
+		Fingerprint: s.Fingerprint,
+		Query:       s.Query,
+		Calls:       s.Calls,
+		Errors:      s.Errors,
+		Rows:        s.Rows,
+		TotalMs:     float64(s.Total.Microseconds()) / 1000,
+		MeanMs:      float64(s.Mean().Microseconds()) / 1000,
+		MaxMs:       float64(s.Max.Microseconds()) / 1000,
+		LastCall:    s.LastCall.UnixMilli(),
+	}
+}
+
+type replicaStatusResponse struct {
+	Name      string `json:"name"`
+	Healthy   bool   `json:"healthy"`
+	Reads     uint64 `json:"reads"`
+	Errors    uint64 `json:"errors"`
+	CheckedAt int64  `json:"checked_at"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+type routingResponse struct {
+	Writes       uint64 `json:"writes"`
+	PrimaryReads uint64 `json:"primary_reads"`
+	ReplicaReads uint64 `json:"replica_reads"`
+	// Pinned is number of reads sent to primary after writes of the same user
+	Pinned    uint64 `json:"pinned"`
+	Failovers uint64 `json:"failovers"`
+	// Unkeyed is number of reads without user which read-your-writes can't pin
+	Unkeyed  uint64                  `json:"unkeyed"`
+	Replicas []replicaStatusResponse `json:"replicas"`
+}
+
+func newRoutingResponse(s postgresql.RoutingStats) routingResponse {
+	response := routingResponse{
+		Writes:       s.Writes,
+		PrimaryReads: s.PrimaryReads,
+		ReplicaReads: s.ReplicaReads,
+		Pinned:       s.Pinned,
+		Failovers:    s.Failovers,
+		Unkeyed:      s.Unkeyed,
+		Replicas:     make([]replicaStatusResponse, len(s.Replicas)),
+	}
+	for i, r := range s.Replicas {
+		response.Replicas[i] = replicaStatusResponse{
+			Name:      r.Name,
+			Healthy:   r.Healthy,
+			Reads:     r.Reads,
+			Errors:    r.Errors,
+			CheckedAt: r.CheckedAt.UnixMilli(),
+			LastError: r.LastError,
+		}
+	}
+	return response
+}
+
+type retriesResponse struct {
+	Retries map[string]uint64 `json:"retries"`
+	// Exhausted is number of operations failed after all attempts or when deadline left no time to retry
+	Exhausted map[string]uint64 `json:"exhausted"`
+}
+
+type migrationStatusResponse struct {
+	Version   uint64 `json:"version"`
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	AppliedAt int64  `json:"applied_at,omitempty"`
+}
+
+type columnDriftResponse struct {
+	Table    string `json:"table"`
+	Column   string `json:"column"`
+	Expected string `json:"expected"`
+	// Actual is empty when column is missing
+	Actual string `json:"actual,omitempty"`
+}
+
+type schemaResponse struct {
+	Clean bool `json:"clean"`
+	// Version is the newest applied migration, Latest is the newest migration known by binary
+	Version uint64 `json:"version"`
+	Latest  uint64 `json:"latest"`
+	// Migrations are pending, dirty, modified or missing
+	Migrations []migrationStatusResponse `json:"migrations"`
+	Columns    []columnDriftResponse     `json:"columns"`
+}
+
+func newSchemaResponse(d migrate.Drift) schemaResponse {
+	response := schemaResponse{
+		Clean:      d.Clean(),
+		Version:    d.Version,
+		Latest:     d.Latest,
+		Migrations: make([]migrationStatusResponse, len(d.Migrations)),
+		Columns:    make([]columnDriftResponse, len(d.Columns)),
+	}
+	for i, m := range d.Migrations {
+		response.Migrations[i] = migrationStatusResponse{
+			Version: m.Version,
+			Name:    m.Name,
+			State:   string(m.State),
+		}
+		if !m.AppliedAt.IsZero() {
+			response.Migrations[i].AppliedAt = m.AppliedAt.UnixMilli()
+		}
+	}
+	for i, c := range d.Columns {
+		response.Columns[i] = columnDriftResponse(c)
+	}
+	return response
+}
+
+type jobResponse struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	UniqueKey   string          `json:"unique_key,omitempty"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       int64           `json:"run_at"`
+	LockedBy    string          `json:"locked_by,omitempty"`
+	LockedUntil int64           `json:"locked_until,omitempty"`
+	LastError   string          `json:"last_error,omitempty"`
+	CreatedAt   int64           `json:"created_at"`
+	UpdatedAt   int64           `json:"updated_at"`
+	FinishedAt  int64           `json:"finished_at,omitempty"`
+}
+
+func newJobResponse(j queue.Job) jobResponse {
+	response := jobResponse{
+		ID:          j.ID,
+		Type:        j.Type,
+		Payload:     j.Payload,
+		Status:      string(j.Status),
+		UniqueKey:   j.UniqueKey.String,
+		Attempts:    j.Attempts,
+		MaxAttempts: j.MaxAttempts,
+		RunAt:       j.RunAt.UnixMilli(),
+		LockedBy:    j.LockedBy.String,
+		LastError:   j.LastError.String,
+		CreatedAt:   j.CreatedAt.UnixMilli(),
+		UpdatedAt:   j.UpdatedAt.UnixMilli(),
+	}
+	if j.LockedUntil.Valid {
+		response.LockedUntil = j.LockedUntil.Time.UnixMilli()
+	}
+	if j.FinishedAt.Valid {
+		response.FinishedAt = j.FinishedAt.Time.UnixMilli()
+	}
+	return response
+}
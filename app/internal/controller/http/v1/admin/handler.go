@@ -0,0 +1,145 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/jwt"
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	"github.com/HollyEllmo/my-first-go-project/pkg/migrate"
+	"github.com/HollyEllmo/my-first-go-project/pkg/queue"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	queryStatsURL = "/api/v1/admin/db/queries"
+	routingURL    = "/api/v1/admin/db/routing"
+	retriesURL    = "/api/v1/admin/db/retries"
+	schemaURL     = "/api/v1/admin/db/schema"
+	jobsURL       = "/api/v1/admin/jobs"
+	jobURL        = "/api/v1/admin/jobs/:id"
+	jobRetryURL   = "/api/v1/admin/jobs/:id/retry"
+	jobCancelURL  = "/api/v1/admin/jobs/:id/cancel"
+)
+
+type Handler struct {
+	queryStats  *postgresql.QueryStats
+	routing     *postgresql.ReplicatedClient
+	retries     *postgresql.RetryStats
+	schema      *migrate.Checker
+	jobs        *queue.Queue
+	jwtSecret   string
+	editorRoles []uint64
+}
+
+func NewHandler(queryStats *postgresql.QueryStats, routing *postgresql.ReplicatedClient, retries *postgresql.RetryStats, schema *migrate.Checker, jobs *queue.Queue, jwtSecret string, editorRoles []uint64) *Handler {
+	return &Handler{
+		queryStats:  queryStats,
+		routing:     routing,
+		retries:     retries,
+		schema:      schema,
+		jobs:        jobs,
+		jwtSecret:   jwtSecret,
+		editorRoles: editorRoles,
+	}
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, queryStatsURL, jwt.Middleware(h.QueryStats, h.jwtSecret, h.editorRoles...))
+	router.HandlerFunc(http.MethodDelete, queryStatsURL, jwt.Middleware(h.ResetQueryStats, h.jwtSecret, h.editorRoles...))
+	router.HandlerFunc(http.MethodGet, routingURL, jwt.Middleware(h.Routing, h.jwtSecret, h.editorRoles...))
+	router.HandlerFunc(http.MethodGet, retriesURL, jwt.Middleware(h.Retries, h.jwtSecret, h.editorRoles...))
+	router.HandlerFunc(http.MethodGet, schemaURL, jwt.Middleware(h.Schema, h.jwtSecret, h.editorRoles...))
+	router.HandlerFunc(http.MethodGet, jobsURL, jwt.Middleware(h.Jobs, h.jwtSecret, h.editorRoles...))
+	router.HandlerFunc(http.MethodGet, jobURL, jwt.Middleware(h.Job, h.jwtSecret, h.editorRoles...))
+	router.HandlerFunc(http.MethodPost, jobRetryURL, jwt.Middleware(h.RetryJob, h.jwtSecret, h.editorRoles...))
+	router.HandlerFunc(http.MethodPost, jobCancelURL, jwt.Middleware(h.CancelJob, h.jwtSecret, h.editorRoles...))
+}
+
+// QueryStats
+// @Summary Statistics of database statements per query fingerprint, slowest in total first
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} queryStatsResponse
+// @Failure 401
+// @Failure 403
+// @Router /api/v1/admin/db/queries [get]
+func (h *Handler) QueryStats(w http.ResponseWriter, r *http.Request) {
+	stats, dropped := h.queryStats.Snapshot()
+
+	response := queryStatsResponse{
+		Queries: make([]queryStatResponse, len(stats)),
+		Dropped: dropped,
+	}
+	for i, s := range stats {
+		response.Queries[i] = newQueryStatResponse(s)
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// ResetQueryStats
+// @Summary Clear statistics of database statements
+// @Tags Admin
+// @Success 204
+// @Failure 401
+// @Failure 403
+// @Router /api/v1/admin/db/queries [delete]
+func (h *Handler) ResetQueryStats(w http.ResponseWriter, r *http.Request) {
+	h.queryStats.Reset()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Routing
+// @Summary Counters of statements routed to primary and replicas, health of replicas
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} routingResponse
+// @Failure 401
+// @Failure 403
+// @Router /api/v1/admin/db/routing [get]
+func (h *Handler) Routing(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, newRoutingResponse(h.routing.Stats()))
+}
+
+// Retries
+// @Summary Retries of database operations failed with transient errors by reason
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} retriesResponse
+// @Failure 401
+// @Failure 403
+// @Router /api/v1/admin/db/retries [get]
+func (h *Handler) Retries(w http.ResponseWriter, r *http.Request) {
+	retries, exhausted := h.retries.Snapshot()
+	writeJSON(w, r, http.StatusOK, retriesResponse{Retries: retries, Exhausted: exhausted})
+}
+
+// Schema
+// @Summary Difference between database schema and schema expected by running binary
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} schemaResponse
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/admin/db/schema [get]
+func (h *Handler) Schema(w http.ResponseWriter, r *http.Request) {
+	drift, err := h.schema.Check(r.Context())
+	if err != nil {
+		logging.WithError(r.Context(), err).Error("failed to check database schema")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newSchemaResponse(drift))
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logging.WithError(r.Context(), err).Error("failed to encode response")
+	}
+}
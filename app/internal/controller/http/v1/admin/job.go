@@ -0,0 +1,151 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	"github.com/HollyEllmo/my-first-go-project/pkg/queue"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Jobs
+// @Summary List background jobs from the newest
+// @Tags Admin
+// @Produce json
+// @Param status query string false "pending, running, succeeded, failed or cancelled"
+// @Param type query string false "Job type"
+// @Param limit query int false "50 by default, at most 500"
+// @Param offset query int false "Offset"
+// @Success 200 {array} jobResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/admin/jobs [get]
+func (h *Handler) Jobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := queue.ListFilter{
+		Status: queue.Status(query.Get("status")),
+		Type:   query.Get("type"),
+	}
+	for name, target := range map[string]*uint64{"limit": &filter.Limit, "offset": &filter.Offset} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, errors.New("bad "+name))
+			return
+		}
+		*target = value
+	}
+
+	jobs, err := h.jobs.List(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := make([]jobResponse, len(jobs))
+	for i, job := range jobs {
+		response[i] = newJobResponse(job)
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// Job
+// @Summary Background job
+// @Tags Admin
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} jobResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /api/v1/admin/jobs/{id} [get]
+func (h *Handler) Job(w http.ResponseWriter, r *http.Request) {
+	h.jobAction(w, r, h.jobs.One)
+}
+
+// RetryJob
+// @Summary Queue failed or cancelled job again with reset attempts
+// @Tags Admin
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} jobResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /api/v1/admin/jobs/{id}/retry [post]
+func (h *Handler) RetryJob(w http.ResponseWriter, r *http.Request) {
+	h.jobAction(w, r, h.jobs.Retry)
+}
+
+// CancelJob
+// @Summary Cancel pending job
+// @Tags Admin
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} jobResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /api/v1/admin/jobs/{id}/cancel [post]
+func (h *Handler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	h.jobAction(w, r, h.jobs.Cancel)
+}
+
+// jobAction runs action with job id of path and writes resulting job
+func (h *Handler) jobAction(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, id string) (queue.Job, error)) {
+	id := httprouter.ParamsFromContext(r.Context()).ByName("id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(w, r, http.StatusBadRequest, errors.New("bad job id"))
+		return
+	}
+
+	job, err := action(r.Context(), id)
+	if err != nil {
+		writeError(w, r, jobStatusFromError(err), err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newJobResponse(job))
+}
+
+func jobStatusFromError(err error) int {
+	switch {
+	case errors.Is(err, postgresql.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, queue.ErrState),
+		errors.Is(err, queue.ErrDuplicate):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError hides details of server errors from client, they are only logged
+func writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	logging.WithError(r.Context(), err).Error("request failed")
+
+	message := err.Error()
+	if status >= http.StatusInternalServerError {
+		message = http.StatusText(status)
+	}
+	writeJSON(w, r, status, map[string]string{"error": message})
+}
@@ -0,0 +1,349 @@
+package product
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/internal/controller/dto"
+	promotion "github.com/HollyEllmo/my-first-go-project/internal/domain/promotion/model"
+	"github.com/HollyEllmo/my-first-go-project/internal/domain/pruduct/model"
+)
+
+type translationRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (r translationRequest) toDTO(locale string) *dto.ProductTranslationDTO {
+	return &dto.ProductTranslationDTO{
+		Locale:      locale,
+		Name:        r.Name,
+		Description: r.Description,
+	}
+}
+
+type translationResponse struct {
+	ProductID   string `json:"product_id"`
+	Locale      string `json:"locale"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CreatedAt   int64  `json:"created_at"`
+	UpdatedAt   int64  `json:"updated_at,omitempty"`
+}
+
+func newTranslationResponse(t *model.ProductTranslation) translationResponse {
+	var updatedAt int64
+	if t.UpdatedAt != nil {
+		updatedAt = t.UpdatedAt.UnixMilli()
+	}
+
+	return translationResponse{
+		ProductID:   t.ProductID,
+		Locale:      t.Locale,
+		Name:        t.Name,
+		Description: t.Description,
+		CreatedAt:   t.CreatedAt.UnixMilli(),
+		UpdatedAt:   updatedAt,
+	}
+}
+
+type statusRequest struct {
+	Status string `json:"status"`
+	// At schedules the change, status is changed immediately when empty
+	At *time.Time `json:"at,omitempty"`
+}
+
+type scheduleResponse struct {
+	ID         string  `json:"id"`
+	ProductID  string  `json:"product_id"`
+	Status     string  `json:"status"`
+	RunAt      int64   `json:"run_at"`
+	CreatedAt  int64   `json:"created_at"`
+	ExecutedAt int64   `json:"executed_at,omitempty"`
+	Error      *string `json:"error,omitempty"`
+}
+
+func newScheduleResponse(s *model.StatusSchedule) scheduleResponse {
+	var executedAt int64
+	if s.ExecutedAt != nil {
+		executedAt = s.ExecutedAt.UnixMilli()
+	}
+
+	return scheduleResponse{
+		ID:         s.ID,
+		ProductID:  s.ProductID,
+		Status:     s.Status,
+		RunAt:      s.RunAt.UnixMilli(),
+		CreatedAt:  s.CreatedAt.UnixMilli(),
+		ExecutedAt: executedAt,
+		Error:      s.Error,
+	}
+}
+
+type priceRequest struct {
+	Price uint64 `json:"price"`
+	// At schedules the change, price is changed immediately when empty
+	At *time.Time `json:"at,omitempty"`
+}
+
+type priceScheduleResponse struct {
+	ID         string  `json:"id"`
+	ProductID  string  `json:"product_id"`
+	Price      uint64  `json:"price"`
+	Actor      string  `json:"actor"`
+	RunAt      int64   `json:"run_at"`
+	CreatedAt  int64   `json:"created_at"`
+	ExecutedAt int64   `json:"executed_at,omitempty"`
+	Error      *string `json:"error,omitempty"`
+}
+
+func newPriceScheduleResponse(s *model.PriceSchedule) priceScheduleResponse {
+	var executedAt int64
+	if s.ExecutedAt != nil {
+		executedAt = s.ExecutedAt.UnixMilli()
+	}
+
+	return priceScheduleResponse{
+		ID:         s.ID,
+		ProductID:  s.ProductID,
+		Price:      s.Price,
+		Actor:      s.Actor,
+		RunAt:      s.RunAt.UnixMilli(),
+		CreatedAt:  s.CreatedAt.UnixMilli(),
+		ExecutedAt: executedAt,
+		Error:      s.Error,
+	}
+}
+
+type priceChangeResponse struct {
+	OldPrice  *uint64 `json:"old_price,omitempty"`
+	NewPrice  uint64  `json:"new_price"`
+	Actor     string  `json:"actor"`
+	Source    string  `json:"source"`
+	ChangedAt int64   `json:"changed_at"`
+}
+
+type priceStatisticsResponse struct {
+	Current    uint64 `json:"current"`
+	Min        uint64 `json:"min"`
+	Max        uint64 `json:"max"`
+	Min30d     uint64 `json:"min_30d"`
+	Max30d     uint64 `json:"max_30d"`
+	Changes30d uint64 `json:"changes_30d"`
+}
+
+type priceHistoryResponse struct {
+	Changes    []priceChangeResponse   `json:"changes"`
+	Statistics priceStatisticsResponse `json:"statistics"`
+}
+
+func newPriceHistoryResponse(h *model.PriceHistory) priceHistoryResponse {
+	changes := make([]priceChangeResponse, len(h.Changes))
+	for i, c := range h.Changes {
+		changes[i] = priceChangeResponse{
+			OldPrice:  c.OldPrice,
+			NewPrice:  c.NewPrice,
+			Actor:     c.Actor,
+			Source:    c.Source,
+			ChangedAt: c.ChangedAt.UnixMilli(),
+		}
+	}
+
+	return priceHistoryResponse{
+		Changes:    changes,
+		Statistics: priceStatisticsResponse(h.Statistics),
+	}
+}
+
+func newFacetsOptions(query url.Values) (model.FacetsOptions, error) {
+	var opts model.FacetsOptions
+
+	if raw := query.Get("price_bounds"); raw != "" {
+		for _, b := range strings.Split(raw, ",") {
+			bound, err := strconv.ParseUint(strings.TrimSpace(b), 10, 64)
+			if err != nil {
+				return opts, fmt.Errorf("wrong price bound:`%s`", b)
+			}
+			opts.PriceBounds = append(opts.PriceBounds, bound)
+		}
+	}
+
+	if raw := query.Get("spec_keys_limit"); raw != "" {
+		limit, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return opts, fmt.Errorf("wrong spec_keys_limit:`%s`", raw)
+		}
+		opts.SpecificationKeysLimit = limit
+	}
+
+	return opts, nil
+}
+
+type facetValueResponse struct {
+	Value string `json:"value"`
+	Count uint64 `json:"count"`
+}
+
+type priceBucketResponse struct {
+	From  *uint64 `json:"from,omitempty"`
+	To    *uint64 `json:"to,omitempty"`
+	Count uint64  `json:"count"`
+}
+
+type facetsResponse struct {
+	Categories        []facetValueResponse  `json:"categories"`
+	Prices            []priceBucketResponse `json:"prices"`
+	Ratings           []facetValueResponse  `json:"ratings"`
+	SpecificationKeys []facetValueResponse  `json:"specification_keys"`
+}
+
+func newFacetValuesResponse(values []model.FacetValue) []facetValueResponse {
+	response := make([]facetValueResponse, len(values))
+	for i, v := range values {
+		response[i] = facetValueResponse{Value: v.Value, Count: v.Count}
+	}
+	return response
+}
+
+func newFacetsResponse(f *model.Facets) facetsResponse {
+	prices := make([]priceBucketResponse, len(f.Prices))
+	for i, b := range f.Prices {
+		prices[i] = priceBucketResponse{From: b.From, To: b.To, Count: b.Count}
+	}
+
+	return facetsResponse{
+		Categories:        newFacetValuesResponse(f.Categories),
+		Prices:            prices,
+		Ratings:           newFacetValuesResponse(f.Ratings),
+		SpecificationKeys: newFacetValuesResponse(f.SpecificationKeys),
+	}
+}
+
+// maxDistinctTop ограничивает число значений в одном ответе DistinctValues
+const maxDistinctTop = 1000
+
+func parseTop(query url.Values) (uint64, error) {
+	raw := query.Get("top")
+	if raw == "" {
+		return 0, nil
+	}
+	top, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil || top == 0 || top > maxDistinctTop {
+		return 0, fmt.Errorf("wrong top:`%s`, must be from 1 to %d", raw, maxDistinctTop)
+	}
+	return top, nil
+}
+
+type countResponse struct {
+	Count uint64 `json:"count"`
+}
+
+type distinctValuesResponse struct {
+	Field     string               `json:"field"`
+	Values    []facetValueResponse `json:"values"`
+	Truncated bool                 `json:"truncated"`
+}
+
+func newDistinctValuesResponse(v *model.DistinctValues) distinctValuesResponse {
+	return distinctValuesResponse{
+		Field:     v.Field,
+		Values:    newFacetValuesResponse(v.Values),
+		Truncated: v.Truncated,
+	}
+}
+
+func parsePriceQuery(query url.Values) (time.Time, uint32, error) {
+	at := time.Now()
+	if raw := query.Get("at"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return at, 0, fmt.Errorf("wrong at:`%s`", raw)
+		}
+		at = parsed
+	}
+
+	quantity := uint64(1)
+	if raw := query.Get("quantity"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil || parsed == 0 {
+			return at, 0, fmt.Errorf("wrong quantity:`%s`", raw)
+		}
+		quantity = parsed
+	}
+
+	return at, uint32(quantity), nil
+}
+
+type productResponse struct {
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	Description    string  `json:"description"`
+	Locale         string  `json:"locale,omitempty"`
+	ImageID        *string `json:"image_id,omitempty"`
+	Price          uint64  `json:"price"`
+	EffectivePrice uint64  `json:"effective_price"`
+	CurrencyID     uint32  `json:"currency_id"`
+	Rating         uint32  `json:"rating"`
+	CategoryID     uint32  `json:"category_id"`
+	Specification  string  `json:"specification"`
+	Status         string  `json:"status"`
+	CreatedAt      int64   `json:"created_at"`
+	UpdatedAt      int64   `json:"updated_at,omitempty"`
+}
+
+func newProductResponse(p *model.Product) productResponse {
+	var updatedAt int64
+	if p.UpdatedAt != nil {
+		updatedAt = p.UpdatedAt.UnixMilli()
+	}
+
+	return productResponse{
+		ID:             p.ID,
+		Name:           p.Name,
+		Description:    p.Description,
+		Locale:         p.Locale,
+		ImageID:        p.ImageID,
+		Price:          p.Price,
+		EffectivePrice: p.EffectivePrice,
+		CurrencyID:     p.CurrencyID,
+		Rating:         p.Rating,
+		CategoryID:     p.CategoryID,
+		Specification:  p.Specification,
+		Status:         p.Status,
+		CreatedAt:      p.CreatedAt.UnixMilli(),
+		UpdatedAt:      updatedAt,
+	}
+}
+
+type priceResponse struct {
+	ProductID string   `json:"product_id"`
+	Quantity  uint32   `json:"quantity"`
+	At        int64    `json:"at"`
+	List      uint64   `json:"list"`
+	Effective uint64   `json:"effective"`
+	Applied   []string `json:"applied_promotions"`
+}
+
+func newPriceResponse(p *promotion.Price) priceResponse {
+	return priceResponse{
+		ProductID: p.ProductID,
+		Quantity:  p.Quantity,
+		At:        p.At.UnixMilli(),
+		List:      p.List,
+		Effective: p.Effective,
+		Applied:   p.Applied,
+	}
+}
+
+type fieldViolationResponse struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+type errorResponse struct {
+	Error      string                   `json:"error"`
+	Violations []fieldViolationResponse `json:"violations,omitempty"`
+}
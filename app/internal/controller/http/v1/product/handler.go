@@ -0,0 +1,487 @@
+package product
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/HollyEllmo/my-first-go-project/internal/domain/pruduct/model"
+	"github.com/HollyEllmo/my-first-go-project/internal/domain/pruduct/policy"
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/filter"
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/jwt"
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/query"
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	productsURL        = "/api/v1/products"
+	priceURL           = "/api/v1/products/:id/price"
+	priceHistoryURL    = "/api/v1/products/:id/price/history"
+	priceSchedulesURL  = "/api/v1/products/:id/price/schedules"
+	translationsURL    = "/api/v1/products/:id/translations"
+	translationURL     = "/api/v1/products/:id/translations/:locale"
+	statusURL          = "/api/v1/products/:id/status"
+	statusSchedulesURL = "/api/v1/products/:id/status/schedules"
+	facetsURL          = "/api/v1/facets/products"
+	countURL           = "/api/v1/count/products"
+	distinctURL        = "/api/v1/distinct/products/:field"
+)
+
+var (
+	productsQuery = query.Resource{
+		FilterFields: model.ProductsFilterFields(),
+		SortFields:   model.ProductsSortFields(),
+		DefaultLimit: 100,
+		MaxLimit:     1000,
+	}
+	facetsQuery = query.Resource{
+		FilterFields: model.ProductsFilterFields(),
+	}
+	countQuery = query.Resource{
+		FilterFields: model.ProductsFilterFields(),
+	}
+)
+
+type Handler struct {
+	policy      *policy.ProductPolicy
+	jwtSecret   string
+	editorRoles []uint64
+}
+
+func NewHandler(policy *policy.ProductPolicy, jwtSecret string, editorRoles []uint64) *Handler {
+	return &Handler{
+		policy:      policy,
+		jwtSecret:   jwtSecret,
+		editorRoles: editorRoles,
+	}
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.GET(productsURL, h.optionalAuth(productsQuery.Middleware(h.All)))
+	router.GET(priceURL, h.optionalAuth(h.Price))
+	router.HandlerFunc(http.MethodPut, priceURL, jwt.Middleware(h.ChangePrice, h.jwtSecret, h.editorRoles...))
+	router.HandlerFunc(http.MethodGet, priceHistoryURL, jwt.Middleware(h.PriceHistory, h.jwtSecret, h.editorRoles...))
+	router.HandlerFunc(http.MethodGet, priceSchedulesURL, jwt.Middleware(h.PriceSchedules, h.jwtSecret, h.editorRoles...))
+	router.GET(facetsURL, h.optionalAuth(facetsQuery.Middleware(h.Facets)))
+	router.GET(countURL, h.optionalAuth(countQuery.Middleware(h.Count)))
+	router.GET(distinctURL, h.optionalAuth(countQuery.Middleware(h.DistinctValues)))
+	router.GET(translationsURL, h.optionalAuth(h.Translations))
+	router.HandlerFunc(http.MethodPut, translationURL, jwt.Middleware(h.UpsertTranslation, h.jwtSecret, h.editorRoles...))
+	router.HandlerFunc(http.MethodPut, statusURL, jwt.Middleware(h.ChangeStatus, h.jwtSecret, h.editorRoles...))
+	router.HandlerFunc(http.MethodGet, statusSchedulesURL, jwt.Middleware(h.StatusSchedules, h.jwtSecret, h.editorRoles...))
+}
+
+// optionalAuth authenticates user when request has token, so reads of editor aren't restricted to published
+// products and go to primary after the editor's writes
+func (h *Handler) optionalAuth(next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		jwt.OptionalMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			next(w, r, params)
+		}, h.jwtSecret)(w, r)
+	}
+}
+
+// All
+// @Summary List products with list and effective prices
+// @Tags Products
+// @Produce json
+// @Param filter query []string false "Filter expression, e.g. `name eq \"red apple\" and (rating ge 4 or not price gt 100)`, several filters are joined with AND" collectionFormat(multi)
+// @Param filter_syntax query string false "Syntax of filters, `rsql` allows e.g. `name==foo*;price=gt=100,rating==5`" Enums(expression, rsql) default(expression)
+// @Param sort query string false "Comma separated sort fields, `-` prefix for descending order, `:nulls_first` or `:nulls_last` suffix, e.g. `-rating:nulls_last,specification.weight`"
+// @Param limit query int false "Limit, 100 by default, at most 1000"
+// @Param offset query int false "Offset"
+// @Param cursor query string false "Cursor of the next page from `Link` header, can't be used with offset"
+// @Success 200 {array} productResponse
+// @Header 200 {string} Link "URL of the next page"
+// @Failure 400
+// @Failure 500
+// @Router /api/v1/products [get]
+func (h *Handler) All(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	q, _ := query.FromContext(r.Context())
+
+	products, err := h.policy.All(r.Context(), q.Filter, q.Sort)
+	if err != nil {
+		writeError(w, r, statusFromError(err), err)
+		return
+	}
+
+	response := make([]productResponse, len(products))
+	for i, p := range products {
+		response[i] = newProductResponse(p)
+	}
+
+	query.SetNextLink(w, r, q, len(products))
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// Price
+// @Summary Effective unit price of product after promotions
+// @Tags Products
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param at query string false "RFC3339 time, now by default"
+// @Param quantity query int false "Quantity, 1 by default"
+// @Success 200 {object} priceResponse
+// @Failure 400
+// @Failure 404
+// @Failure 500
+// @Router /api/v1/products/{id}/price [get]
+func (h *Handler) Price(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	at, quantity, err := parsePriceQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	price, err := h.policy.EffectivePrice(r.Context(), params.ByName("id"), at, quantity)
+	if err != nil {
+		writeError(w, r, statusFromError(err), err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newPriceResponse(price))
+}
+
+// ChangePrice
+// @Summary Change product list price now or at a future time
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param price body priceRequest true "New list price"
+// @Success 204
+// @Success 202 {object} priceScheduleResponse
+// @Failure 400
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/products/{id}/price [put]
+func (h *Handler) ChangePrice(w http.ResponseWriter, r *http.Request) {
+	id := httprouter.ParamsFromContext(r.Context()).ByName("id")
+
+	var req priceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.At == nil {
+		if err := h.policy.ChangePrice(r.Context(), id, req.Price); err != nil {
+			writeError(w, r, statusFromError(err), err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	schedule, err := h.policy.SchedulePrice(r.Context(), id, req.Price, *req.At)
+	if err != nil {
+		writeError(w, r, statusFromError(err), err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusAccepted, newPriceScheduleResponse(schedule))
+}
+
+// PriceHistory
+// @Summary Product list price history with statistics
+// @Tags Products
+// @Produce json
+// @Param id path string true "Product ID"
+// @Success 200 {object} priceHistoryResponse
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/products/{id}/price/history [get]
+func (h *Handler) PriceHistory(w http.ResponseWriter, r *http.Request) {
+	id := httprouter.ParamsFromContext(r.Context()).ByName("id")
+
+	history, err := h.policy.PriceHistory(r.Context(), id)
+	if err != nil {
+		writeError(w, r, statusFromError(err), err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newPriceHistoryResponse(history))
+}
+
+// PriceSchedules
+// @Summary List scheduled price changes of product
+// @Tags Products
+// @Produce json
+// @Param id path string true "Product ID"
+// @Success 200 {array} priceScheduleResponse
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/products/{id}/price/schedules [get]
+func (h *Handler) PriceSchedules(w http.ResponseWriter, r *http.Request) {
+	id := httprouter.ParamsFromContext(r.Context()).ByName("id")
+
+	schedules, err := h.policy.PriceSchedules(r.Context(), id)
+	if err != nil {
+		writeError(w, r, statusFromError(err), err)
+		return
+	}
+
+	response := make([]priceScheduleResponse, len(schedules))
+	for i, s := range schedules {
+		response[i] = newPriceScheduleResponse(s)
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// Translations
+// @Summary List product translations
+// @Tags Products
+// @Produce json
+// @Param id path string true "Product ID"
+// @Success 200 {array} translationResponse
+// @Failure 404
+// @Failure 500
+// @Router /api/v1/products/{id}/translations [get]
+func (h *Handler) Translations(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	translations, err := h.policy.Translations(r.Context(), params.ByName("id"))
+	if err != nil {
+		writeError(w, r, statusFromError(err), err)
+		return
+	}
+
+	response := make([]translationResponse, len(translations))
+	for i, t := range translations {
+		response[i] = newTranslationResponse(t)
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// UpsertTranslation
+// @Summary Create or update product translation
+// @Tags Products
+// @Accept json
+// @Param id path string true "Product ID"
+// @Param locale path string true "Locale"
+// @Param translation body translationRequest true "Translation"
+// @Success 204
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/products/{id}/translations/{locale} [put]
+func (h *Handler) UpsertTranslation(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	var req translationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	err := h.policy.UpsertTranslation(r.Context(), params.ByName("id"), req.toDTO(params.ByName("locale")))
+	if err != nil {
+		writeError(w, r, statusFromError(err), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangeStatus
+// @Summary Change product status now or at a future time
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param status body statusRequest true "Target status"
+// @Success 204
+// @Success 202 {object} scheduleResponse
+// @Failure 400
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/products/{id}/status [put]
+func (h *Handler) ChangeStatus(w http.ResponseWriter, r *http.Request) {
+	id := httprouter.ParamsFromContext(r.Context()).ByName("id")
+
+	var req statusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.At == nil {
+		if err := h.policy.ChangeStatus(r.Context(), id, req.Status); err != nil {
+			writeError(w, r, statusFromError(err), err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	schedule, err := h.policy.ScheduleStatus(r.Context(), id, req.Status, *req.At)
+	if err != nil {
+		writeError(w, r, statusFromError(err), err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusAccepted, newScheduleResponse(schedule))
+}
+
+// StatusSchedules
+// @Summary List scheduled status changes of product
+// @Tags Products
+// @Produce json
+// @Param id path string true "Product ID"
+// @Success 200 {array} scheduleResponse
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/products/{id}/status/schedules [get]
+func (h *Handler) StatusSchedules(w http.ResponseWriter, r *http.Request) {
+	id := httprouter.ParamsFromContext(r.Context()).ByName("id")
+
+	schedules, err := h.policy.StatusSchedules(r.Context(), id)
+	if err != nil {
+		writeError(w, r, statusFromError(err), err)
+		return
+	}
+
+	response := make([]scheduleResponse, len(schedules))
+	for i, s := range schedules {
+		response[i] = newScheduleResponse(s)
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// Facets
+// @Summary Product counts per category, price bucket, rating and specification key
+// @Tags Products
+// @Produce json
+// @Param filter query []string false "Filter expression, e.g. `name eq \"red apple\" and (rating ge 4 or not price gt 100)`, several filters are joined with AND" collectionFormat(multi)
+// @Param filter_syntax query string false "Syntax of filters, `rsql` allows e.g. `name==foo*;price=gt=100,rating==5`" Enums(expression, rsql) default(expression)
+// @Param price_bounds query string false "Comma separated ascending price bucket boundaries"
+// @Param spec_keys_limit query int false "Max number of specification keys"
+// @Success 200 {object} facetsResponse
+// @Failure 400
+// @Failure 500
+// @Router /api/v1/facets/products [get]
+func (h *Handler) Facets(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	q, _ := query.FromContext(r.Context())
+
+	opts, err := newFacetsOptions(r.URL.Query())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	facets, err := h.policy.Facets(r.Context(), q.Filter, opts)
+	if err != nil {
+		writeError(w, r, statusFromError(err), err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newFacetsResponse(facets))
+}
+
+// Count
+// @Summary Number of products matching filters
+// @Tags Products
+// @Produce json
+// @Param filter query []string false "Filter expression, e.g. `name eq \"red apple\" and (rating ge 4 or not price gt 100)`, several filters are joined with AND" collectionFormat(multi)
+// @Param filter_syntax query string false "Syntax of filters, `rsql` allows e.g. `name==foo*;price=gt=100,rating==5`" Enums(expression, rsql) default(expression)
+// @Success 200 {object} countResponse
+// @Failure 400
+// @Failure 500
+// @Router /api/v1/count/products [get]
+func (h *Handler) Count(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	q, _ := query.FromContext(r.Context())
+
+	count, err := h.policy.Count(r.Context(), q.Filter)
+	if err != nil {
+		writeError(w, r, statusFromError(err), err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, countResponse{Count: count})
+}
+
+// DistinctValues
+// @Summary Distinct values of field among products matching filters, most frequent first
+// @Tags Products
+// @Produce json
+// @Param field path string true "Field" Enums(category_id, category.name, currency_id, currency.name, currency.symbol, rating, status)
+// @Param filter query []string false "Filter expression, e.g. `name eq \"red apple\" and (rating ge 4 or not price gt 100)`, several filters are joined with AND" collectionFormat(multi)
+// @Param filter_syntax query string false "Syntax of filters, `rsql` allows e.g. `name==foo*;price=gt=100,rating==5`" Enums(expression, rsql) default(expression)
+// @Param top query int false "Max number of values, all values by default"
+// @Success 200 {object} distinctValuesResponse
+// @Failure 400
+// @Failure 500
+// @Router /api/v1/distinct/products/{field} [get]
+func (h *Handler) DistinctValues(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	q, _ := query.FromContext(r.Context())
+
+	top, err := parseTop(r.URL.Query())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	values, err := h.policy.DistinctValues(r.Context(), q.Filter, params.ByName("field"), top)
+	if err != nil {
+		writeError(w, r, statusFromError(err), err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newDistinctValuesResponse(values))
+}
+
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, policy.ErrUnsupportedLocale),
+		errors.Is(err, policy.ErrUnknownStatus),
+		errors.Is(err, policy.ErrForbiddenTransition),
+		errors.Is(err, policy.ErrScheduleInPast),
+		errors.Is(err, policy.ErrUnsupportedField),
+		errors.Is(err, policy.ErrEmptyName),
+		errors.Is(err, postgresql.ErrCheckViolation):
+		return http.StatusBadRequest
+	case errors.Is(err, policy.ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, policy.ErrProductNotFound),
+		errors.Is(err, postgresql.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, postgresql.ErrAlreadyExists),
+		errors.Is(err, postgresql.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, postgresql.ErrForeignKeyViolation):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logging.WithError(r.Context(), err).Error("failed to encode response")
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	logging.WithError(r.Context(), err).Error("request failed")
+
+	response := errorResponse{Error: err.Error()}
+	for _, v := range filter.Violations(err) {
+		response.Violations = append(response.Violations, fieldViolationResponse{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+	for _, v := range policy.Violations(err) {
+		response.Violations = append(response.Violations, fieldViolationResponse{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+	writeJSON(w, r, status, response)
+}
@@ -0,0 +1,85 @@
+package promotion
+
+import (
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/internal/domain/promotion/model"
+)
+
+type createPromotionRequest struct {
+	Name        string     `json:"name"`
+	Kind        string     `json:"kind"`
+	Percent     float64    `json:"percent"`
+	Amount      uint64     `json:"amount"`
+	BuyQuantity uint32     `json:"buy_quantity"`
+	GetQuantity uint32     `json:"get_quantity"`
+	ProductID   *string    `json:"product_id"`
+	CategoryID  *uint32    `json:"category_id"`
+	Priority    int32      `json:"priority"`
+	Stackable   bool       `json:"stackable"`
+	StartsAt    *time.Time `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at"`
+}
+
+func (r createPromotionRequest) toDTO() *model.CreatePromotionDTO {
+	startsAt := time.Now()
+	if r.StartsAt != nil {
+		startsAt = *r.StartsAt
+	}
+
+	return &model.CreatePromotionDTO{
+		Name:        r.Name,
+		Kind:        r.Kind,
+		Percent:     r.Percent,
+		Amount:      r.Amount,
+		BuyQuantity: r.BuyQuantity,
+		GetQuantity: r.GetQuantity,
+		ProductID:   r.ProductID,
+		CategoryID:  r.CategoryID,
+		Priority:    r.Priority,
+		Stackable:   r.Stackable,
+		StartsAt:    startsAt,
+		EndsAt:      r.EndsAt,
+	}
+}
+
+type promotionResponse struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Kind        string  `json:"kind"`
+	Percent     float64 `json:"percent,omitempty"`
+	Amount      uint64  `json:"amount,omitempty"`
+	BuyQuantity uint32  `json:"buy_quantity,omitempty"`
+	GetQuantity uint32  `json:"get_quantity,omitempty"`
+	ProductID   *string `json:"product_id,omitempty"`
+	CategoryID  *uint32 `json:"category_id,omitempty"`
+	Priority    int32   `json:"priority"`
+	Stackable   bool    `json:"stackable"`
+	StartsAt    int64   `json:"starts_at"`
+	EndsAt      int64   `json:"ends_at,omitempty"`
+	CreatedAt   int64   `json:"created_at"`
+}
+
+func newPromotionResponse(p *model.Promotion) promotionResponse {
+	var endsAt int64
+	if p.EndsAt != nil {
+		endsAt = p.EndsAt.UnixMilli()
+	}
+
+	return promotionResponse{
+		ID:          p.ID,
+		Name:        p.Name,
+		Kind:        p.Kind,
+		Percent:     p.Percent,
+		Amount:      p.Amount,
+		BuyQuantity: p.BuyQuantity,
+		GetQuantity: p.GetQuantity,
+		ProductID:   p.ProductID,
+		CategoryID:  p.CategoryID,
+		Priority:    p.Priority,
+		Stackable:   p.Stackable,
+		StartsAt:    p.StartsAt.UnixMilli(),
+		EndsAt:      endsAt,
+		CreatedAt:   p.CreatedAt.UnixMilli(),
+	}
+}
@@ -0,0 +1,147 @@
+package promotion
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/internal/domain/promotion/service"
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/jwt"
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	promotionsURL = "/api/v1/promotions"
+	promotionURL  = "/api/v1/promotions/:id"
+)
+
+type Handler struct {
+	service     *service.Service
+	jwtSecret   string
+	editorRoles []uint64
+}
+
+func NewHandler(service *service.Service, jwtSecret string, editorRoles []uint64) *Handler {
+	return &Handler{
+		service:     service,
+		jwtSecret:   jwtSecret,
+		editorRoles: editorRoles,
+	}
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, promotionsURL, jwt.Middleware(h.All, h.jwtSecret, h.editorRoles...))
+	router.HandlerFunc(http.MethodPost, promotionsURL, jwt.Middleware(h.Create, h.jwtSecret, h.editorRoles...))
+	router.HandlerFunc(http.MethodDelete, promotionURL, jwt.Middleware(h.Delete, h.jwtSecret, h.editorRoles...))
+}
+
+// All
+// @Summary List promotions
+// @Tags Promotions
+// @Produce json
+// @Param active_at query string false "Only promotions active at RFC3339 time"
+// @Success 200 {array} promotionResponse
+// @Failure 400
+// @Failure 500
+// @Router /api/v1/promotions [get]
+func (h *Handler) All(w http.ResponseWriter, r *http.Request) {
+	var activeAt *time.Time
+	if raw := r.URL.Query().Get("active_at"); raw != "" {
+		at, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		activeAt = &at
+	}
+
+	promotions, err := h.service.All(r.Context(), activeAt)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := make([]promotionResponse, len(promotions))
+	for i, p := range promotions {
+		response[i] = newPromotionResponse(p)
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// Create
+// @Summary Create promotion
+// @Tags Promotions
+// @Accept json
+// @Produce json
+// @Param promotion body createPromotionRequest true "Promotion"
+// @Success 201 {object} promotionResponse
+// @Failure 400
+// @Failure 500
+// @Router /api/v1/promotions [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var req createPromotionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	promotion, err := h.service.Create(r.Context(), req.toDTO())
+	if err != nil {
+		writeError(w, r, statusFromError(err), err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, newPromotionResponse(promotion))
+}
+
+// Delete
+// @Summary Delete promotion
+// @Tags Promotions
+// @Param id path string true "Promotion ID"
+// @Success 204
+// @Failure 404
+// @Failure 500
+// @Router /api/v1/promotions/{id} [delete]
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	id := httprouter.ParamsFromContext(r.Context()).ByName("id")
+
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		writeError(w, r, statusFromError(err), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidPromotion),
+		errors.Is(err, postgresql.ErrCheckViolation),
+		errors.Is(err, postgresql.ErrForeignKeyViolation):
+		return http.StatusBadRequest
+	case errors.Is(err, postgresql.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, postgresql.ErrAlreadyExists),
+		errors.Is(err, postgresql.ErrConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logging.WithError(r.Context(), err).Error("failed to encode response")
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	logging.WithError(r.Context(), err).Error("request failed")
+	writeJSON(w, r, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,35 @@
+package dao
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// JoinEffectivePrice joins `alias.price` - effective unit price of product `productAlias` at time at.
+// It is the SQL version of service.Apply for quantity 1, so buy-N-get-M promotions are never applied.
+// Percentages are folded into factor in basis points by recursive CTE in order of priority and id
+// with the same integer rounding as service.Apply.
+func JoinEffectivePrice(query sq.SelectBuilder, productAlias, alias string, at time.Time) sq.SelectBuilder {
+	ts := at.UTC().Format(time.RFC3339)
+
+	return query.LeftJoin(
+		"LATERAL ("+
+			"WITH RECURSIVE applicable AS ("+
+			"SELECT pr.id, pr.kind, pr.percent, pr.amount, pr.priority, pr.stackable FROM "+tableScheme+" pr "+
+			"WHERE pr.kind <> 'buy_n_get_m' AND pr.starts_at <= ? AND (pr.ends_at IS NULL OR pr.ends_at > ?) "+
+			"AND (pr.product_id = "+productAlias+".id OR pr.category_id = "+productAlias+".category_id "+
+			"OR (pr.product_id IS NULL AND pr.category_id IS NULL))"+
+			"), top AS (SELECT * FROM applicable ORDER BY priority DESC, id LIMIT 1), "+
+			"chosen AS (SELECT a.* FROM applicable a, top "+
+			"WHERE (top.stackable AND a.stackable) OR (NOT top.stackable AND a.id = top.id)), "+
+			"percentage AS (SELECT row_number() OVER (ORDER BY priority DESC, id) AS n, "+
+			"LEAST(round(percent * 100)::bigint, 10000) AS bp FROM chosen WHERE kind = 'percentage'), "+
+			"factor AS (SELECT 0::bigint AS n, 10000::bigint AS bp UNION ALL "+
+			"SELECT f.n + 1, (f.bp * (10000 - pct.bp) + 5000) / 10000 FROM factor f JOIN percentage pct ON pct.n = f.n + 1) "+
+			"SELECT GREATEST(0, ("+productAlias+".price * (SELECT bp FROM factor ORDER BY n DESC LIMIT 1) + 5000) / 10000 "+
+			"- COALESCE((SELECT sum(amount) FROM chosen WHERE kind = 'fixed'), 0))::bigint AS price"+
+			") "+alias+" ON TRUE",
+		ts, ts,
+	)
+}
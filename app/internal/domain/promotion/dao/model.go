@@ -0,0 +1,79 @@
+package dao
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/internal/domain/promotion/model"
+	"github.com/google/uuid"
+)
+
+type PromotionStorage struct {
+	ID          string
+	Name        string
+	Kind        string
+	Percent     sql.NullFloat64
+	Amount      sql.NullInt64
+	BuyQuantity sql.NullInt32
+	GetQuantity sql.NullInt32
+	ProductID   sql.NullString
+	CategoryID  sql.NullInt32
+	Priority    int32
+	Stackable   bool
+	StartsAt    string
+	EndsAt      sql.NullString
+	CreatedAt   sql.NullString
+	UpdatedAt   sql.NullString
+}
+
+type CreatePromotionStorageDTO struct {
+	ID          string
+	Name        string
+	Kind        string
+	Percent     *float64
+	Amount      *uint64
+	BuyQuantity *uint32
+	GetQuantity *uint32
+	ProductID   *string
+	CategoryID  *uint32
+	Priority    int32
+	Stackable   bool
+	StartsAt    string
+	EndsAt      *string
+	CreatedAt   string
+	UpdatedAt   string
+}
+
+// NewCreatePromotionStorageDTO создает DTO для создания промоакции в хранилище.
+// Параметры, не относящиеся к виду промоакции, сохраняются как NULL.
+func NewCreatePromotionStorageDTO(dto *model.CreatePromotionDTO) *CreatePromotionStorageDTO {
+	storageDTO := &CreatePromotionStorageDTO{
+		ID:         uuid.New().String(),
+		Name:       dto.Name,
+		Kind:       dto.Kind,
+		ProductID:  dto.ProductID,
+		CategoryID: dto.CategoryID,
+		Priority:   dto.Priority,
+		Stackable:  dto.Stackable,
+		StartsAt:   dto.StartsAt.UTC().Format(time.RFC3339),
+		CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+		UpdatedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+
+	switch dto.Kind {
+	case model.KindPercentage:
+		storageDTO.Percent = &dto.Percent
+	case model.KindFixed:
+		storageDTO.Amount = &dto.Amount
+	case model.KindBuyNGetM:
+		storageDTO.BuyQuantity = &dto.BuyQuantity
+		storageDTO.GetQuantity = &dto.GetQuantity
+	}
+
+	if dto.EndsAt != nil {
+		endsAt := dto.EndsAt.UTC().Format(time.RFC3339)
+		storageDTO.EndsAt = &endsAt
+	}
+
+	return storageDTO
+}
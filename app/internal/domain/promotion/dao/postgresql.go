@@ -0,0 +1,248 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	db "github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql/model"
+	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	sq "github.com/Masterminds/squirrel"
+)
+
+type PromotionDAO struct {
+	queryBuilder sq.StatementBuilderType
+	client       PostgreSQLClient
+}
+
+func NewPromotionStorage(client PostgreSQLClient) *PromotionDAO {
+	return &PromotionDAO{
+		queryBuilder: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+		client:       client,
+	}
+}
+
+const (
+	scheme      = "public"
+	table       = "promotion"
+	tableScheme = scheme + "." + table
+)
+
+func (s *PromotionDAO) selectPromotions() sq.SelectBuilder {
+	return s.queryBuilder.
+		Select("id").
+		Columns(
+			"name",
+			"kind",
+			"percent::float8",
+			"amount",
+			"buy_quantity",
+			"get_quantity",
+			"product_id",
+			"category_id",
+			"priority",
+			"stackable",
+			"starts_at",
+			"ends_at",
+			"created_at",
+			"updated_at",
+		).
+		From(tableScheme)
+}
+
+// All возвращает промоакции. Если activeAt задан, только действующие в этот момент
+func (s *PromotionDAO) All(ctx context.Context, activeAt *time.Time) ([]*PromotionStorage, error) {
+	query := s.selectPromotions().OrderBy("priority DESC", "id")
+	if activeAt != nil {
+		query = query.Where(activeCondition(*activeAt))
+	}
+
+	return s.promotions(ctx, query)
+}
+
+// Applicable возвращает промоакции, действующие для продукта в момент at
+func (s *PromotionDAO) Applicable(ctx context.Context, productID string, categoryID uint32, at time.Time) ([]*PromotionStorage, error) {
+	query := s.selectPromotions().
+		Where(activeCondition(at)).
+		Where(sq.Or{
+			sq.Eq{"product_id": productID},
+			sq.Eq{"category_id": categoryID},
+			sq.Eq{"product_id": nil, "category_id": nil},
+		}).
+		OrderBy("priority DESC", "id")
+
+	return s.promotions(ctx, query)
+}
+
+func (s *PromotionDAO) Create(ctx context.Context, dto *CreatePromotionStorageDTO) error {
+	sql, args, buildErr := s.queryBuilder.
+		Insert(tableScheme).
+		Columns(
+			"id",
+			"name",
+			"kind",
+			"percent",
+			"amount",
+			"buy_quantity",
+			"get_quantity",
+			"product_id",
+			"category_id",
+			"priority",
+			"stackable",
+			"starts_at",
+			"ends_at",
+			"created_at",
+			"updated_at",
+		).Values(
+		dto.ID,
+		dto.Name,
+		dto.Kind,
+		dto.Percent,
+		dto.Amount,
+		dto.BuyQuantity,
+		dto.GetQuantity,
+		dto.ProductID,
+		dto.CategoryID,
+		dto.Priority,
+		dto.Stackable,
+		dto.StartsAt,
+		dto.EndsAt,
+		dto.CreatedAt,
+		dto.UpdatedAt,
+	).ToSql()
+
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": tableScheme,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if buildErr != nil {
+		buildErr = db.ErrCreateQuery(buildErr)
+		logger.Error(buildErr)
+		return buildErr
+	}
+
+	if exec, execErr := s.client.Exec(ctx, sql, args...); execErr != nil {
+		execErr = db.ErrDoQuery(execErr)
+		logger.Error(execErr)
+		return execErr
+	} else if exec.RowsAffected() == 0 || !exec.Insert() {
+		execErr = db.ErrDoQuery(errors.New("promotion was not created. 0 rows were affected"))
+		logger.Error(execErr)
+		return execErr
+	}
+
+	return nil
+}
+
+func (s *PromotionDAO) One(ctx context.Context, id string) (*PromotionStorage, error) {
+	list, err := s.promotions(ctx, s.selectPromotions().Where(sq.Eq{"id": id}))
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, db.ErrDoQuery(errors.New("promotion not found"))
+	}
+
+	return list[0], nil
+}
+
+func (s *PromotionDAO) Delete(ctx context.Context, id string) error {
+	sql, args, buildErr := s.queryBuilder.
+		Delete(tableScheme).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": tableScheme,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if buildErr != nil {
+		buildErr = db.ErrCreateQuery(buildErr)
+		logger.Error(buildErr)
+		return buildErr
+	}
+
+	if exec, execErr := s.client.Exec(ctx, sql, args...); execErr != nil {
+		execErr = db.ErrDoQuery(execErr)
+		logger.Error(execErr)
+		return execErr
+	} else if exec.RowsAffected() == 0 || !exec.Delete() {
+		execErr = db.ErrDoQuery(errors.Wrap(postgresql.ErrNotFound, "promotion was not deleted. 0 rows were affected"))
+		logger.Error(execErr)
+		return execErr
+	}
+
+	return nil
+}
+
+func activeCondition(at time.Time) sq.Sqlizer {
+	ts := at.UTC().Format(time.RFC3339)
+	return sq.And{
+		sq.LtOrEq{"starts_at": ts},
+		sq.Or{sq.Eq{"ends_at": nil}, sq.Gt{"ends_at": ts}},
+	}
+}
+
+func (s *PromotionDAO) promotions(ctx context.Context, query sq.SelectBuilder) ([]*PromotionStorage, error) {
+	sql, args, buildErr := query.ToSql()
+
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": tableScheme,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if buildErr != nil {
+		buildErr = db.ErrCreateQuery(buildErr)
+		logger.Error(buildErr)
+		return nil, buildErr
+	}
+
+	rows, err := s.client.Query(ctx, sql, args...)
+	if err != nil {
+		err = db.ErrDoQuery(err)
+		logger.Error(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	list := make([]*PromotionStorage, 0)
+
+	for rows.Next() {
+		ps := PromotionStorage{}
+		if err = rows.Scan(
+			&ps.ID,
+			&ps.Name,
+			&ps.Kind,
+			&ps.Percent,
+			&ps.Amount,
+			&ps.BuyQuantity,
+			&ps.GetQuantity,
+			&ps.ProductID,
+			&ps.CategoryID,
+			&ps.Priority,
+			&ps.Stackable,
+			&ps.StartsAt,
+			&ps.EndsAt,
+			&ps.CreatedAt,
+			&ps.UpdatedAt,
+		); err != nil {
+			err = db.ErrScan(err)
+			logger.Error(err)
+			return nil, err
+		}
+
+		list = append(list, &ps)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = db.ErrDoQuery(err)
+		logger.Error(err)
+		return nil, err
+	}
+
+	return list, nil
+}
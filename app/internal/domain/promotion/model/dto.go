@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type CreatePromotionDTO struct {
+	Name        string
+	Kind        string
+	Percent     float64
+	Amount      uint64
+	BuyQuantity uint32
+	GetQuantity uint32
+	ProductID   *string
+	CategoryID  *uint32
+	Priority    int32
+	Stackable   bool
+	StartsAt    time.Time
+	EndsAt      *time.Time
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	KindPercentage = "percentage"
+	KindFixed      = "fixed"
+	KindBuyNGetM   = "buy_n_get_m"
+)
+
+// BasisPointsPerUnit is 100% in basis points (hundredths of percent)
+const BasisPointsPerUnit = 10000
+
+// Promotion скидка на продукт, категорию или на все продукты, если область не задана
+type Promotion struct {
+	ID          string
+	Name        string
+	Kind        string
+	Percent     float64
+	Amount      uint64
+	BuyQuantity uint32
+	GetQuantity uint32
+	ProductID   *string
+	CategoryID  *uint32
+	Priority    int32
+	// Stackable promotions are combined with each other, otherwise only the top promotion is applied
+	Stackable bool
+	StartsAt  time.Time
+	EndsAt    *time.Time
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+}
+
+// BasisPoints returns Percent in basis points, percent column keeps two decimal places so it's exact
+func (p *Promotion) BasisPoints() uint64 {
+	return uint64(math.Round(p.Percent * 100))
+}
+
+// IsActive reports whether promotion time window contains at
+func (p *Promotion) IsActive(at time.Time) bool {
+	if at.Before(p.StartsAt) {
+		return false
+	}
+	return p.EndsAt == nil || at.Before(*p.EndsAt)
+}
+
+// Covers reports whether promotion scope contains the product
+func (p *Promotion) Covers(productID string, categoryID uint32) bool {
+	switch {
+	case p.ProductID != nil:
+		return *p.ProductID == productID
+	case p.CategoryID != nil:
+		return *p.CategoryID == categoryID
+	default:
+		return true
+	}
+}
+
+// Price цена продукта с учетом скидок
+type Price struct {
+	ProductID string
+	Quantity  uint32
+	At        time.Time
+	// List is unit price without promotions
+	List uint64
+	// Effective is unit price after promotions
+	Effective uint64
+	Applied   []string
+}
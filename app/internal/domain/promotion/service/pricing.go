@@ -0,0 +1,95 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/internal/domain/promotion/model"
+)
+
+// Apply вычисляет цену единицы товара по правилам:
+//   - учитываются только промоакции, применимые к количеству quantity
+//     (buy-N-get-M действует, начиная с N+M единиц);
+//   - промоакции упорядочиваются по priority по убыванию, затем по id;
+//   - если первая промоакция не stackable, применяется только она,
+//     иначе применяются все stackable промоакции;
+//   - проценты и buy-N-get-M перемножаются в порядке промоакций, множитель хранится
+//     в базисных пунктах и округляется после каждого шага;
+//   - цена умножается на множитель и округляется до целого, затем вычитается сумма
+//     фиксированных скидок, результат не бывает меньше нуля.
+//
+// Вычисления целочисленные, округление половины вверх, чтобы совпадать с SQL до единицы.
+//
+// Это же правило для quantity = 1 реализовано в SQL в dao.JoinEffectivePrice.
+func Apply(productID string, list uint64, promotions []*model.Promotion, at time.Time, quantity uint32) *model.Price {
+	if quantity == 0 {
+		quantity = 1
+	}
+
+	applicable := make([]*model.Promotion, 0, len(promotions))
+	for _, p := range promotions {
+		if p.Kind == model.KindBuyNGetM && quantity < p.BuyQuantity+p.GetQuantity {
+			continue
+		}
+		applicable = append(applicable, p)
+	}
+
+	price := &model.Price{
+		ProductID: productID,
+		Quantity:  quantity,
+		At:        at,
+		List:      list,
+		Effective: list,
+		Applied:   []string{},
+	}
+	if len(applicable) == 0 {
+		return price
+	}
+
+	sort.SliceStable(applicable, func(i, j int) bool {
+		if applicable[i].Priority != applicable[j].Priority {
+			return applicable[i].Priority > applicable[j].Priority
+		}
+		return applicable[i].ID < applicable[j].ID
+	})
+
+	chosen := applicable[:1]
+	if applicable[0].Stackable {
+		chosen = make([]*model.Promotion, 0, len(applicable))
+		for _, p := range applicable {
+			if p.Stackable {
+				chosen = append(chosen, p)
+			}
+		}
+	}
+
+	factor := uint64(model.BasisPointsPerUnit)
+	var fixed uint64
+	for _, p := range chosen {
+		switch p.Kind {
+		case model.KindPercentage:
+			discount := min(p.BasisPoints(), model.BasisPointsPerUnit)
+			factor = mulRound(factor, model.BasisPointsPerUnit-discount, model.BasisPointsPerUnit)
+		case model.KindFixed:
+			fixed += p.Amount
+		case model.KindBuyNGetM:
+			free := quantity / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+			factor = mulRound(factor, uint64(quantity-free), uint64(quantity))
+		}
+		price.Applied = append(price.Applied, p.ID)
+	}
+
+	effective := mulRound(list, factor, model.BasisPointsPerUnit)
+	if effective > fixed {
+		price.Effective = effective - fixed
+	} else {
+		price.Effective = 0
+	}
+
+	return price
+}
+
+// mulRound returns a*b/d rounded half up
+func mulRound(a, b, d uint64) uint64 {
+	return (a*b + d/2) / d
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/internal/domain/promotion/model"
+)
+
+func percentage(id string, percent float64, priority int32, stackable bool) *model.Promotion {
+	return &model.Promotion{ID: id, Kind: model.KindPercentage, Percent: percent, Priority: priority, Stackable: stackable}
+}
+
+func fixed(id string, amount uint64, priority int32, stackable bool) *model.Promotion {
+	return &model.Promotion{ID: id, Kind: model.KindFixed, Amount: amount, Priority: priority, Stackable: stackable}
+}
+
+func buyNGetM(id string, buy, get uint32, priority int32, stackable bool) *model.Promotion {
+	return &model.Promotion{ID: id, Kind: model.KindBuyNGetM, BuyQuantity: buy, GetQuantity: get, Priority: priority, Stackable: stackable}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name          string
+		list          uint64
+		quantity      uint32
+		promotions    []*model.Promotion
+		wantEffective uint64
+		wantApplied   []string
+	}{
+		{
+			name:          "no promotions",
+			list:          1000,
+			wantEffective: 1000,
+			wantApplied:   []string{},
+		},
+		{
+			name:          "percentage",
+			list:          999,
+			promotions:    []*model.Promotion{percentage("a", 15, 0, false)},
+			wantEffective: 849,
+			wantApplied:   []string{"a"},
+		},
+		{
+			name:          "fractional percentage",
+			list:          1000,
+			promotions:    []*model.Promotion{percentage("a", 12.34, 0, false)},
+			wantEffective: 877,
+			wantApplied:   []string{"a"},
+		},
+		{
+			name: "top non-stackable promotion wins",
+			list: 1000,
+			promotions: []*model.Promotion{
+				percentage("a", 50, 1, true),
+				percentage("b", 10, 5, false),
+				fixed("c", 100, 3, true),
+			},
+			wantEffective: 900,
+			wantApplied:   []string{"b"},
+		},
+		{
+			name: "stackable promotions ignore non-stackable ones",
+			list: 1000,
+			promotions: []*model.Promotion{
+				percentage("a", 10, 5, true),
+				percentage("b", 50, 3, false),
+				percentage("c", 20, 1, true),
+			},
+			wantEffective: 720,
+			wantApplied:   []string{"a", "c"},
+		},
+		{
+			name: "equal priority is ordered by id",
+			list: 1000,
+			promotions: []*model.Promotion{
+				percentage("b", 10, 1, false),
+				percentage("a", 20, 1, false),
+			},
+			wantEffective: 800,
+			wantApplied:   []string{"a"},
+		},
+		{
+			name: "percentages are multiplied before fixed amounts are subtracted",
+			list: 1000,
+			promotions: []*model.Promotion{
+				fixed("a", 50, 2, true),
+				percentage("b", 10, 1, true),
+			},
+			wantEffective: 850,
+			wantApplied:   []string{"a", "b"},
+		},
+		{
+			// множитель округляется после каждого шага: 10000 -> 6667 -> 4445 -> 2963
+			name: "factor is rounded in basis points",
+			list: 10001,
+			promotions: []*model.Promotion{
+				percentage("a", 33.33, 0, true),
+				percentage("b", 33.33, 0, true),
+				percentage("c", 33.33, 0, true),
+			},
+			wantEffective: 2963,
+			wantApplied:   []string{"a", "b", "c"},
+		},
+		{
+			name:          "hundred percent",
+			list:          1000,
+			promotions:    []*model.Promotion{percentage("a", 100, 0, true), fixed("b", 10, 0, true)},
+			wantEffective: 0,
+			wantApplied:   []string{"a", "b"},
+		},
+		{
+			name:          "fixed amount greater than price",
+			list:          100,
+			promotions:    []*model.Promotion{fixed("a", 150, 0, false)},
+			wantEffective: 0,
+			wantApplied:   []string{"a"},
+		},
+		{
+			name:          "buy-N-get-M below threshold",
+			list:          300,
+			quantity:      2,
+			promotions:    []*model.Promotion{buyNGetM("a", 2, 1, 0, false)},
+			wantEffective: 300,
+			wantApplied:   []string{},
+		},
+		{
+			name:          "buy-N-get-M",
+			list:          300,
+			quantity:      3,
+			promotions:    []*model.Promotion{buyNGetM("a", 2, 1, 0, false)},
+			wantEffective: 200,
+			wantApplied:   []string{"a"},
+		},
+		{
+			// 7 единиц: 2 бесплатно, 5/7 цены
+			name:          "buy-N-get-M with remainder",
+			list:          100,
+			quantity:      7,
+			promotions:    []*model.Promotion{buyNGetM("a", 2, 1, 0, false)},
+			wantEffective: 71,
+			wantApplied:   []string{"a"},
+		},
+		{
+			name:     "buy-N-get-M stacked with percentage",
+			list:     1000,
+			quantity: 4,
+			promotions: []*model.Promotion{
+				buyNGetM("a", 3, 1, 2, true),
+				percentage("b", 10, 1, true),
+			},
+			wantEffective: 675,
+			wantApplied:   []string{"a", "b"},
+		},
+		{
+			name:     "inapplicable buy-N-get-M doesn't block stacking",
+			list:     1000,
+			quantity: 1,
+			promotions: []*model.Promotion{
+				buyNGetM("a", 1, 1, 10, false),
+				percentage("b", 10, 1, true),
+			},
+			wantEffective: 900,
+			wantApplied:   []string{"b"},
+		},
+	}
+
+	at := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price := Apply("p", tt.list, tt.promotions, at, tt.quantity)
+
+			if price.Effective != tt.wantEffective {
+				t.Errorf("Apply() effective = %d, want %d", price.Effective, tt.wantEffective)
+			}
+			if !slices.Equal(price.Applied, tt.wantApplied) {
+				t.Errorf("Apply() applied = %v, want %v", price.Applied, tt.wantApplied)
+			}
+			if price.List != tt.list || price.Quantity != max(tt.quantity, 1) {
+				t.Errorf("Apply() list, quantity = %d, %d, want %d, %d", price.List, price.Quantity, tt.list, max(tt.quantity, 1))
+			}
+		})
+	}
+}
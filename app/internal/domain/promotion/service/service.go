@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/internal/domain/promotion/dao"
+	"github.com/HollyEllmo/my-first-go-project/internal/domain/promotion/model"
+	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
+)
+
+var ErrInvalidPromotion = errors.New("invalid promotion")
+
+// convertPromotionStorageToModel конвертирует PromotionStorage в модель Promotion
+func convertPromotionStorageToModel(ps *dao.PromotionStorage) *model.Promotion {
+	p := &model.Promotion{
+		ID:          ps.ID,
+		Name:        ps.Name,
+		Kind:        ps.Kind,
+		Percent:     ps.Percent.Float64,
+		Amount:      uint64(ps.Amount.Int64),
+		BuyQuantity: uint32(ps.BuyQuantity.Int32),
+		GetQuantity: uint32(ps.GetQuantity.Int32),
+		Priority:    ps.Priority,
+		Stackable:   ps.Stackable,
+		CreatedAt:   time.Now(),
+	}
+
+	if ps.ProductID.Valid {
+		p.ProductID = &ps.ProductID.String
+	}
+	if ps.CategoryID.Valid {
+		categoryID := uint32(ps.CategoryID.Int32)
+		p.CategoryID = &categoryID
+	}
+	if parsed, err := time.Parse(time.RFC3339, ps.StartsAt); err == nil {
+		p.StartsAt = parsed
+	}
+	if ps.EndsAt.Valid {
+		if parsed, err := time.Parse(time.RFC3339, ps.EndsAt.String); err == nil {
+			p.EndsAt = &parsed
+		}
+	}
+	if ps.CreatedAt.Valid {
+		if parsed, err := time.Parse(time.RFC3339, ps.CreatedAt.String); err == nil {
+			p.CreatedAt = parsed
+		}
+	}
+	if ps.UpdatedAt.Valid {
+		if parsed, err := time.Parse(time.RFC3339, ps.UpdatedAt.String); err == nil {
+			p.UpdatedAt = &parsed
+		}
+	}
+
+	return p
+}
+
+type repository interface {
+	All(ctx context.Context, activeAt *time.Time) ([]*dao.PromotionStorage, error)
+	Applicable(ctx context.Context, productID string, categoryID uint32, at time.Time) ([]*dao.PromotionStorage, error)
+	Create(ctx context.Context, dto *dao.CreatePromotionStorageDTO) error
+	One(ctx context.Context, id string) (*dao.PromotionStorage, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// transactor выполняет fn в транзакции, которую репозиторий берет из контекста
+type transactor interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type Service struct {
+	repository repository
+	tx         transactor
+}
+
+func NewPromotionService(repository repository, tx transactor) *Service {
+	return &Service{
+		repository: repository,
+		tx:         tx,
+	}
+}
+
+func (s *Service) All(ctx context.Context, activeAt *time.Time) ([]*model.Promotion, error) {
+	dbPromotions, err := s.repository.All(ctx, activeAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.All")
+	}
+
+	promotions := make([]*model.Promotion, len(dbPromotions))
+	for i, ps := range dbPromotions {
+		promotions[i] = convertPromotionStorageToModel(ps)
+	}
+
+	return promotions, nil
+}
+
+func (s *Service) Create(ctx context.Context, d *model.CreatePromotionDTO) (*model.Promotion, error) {
+	if err := validatePromotion(d); err != nil {
+		return nil, err
+	}
+
+	storageDTO := dao.NewCreatePromotionStorageDTO(d)
+
+	var one *dao.PromotionStorage
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		err := s.repository.Create(ctx, storageDTO)
+		if err != nil {
+			return errors.Wrap(err, "repository.Create")
+		}
+
+		one, err = s.repository.One(ctx, storageDTO.ID)
+		if err != nil {
+			return errors.Wrap(err, "repository.One")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return convertPromotionStorageToModel(one), nil
+}
+
+func (s *Service) Delete(ctx context.Context, id string) error {
+	return s.repository.Delete(ctx, id)
+}
+
+// EffectivePrice вычисляет цену единицы продукта в момент at при покупке quantity единиц
+func (s *Service) EffectivePrice(
+	ctx context.Context,
+	productID string,
+	categoryID uint32,
+	list uint64,
+	at time.Time,
+	quantity uint32,
+) (*model.Price, error) {
+	dbPromotions, err := s.repository.Applicable(ctx, productID, categoryID, at)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.Applicable")
+	}
+
+	promotions := make([]*model.Promotion, len(dbPromotions))
+	for i, ps := range dbPromotions {
+		promotions[i] = convertPromotionStorageToModel(ps)
+	}
+
+	return Apply(productID, list, promotions, at, quantity), nil
+}
+
+func validatePromotion(d *model.CreatePromotionDTO) error {
+	switch {
+	case d.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidPromotion)
+	case d.ProductID != nil && d.CategoryID != nil:
+		return fmt.Errorf("%w: promotion can target either product or category", ErrInvalidPromotion)
+	case d.EndsAt != nil && !d.EndsAt.After(d.StartsAt):
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidPromotion)
+	}
+
+	switch d.Kind {
+	case model.KindPercentage:
+		if d.Percent <= 0 || d.Percent > 100 {
+			return fmt.Errorf("%w: percent must be in (0, 100]", ErrInvalidPromotion)
+		}
+	case model.KindFixed:
+		if d.Amount == 0 {
+			return fmt.Errorf("%w: amount must be positive", ErrInvalidPromotion)
+		}
+	case model.KindBuyNGetM:
+		if d.BuyQuantity == 0 || d.GetQuantity == 0 {
+			return fmt.Errorf("%w: buy and get quantities must be positive", ErrInvalidPromotion)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind `%s`", ErrInvalidPromotion, d.Kind)
+	}
+
+	return nil
+}
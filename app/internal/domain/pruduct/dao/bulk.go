@@ -0,0 +1,234 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/filter"
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	db "github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql/model"
+	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	bulkUpdate = "update"
+	bulkDelete = "delete"
+)
+
+var (
+	// ErrEmptyBulkFilter защищает от изменения всех продуктов фильтром без условий
+	ErrEmptyBulkFilter = errors.New("bulk operation requires filter")
+	// ErrTooManyAffected возвращается, когда операция затронула бы больше BulkOptions.MaxAffected продуктов
+	ErrTooManyAffected = errors.New("too many products would be affected")
+	// ErrBulkColumn возвращается для колонок, которых нет в bulkUpdatableColumns
+	ErrBulkColumn = errors.New("column can't be updated in bulk")
+)
+
+// bulkUpdatableColumns колонки ProductSchema, которые можно менять в UpdateWhere:
+// id и created_at неизменны, updated_at проставляется автоматически
+func bulkUpdatableColumns() []string {
+	var columns []string
+	for _, column := range ProductSchema().Columns {
+		switch column.Name {
+		case "id", "created_at", "updated_at":
+			continue
+		}
+		columns = append(columns, column.Name)
+	}
+	return columns
+}
+
+// UpdateWhere обновляет колонки всех продуктов, подходящих под фильтр, одним запросом.
+// Значения m могут ссылаться на текущие колонки через sq.Expr, например `p.price * 1.1`.
+// Ключи m подставляются в SQL как имена колонок, поэтому допускаются только bulkUpdatableColumns.
+// Лимит и смещение фильтра не учитываются.
+func (s *ProductDAO) UpdateWhere(
+	ctx context.Context,
+	filtering filter.Filterable,
+	m map[string]interface{},
+	opts BulkOptions,
+) (*BulkResultStorage, error) {
+	allowed := bulkUpdatableColumns()
+	set := make(map[string]interface{}, len(m)+1)
+	set["updated_at"] = time.Now().UTC().Format(time.RFC3339)
+	for _, column := range slices.Sorted(maps.Keys(m)) {
+		if !slices.Contains(allowed, column) {
+			return nil, fmt.Errorf("%w: `%s`", ErrBulkColumn, column)
+		}
+		set[column] = m[column]
+	}
+
+	return s.bulk(ctx, bulkUpdate, filtering, opts, func(scope sq.Sqlizer) sq.Sqlizer {
+		return s.queryBuilder.
+			Update(tableScheme + " AS " + tableAlias).
+			SetMap(set).
+			Where(scope).
+			Suffix("RETURNING " + tableAlias + ".id")
+	})
+}
+
+// DeleteWhere удаляет все продукты, подходящие под фильтр, одним запросом.
+// Лимит и смещение фильтра не учитываются.
+func (s *ProductDAO) DeleteWhere(
+	ctx context.Context,
+	filtering filter.Filterable,
+	opts BulkOptions,
+) (*BulkResultStorage, error) {
+	return s.bulk(ctx, bulkDelete, filtering, opts, func(scope sq.Sqlizer) sq.Sqlizer {
+		return s.queryBuilder.
+			Delete(tableScheme + " AS " + tableAlias).
+			Where(scope).
+			Suffix("RETURNING " + tableAlias + ".id")
+	})
+}
+
+// bulk выполняет операцию над продуктами из scope фильтра в транзакции и пишет результат в аудит.
+// Фильтр применяется так же, как в All: с переводами, эффективной ценой и связанными таблицами.
+func (s *ProductDAO) bulk(
+	ctx context.Context,
+	operation string,
+	filtering filter.Filterable,
+	opts BulkOptions,
+	build func(scope sq.Sqlizer) sq.Sqlizer,
+) (*BulkResultStorage, error) {
+	if filtering.Expression() == nil {
+		return nil, ErrEmptyBulkFilter
+	}
+
+	filterDB := db.NewFilters(filtering).
+		WithExpressions(productExpressions()).
+		WithRelations(productRelations())
+	from := fromFiltered(ctx, sq.Select(tableAlias+".id"), filtering)
+	scope := filterDB.Scope(from, tableAlias, "id")
+
+	var (
+		result *BulkResultStorage
+		err    error
+	)
+	if opts.DryRun {
+		result, err = s.countScope(ctx, scope)
+	} else {
+		result, err = s.execScope(ctx, build(scope), opts.MaxAffected)
+	}
+	if err == nil && opts.MaxAffected > 0 && result.Affected > opts.MaxAffected {
+		err = fmt.Errorf("%w: %d of %d allowed", ErrTooManyAffected, result.Affected, opts.MaxAffected)
+	}
+
+	audit := logging.WithFields(ctx, map[string]interface{}{
+		"audit":        "product_bulk_" + operation,
+		"filter":       describeFilter(filtering),
+		"dry_run":      opts.DryRun,
+		"max_affected": opts.MaxAffected,
+	})
+	if result != nil {
+		audit = audit.WithFields(map[string]interface{}{
+			"affected": result.Affected,
+			"ids":      result.IDs,
+		})
+	}
+	if err != nil {
+		audit.WithError(err).Warn("bulk operation was not applied")
+		return result, err
+	}
+	audit.Info("bulk operation applied")
+
+	return result, nil
+}
+
+// countScope считает продукты scope без изменений
+func (s *ProductDAO) countScope(ctx context.Context, scope sq.Sqlizer) (*BulkResultStorage, error) {
+	sql, args, buildErr := s.queryBuilder.
+		Select("count(*)").
+		From(tableScheme + " " + tableAlias).
+		Where(scope).
+		ToSql()
+
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": tableScheme,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if buildErr != nil {
+		buildErr = db.ErrCreateQuery(buildErr)
+		logger.Error(buildErr)
+		return nil, buildErr
+	}
+
+	result := &BulkResultStorage{DryRun: true}
+	if err := s.client.QueryRow(ctx, sql, args...).Scan(&result.Affected); err != nil {
+		err = db.ErrDoQuery(err)
+		logger.Error(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// execScope выполняет запрос с RETURNING id в транзакции и откатывает ее,
+// если затронуто больше maxAffected строк
+func (s *ProductDAO) execScope(ctx context.Context, query sq.Sqlizer, maxAffected uint64) (*BulkResultStorage, error) {
+	sql, args, buildErr := query.ToSql()
+
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": tableScheme,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if buildErr != nil {
+		buildErr = db.ErrCreateQuery(buildErr)
+		logger.Error(buildErr)
+		return nil, buildErr
+	}
+
+	result := &BulkResultStorage{}
+	err := s.client.BeginFunc(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, sql, args...)
+		if err != nil {
+			return db.ErrDoQuery(err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id string
+			if err = rows.Scan(&id); err != nil {
+				return db.ErrScan(err)
+			}
+			result.IDs = append(result.IDs, id)
+		}
+		if err = rows.Err(); err != nil {
+			return db.ErrDoQuery(err)
+		}
+
+		result.Affected = uint64(len(result.IDs))
+		if maxAffected > 0 && result.Affected > maxAffected {
+			// транзакция откатывается, счетчик остается для аудита
+			return fmt.Errorf("%w: %d of %d allowed", ErrTooManyAffected, result.Affected, maxAffected)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrTooManyAffected) {
+			return &BulkResultStorage{Affected: result.Affected}, err
+		}
+		logger.Error(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// describeFilter перечисляет условия фильтра для аудита
+func describeFilter(filtering filter.Filterable) []string {
+	fields := filtering.Fields()
+	conditions := make([]string, len(fields))
+	for i, f := range fields {
+		conditions[i] = fmt.Sprintf("%s %s %s", f.Name, f.Operator, f.Value)
+	}
+	return conditions
+}
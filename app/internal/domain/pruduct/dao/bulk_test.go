@@ -0,0 +1,190 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/filter"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+)
+
+// fakeClient отвечает на запросы bulk: count(*) через QueryRow и RETURNING id в транзакции
+type fakeClient struct {
+	PostgreSQLClient
+	count      uint64
+	ids        []string
+	queries    []string
+	committed  bool
+	rolledBack bool
+}
+
+func (c *fakeClient) QueryRow(_ context.Context, sql string, _ ...interface{}) pgx.Row {
+	c.queries = append(c.queries, sql)
+	return fakeRow{values: []interface{}{c.count}}
+}
+
+func (c *fakeClient) BeginFunc(_ context.Context, f func(pgx.Tx) error) error {
+	if err := f(&fakeTx{client: c}); err != nil {
+		c.rolledBack = true
+		return err
+	}
+	c.committed = true
+	return nil
+}
+
+type fakeTx struct {
+	pgx.Tx
+	client *fakeClient
+}
+
+func (tx *fakeTx) Query(_ context.Context, sql string, _ ...interface{}) (pgx.Rows, error) {
+	tx.client.queries = append(tx.client.queries, sql)
+	return &fakeRows{ids: tx.client.ids, pos: -1}, nil
+}
+
+type fakeRow struct {
+	values []interface{}
+}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	for i, d := range dest {
+		*d.(*uint64) = r.values[i].(uint64)
+	}
+	return nil
+}
+
+type fakeRows struct {
+	pgx.Rows
+	ids []string
+	pos int
+}
+
+func (r *fakeRows) Next() bool {
+	r.pos++
+	return r.pos < len(r.ids)
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	*dest[0].(*string) = r.ids[r.pos]
+	return nil
+}
+
+func (r *fakeRows) Err() error {
+	return nil
+}
+
+func (r *fakeRows) Close() {}
+
+func bulkFilter(t *testing.T, expression string) filter.Filterable {
+	t.Helper()
+	filtering := filter.NewOptions(10, 5, map[string]string{"rating": filter.DataTypeInt})
+	if expression != "" {
+		if err := filtering.AddFullField(expression); err != nil {
+			t.Fatalf("AddFullField() error = %v", err)
+		}
+	}
+	return filtering
+}
+
+func TestUpdateWhere(t *testing.T) {
+	client := &fakeClient{ids: []string{"a", "b"}}
+	dao := NewProductStorage(client)
+
+	result, err := dao.UpdateWhere(context.Background(), bulkFilter(t, "rating lt 3"),
+		map[string]interface{}{"price": sq.Expr(tableAlias + ".price * 2"), "status": "archived"}, BulkOptions{MaxAffected: 2})
+	if err != nil {
+		t.Fatalf("UpdateWhere() error = %v", err)
+	}
+
+	if result.DryRun || result.Affected != 2 || !slices.Equal(result.IDs, client.ids) {
+		t.Errorf("UpdateWhere() = %+v, want 2 affected products a, b", result)
+	}
+	if !client.committed {
+		t.Error("UpdateWhere() transaction was not committed")
+	}
+	if len(client.queries) != 1 || !strings.HasPrefix(client.queries[0], "UPDATE public.product AS p SET price = p.price * 2, status = $1, updated_at = $2 WHERE") {
+		t.Errorf("UpdateWhere() queries = %q", client.queries)
+	}
+}
+
+func TestUpdateWhereRejectsColumns(t *testing.T) {
+	for _, column := range []string{"id", "created_at", "updated_at", "unknown", "price = 0, name"} {
+		t.Run(column, func(t *testing.T) {
+			client := &fakeClient{}
+			_, err := NewProductStorage(client).UpdateWhere(context.Background(), bulkFilter(t, "rating lt 3"),
+				map[string]interface{}{"price": 1, column: 1}, BulkOptions{})
+			if !errors.Is(err, ErrBulkColumn) {
+				t.Errorf("UpdateWhere() error = %v, want ErrBulkColumn", err)
+			}
+			if len(client.queries) != 0 {
+				t.Errorf("UpdateWhere() ran queries %q", client.queries)
+			}
+		})
+	}
+}
+
+func TestBulkRequiresFilter(t *testing.T) {
+	client := &fakeClient{}
+	dao := NewProductStorage(client)
+
+	_, err := dao.UpdateWhere(context.Background(), bulkFilter(t, ""), map[string]interface{}{"price": 1}, BulkOptions{})
+	if !errors.Is(err, ErrEmptyBulkFilter) {
+		t.Errorf("UpdateWhere() error = %v, want ErrEmptyBulkFilter", err)
+	}
+	_, err = dao.DeleteWhere(context.Background(), bulkFilter(t, ""), BulkOptions{DryRun: true})
+	if !errors.Is(err, ErrEmptyBulkFilter) {
+		t.Errorf("DeleteWhere() error = %v, want ErrEmptyBulkFilter", err)
+	}
+	if len(client.queries) != 0 {
+		t.Errorf("queries without filter = %q", client.queries)
+	}
+}
+
+func TestBulkMaxAffectedRollsBack(t *testing.T) {
+	client := &fakeClient{ids: []string{"a", "b", "c"}}
+
+	result, err := NewProductStorage(client).DeleteWhere(context.Background(), bulkFilter(t, "rating lt 3"), BulkOptions{MaxAffected: 2})
+	if !errors.Is(err, ErrTooManyAffected) {
+		t.Fatalf("DeleteWhere() error = %v, want ErrTooManyAffected", err)
+	}
+	if !client.rolledBack || client.committed {
+		t.Error("DeleteWhere() transaction was not rolled back")
+	}
+	if result == nil || result.Affected != 3 || len(result.IDs) != 0 {
+		t.Errorf("DeleteWhere() = %+v, want 3 affected without ids", result)
+	}
+}
+
+func TestBulkDryRun(t *testing.T) {
+	client := &fakeClient{count: 5}
+
+	result, err := NewProductStorage(client).DeleteWhere(context.Background(), bulkFilter(t, "rating lt 3"), BulkOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("DeleteWhere() error = %v", err)
+	}
+	if !result.DryRun || result.Affected != 5 || len(result.IDs) != 0 {
+		t.Errorf("DeleteWhere() = %+v, want dry run of 5 products", result)
+	}
+	if client.committed || client.rolledBack {
+		t.Error("DeleteWhere() dry run started transaction")
+	}
+	if len(client.queries) != 1 || !strings.HasPrefix(client.queries[0], "SELECT count(*) FROM public.product p WHERE") {
+		t.Errorf("DeleteWhere() queries = %q", client.queries)
+	}
+}
+
+func TestBulkDryRunMaxAffected(t *testing.T) {
+	client := &fakeClient{count: 5}
+
+	result, err := NewProductStorage(client).DeleteWhere(context.Background(), bulkFilter(t, "rating lt 3"), BulkOptions{DryRun: true, MaxAffected: 4})
+	if !errors.Is(err, ErrTooManyAffected) {
+		t.Fatalf("DeleteWhere() error = %v, want ErrTooManyAffected", err)
+	}
+	if result == nil || result.Affected != 5 {
+		t.Errorf("DeleteWhere() = %+v, want 5 affected", result)
+	}
+}
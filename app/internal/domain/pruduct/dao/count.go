@@ -0,0 +1,78 @@
+package dao
+
+import (
+	"context"
+	"slices"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/filter"
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	db "github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql/model"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+)
+
+// Count считает продукты, подходящие под фильтр. Лимит и смещение фильтра не учитываются.
+func (s *ProductDAO) Count(ctx context.Context, filtering filter.Filterable) (uint64, error) {
+	filterDB := db.NewFilters(filtering).
+		WithExpressions(productExpressions()).
+		WithRelations(productRelations())
+
+	return s.products.Count(postgresql.ReadReplica(ctx), func(query sq.SelectBuilder) sq.SelectBuilder {
+		return db.Where(filterDB.Join(joinFiltered(ctx, query, filtering), tableAlias), filterDB, tableAlias)
+	})
+}
+
+// DistinctValues возвращает непустые значения поля у продуктов, подходящих под фильтр,
+// начиная с самых частых. limit 0 означает все значения. Лимит и смещение фильтра не учитываются.
+func (s *ProductDAO) DistinctValues(
+	ctx context.Context,
+	filtering filter.Filterable,
+	field string,
+	limit uint64,
+) ([]FacetValueStorage, error) {
+	filterDB := db.NewFilters(filtering).
+		WithExpressions(productExpressions()).
+		WithRelations(productRelations())
+
+	column, err := filterDB.Column(field, tableAlias)
+	if err != nil {
+		err = db.ErrCreateQuery(err)
+		logging.WithError(ctx, err).Error("failed to resolve distinct field")
+		return nil, err
+	}
+
+	query := fromFiltered(ctx, s.queryBuilder.Select(column+"::text", "count(*)"), filtering, field)
+	query = db.Where(filterDB.Join(query, tableAlias, field), filterDB, tableAlias).
+		Where(sq.NotEq{column: nil}).
+		GroupBy("1").
+		OrderBy("2 DESC", "1")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	return postgresql.Fetch(postgresql.ReadReplica(ctx), s.client, tableScheme, query, func(row pgx.Row) (fv FacetValueStorage, err error) {
+		err = row.Scan(&fv.Value, &fv.Count)
+		return fv, err
+	})
+}
+
+// fromFiltered добавляет к query таблицу продуктов и присоединения joinFiltered
+func fromFiltered(ctx context.Context, query sq.SelectBuilder, filtering filter.Filterable, fields ...string) sq.SelectBuilder {
+	return joinFiltered(ctx, query.From(tableScheme+" "+tableAlias), filtering, fields...)
+}
+
+// joinFiltered присоединяет переводы и эффективную цену, только если их используют условия фильтра или fields
+func joinFiltered(ctx context.Context, query sq.SelectBuilder, filtering filter.Filterable, fields ...string) sq.SelectBuilder {
+	for _, f := range filtering.Fields() {
+		fields = append(fields, f.Name)
+	}
+
+	if slices.Contains(fields, "name") || slices.Contains(fields, "description") {
+		query = joinTranslation(ctx, query)
+	}
+	if slices.Contains(fields, "effective_price") {
+		query = joinEffectivePrice(query)
+	}
+	return query
+}
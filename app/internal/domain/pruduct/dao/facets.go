@@ -0,0 +1,140 @@
+package dao
+
+import (
+	"context"
+	"strings"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/filter"
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	db "github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql/model"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	sq "github.com/Masterminds/squirrel"
+)
+
+const (
+	FacetCategory      = "category"
+	FacetPrice         = "price"
+	FacetRating        = "rating"
+	FacetSpecification = "specification"
+)
+
+// Facets считает агрегаты по категориям, ценовым диапазонам, рейтингам и ключам спецификации
+// одним запросом. Счетчики фасета не учитывают фильтр по его собственному полю.
+// Значение ценового фасета - номер диапазона width_bucket по границам priceBounds.
+func (s *ProductDAO) Facets(
+	ctx context.Context,
+	filtering filter.Filterable,
+	priceBounds []uint64,
+	specKeysLimit uint64,
+) (*FacetsStorage, error) {
+	filterDB := db.NewFilters(filtering).
+		WithExpressions(productExpressions()).
+		WithRelations(productRelations())
+
+	// Подзапросы собираются с `?` и нумеруются один раз для всего UNION
+	builder := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+	base := func(facet, value string, args ...interface{}) sq.SelectBuilder {
+		query := builder.
+			Select("'" + facet + "'").
+			Column(sq.Expr(value+"::text", args...)).
+			Column("count(*)").
+			From(tableScheme + " " + tableAlias)
+		return joinEffectivePrice(joinTranslation(ctx, query))
+	}
+	// Связанные таблицы присоединяются к подзапросу, только если их поля есть в его условиях
+	where := func(query sq.SelectBuilder, excluded ...string) sq.SelectBuilder {
+		f := filterDB.Without(excluded...)
+		return f.Join(query, tableAlias).Where(f.Conditions(tableAlias))
+	}
+
+	queries := []sq.SelectBuilder{
+		where(base(FacetCategory, tableAlias+".category_id"), "category_id", "category.name").
+			GroupBy("2"),
+		where(base(FacetRating, tableAlias+".rating"), "rating").
+			GroupBy("2"),
+		where(base(FacetSpecification, "k.key").
+			JoinClause("CROSS JOIN LATERAL jsonb_object_keys("+tableAlias+".specification) AS k(key)").
+			Where(sq.Eq{"jsonb_typeof(" + tableAlias + ".specification)": "object"})).
+			GroupBy("2").
+			OrderBy("3 DESC", "2").
+			Limit(specKeysLimit),
+	}
+	if len(priceBounds) > 0 {
+		bounds := make([]int64, len(priceBounds))
+		for i, b := range priceBounds {
+			bounds[i] = int64(b)
+		}
+		queries = append(queries, where(base(FacetPrice, "width_bucket("+tableAlias+".price, ?::bigint[])", bounds).
+			Where(sq.NotEq{tableAlias + ".price": nil}), "price").
+			GroupBy("2"))
+	}
+
+	parts := make([]string, 0, len(queries))
+	args := make([]interface{}, 0)
+	var buildErr error
+	for _, query := range queries {
+		part, partArgs, err := query.ToSql()
+		if err != nil {
+			buildErr = err
+			break
+		}
+		parts = append(parts, "("+part+")")
+		args = append(args, partArgs...)
+	}
+
+	sql := strings.Join(parts, " UNION ALL ")
+	if buildErr == nil {
+		sql, buildErr = sq.Dollar.ReplacePlaceholders(sql)
+	}
+
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": tableScheme,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if buildErr != nil {
+		buildErr = db.ErrCreateQuery(buildErr)
+		logger.Error(buildErr)
+		return nil, buildErr
+	}
+
+	rows, err := s.client.Query(postgresql.ReadReplica(ctx), sql, args...)
+	if err != nil {
+		err = db.ErrDoQuery(err)
+		logger.Error(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	facets := FacetsStorage{}
+
+	for rows.Next() {
+		var facet string
+		var fv FacetValueStorage
+		if err = rows.Scan(&facet, &fv.Value, &fv.Count); err != nil {
+			err = db.ErrScan(err)
+			logger.Error(err)
+			return nil, err
+		}
+
+		switch facet {
+		case FacetCategory:
+			facets.Categories = append(facets.Categories, fv)
+		case FacetRating:
+			facets.Ratings = append(facets.Ratings, fv)
+		case FacetSpecification:
+			facets.SpecificationKeys = append(facets.SpecificationKeys, fv)
+		case FacetPrice:
+			facets.PriceBuckets = append(facets.PriceBuckets, fv)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		err = db.ErrDoQuery(err)
+		logger.Error(err)
+		return nil, err
+	}
+
+	return &facets, nil
+}
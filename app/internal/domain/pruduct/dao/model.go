@@ -9,17 +9,21 @@ import (
 )
 
 type ProductStorage struct {
-	ID            string
-	Name          string
-	Description   string
-	ImageID       sql.NullString
-	Price         uint64
-	CurrencyID    uint32
-	Rating        uint32
-	CategoryID    uint32 // Changed to int32 for consistency with proto definition
-	Specification map[string]interface{}
-	CreatedAt     sql.NullString
-	UpdatedAt     sql.NullString
+	ID             string
+	Name           string
+	Description    string
+	ImageID        sql.NullString
+	Price          uint64
+	CurrencyID     uint32
+	Rating         uint32
+	CategoryID     uint32 // Changed to int32 for consistency with proto definition
+	Specification  map[string]interface{}
+	CreatedAt      sql.NullString
+	UpdatedAt      sql.NullString
+	// Locale of name and description, empty when base columns are used
+	Locale         string
+	Status         string
+	EffectivePrice uint64
 }
 
 type CreateProductStorageDTO struct {
@@ -32,6 +36,7 @@ type CreateProductStorageDTO struct {
 	Rating        uint32
 	CategoryID    uint32
 	Specification map[string]interface{}
+	Status        string
 	CreatedAt     string
 	UpdatedAt     string
 }
@@ -78,4 +83,124 @@ func NewUpdateProductStorageDTO(dto *dto.UpdateProductDTO) *UpdateProductStorage
 		Specification: dto.Specification,
 		UpdatedAt:     time.Now().UTC().Format(time.RFC3339),
 	}
-}
\ No newline at end of file
+}
+type ProductTranslationStorage struct {
+	ProductID   string
+	Locale      string
+	Name        string
+	Description string
+	CreatedAt   sql.NullString
+	UpdatedAt   sql.NullString
+}
+
+// NewProductTranslationStorage создает DTO перевода продукта для хранилища
+func NewProductTranslationStorage(productID string, dto *dto.ProductTranslationDTO) *ProductTranslationStorage {
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	return &ProductTranslationStorage{
+		ProductID:   productID,
+		Locale:      dto.Locale,
+		Name:        dto.Name,
+		Description: dto.Description,
+		CreatedAt:   sql.NullString{String: now, Valid: true},
+		UpdatedAt:   sql.NullString{String: now, Valid: true},
+	}
+}
+
+type StatusScheduleStorage struct {
+	ID         string
+	ProductID  string
+	Status     string
+	RunAt      time.Time
+	CreatedAt  sql.NullTime
+	ExecutedAt sql.NullTime
+	Error      sql.NullString
+}
+
+// NewStatusScheduleStorage создает DTO отложенной смены статуса для хранилища
+func NewStatusScheduleStorage(productID, status string, runAt time.Time) *StatusScheduleStorage {
+	return &StatusScheduleStorage{
+		ID:        uuid.New().String(),
+		ProductID: productID,
+		Status:    status,
+		RunAt:     runAt.UTC(),
+		CreatedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	}
+}
+
+type FacetsStorage struct {
+	Categories        []FacetValueStorage
+	Ratings           []FacetValueStorage
+	SpecificationKeys []FacetValueStorage
+	// PriceBuckets values are width_bucket numbers
+	PriceBuckets []FacetValueStorage
+}
+
+type FacetValueStorage struct {
+	Value sql.NullString
+	Count uint64
+}
+
+type PriceChangeStorage struct {
+	ID        string
+	ProductID string
+	OldPrice  sql.NullInt64
+	NewPrice  uint64
+	Actor     string
+	Source    string
+	ChangedAt time.Time
+}
+
+// NewPriceChangeStorage создает DTO записи истории цены для хранилища
+func NewPriceChangeStorage(productID string, oldPrice *uint64, newPrice uint64, actor, source string) *PriceChangeStorage {
+	storage := &PriceChangeStorage{
+		ID:        uuid.New().String(),
+		ProductID: productID,
+		NewPrice:  newPrice,
+		Actor:     actor,
+		Source:    source,
+		ChangedAt: time.Now().UTC(),
+	}
+	if oldPrice != nil {
+		storage.OldPrice = sql.NullInt64{Int64: int64(*oldPrice), Valid: true}
+	}
+	return storage
+}
+
+type PriceScheduleStorage struct {
+	ID         string
+	ProductID  string
+	Price      uint64
+	Actor      string
+	RunAt      time.Time
+	CreatedAt  sql.NullTime
+	ExecutedAt sql.NullTime
+	Error      sql.NullString
+}
+
+// NewPriceScheduleStorage создает DTO отложенного изменения цены для хранилища
+func NewPriceScheduleStorage(productID string, price uint64, actor string, runAt time.Time) *PriceScheduleStorage {
+	return &PriceScheduleStorage{
+		ID:        uuid.New().String(),
+		ProductID: productID,
+		Price:     price,
+		Actor:     actor,
+		RunAt:     runAt.UTC(),
+		CreatedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	}
+}
+
+// BulkOptions ограничивают массовое изменение продуктов по фильтру
+type BulkOptions struct {
+	// DryRun only counts matching products, nothing is changed
+	DryRun bool
+	// MaxAffected rolls operation back when more products are affected, 0 means no limit
+	MaxAffected uint64
+}
+
+// BulkResultStorage is outcome of bulk operation, IDs are empty for dry run
+type BulkResultStorage struct {
+	DryRun   bool
+	Affected uint64
+	IDs      []string
+}
@@ -2,24 +2,29 @@ package dao
 
 import (
 	"context"
+	"time"
 
+	promotion "github.com/HollyEllmo/my-first-go-project/internal/domain/promotion/dao"
 	"github.com/HollyEllmo/my-first-go-project/pkg/api/filter"
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/locale"
 	"github.com/HollyEllmo/my-first-go-project/pkg/api/sort"
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
 	db "github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql/model"
-	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
-	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
 )
 
 type ProductDAO struct {
 	queryBuilder sq.StatementBuilderType
 	client       PostgreSQLClient
+	products     *postgresql.Repository[*ProductStorage]
 }
 
 func NewProductStorage(client PostgreSQLClient) *ProductDAO {
 	return &ProductDAO{
 		queryBuilder: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
 		client:       client,
+		products:     postgresql.NewRepository(client, productTable()),
 	}
 }
 
@@ -27,139 +32,77 @@ const (
 	scheme      = "public"
 	table       = "product"
 	tableScheme = scheme + "." + table
-)
+	tableAlias  = "p"
 
-func (s *ProductDAO) All(ctx context.Context, filtering filter.Filterable, sorting sort.Sortable) ([]*ProductStorage, error) {
-	sortDB := db.NewSortOptions(sorting)
-	filterDB := db.NewFilters(filtering)
+	translationTable       = "product_translation"
+	translationTableScheme = scheme + "." + translationTable
+	translationAlias       = "tr"
 
-	query := s.queryBuilder.
-		Select("id").
-		Columns(
-			"name",
-			"description",
-			"image_id",
-			"price",
-			"currency_id",
-			"rating",
-			"category_id",
-			"specification",
-			"created_at",
-			"updated_at",
-		).
-		From(tableScheme)
+	localizedName        = "COALESCE(" + translationAlias + ".name, " + tableAlias + ".name)"
+	localizedDescription = "COALESCE(" + translationAlias + ".description, " + tableAlias + ".description)"
+	localizedLocale      = "COALESCE(" + translationAlias + ".locale, '')"
 
-	query = filterDB.Enrich(query, "")
-	query = sortDB.Sort(query, "")
+	categoryTableScheme = scheme + ".category"
+	categoryAlias       = "cat"
+	currencyTableScheme = scheme + ".currency"
+	currencyAlias       = "cur"
 
-	sql, args, err := query.ToSql()
-	logger := logging.WithFields(ctx, map[string]interface{}{
-		"sql":   sql,
-		"table": tableScheme,
-		"args":  args,
-	})
-	if err != nil {
-		err = db.ErrCreateQuery(err)
-		logger.Error(err)
-		return nil, err
-	}
+	effectivePriceAlias = "ep"
+	effectivePrice      = "COALESCE(" + effectivePriceAlias + ".price, " + tableAlias + ".price)"
+)
 
-	rows, err := s.client.Query(ctx, sql, args...)
-	if err != nil {
-		err = db.ErrDoQuery(err)
-		logger.Error(err)
-		return nil, err
+// productExpressions maps filter and sort fields to SQL expressions which are not plain columns
+func productExpressions() map[string]string {
+	return map[string]string{
+		"name":            localizedName,
+		"description":     localizedDescription,
+		"effective_price": effectivePrice,
 	}
+}
 
-	defer rows.Close()
-
-	list := make([]*ProductStorage, 0)
-
-	for rows.Next() {
-		ps := ProductStorage{}
-		if err = rows.Scan(
-			&ps.ID,
-			&ps.Name,
-			&ps.Description,
-			&ps.ImageID,
-			&ps.Price,
-			&ps.CurrencyID,
-			&ps.Rating,
-			&ps.CategoryID,
-			&ps.Specification,
-			&ps.CreatedAt,
-			&ps.UpdatedAt,
-		); err != nil {
-			err = db.ErrScan(err)
-			logger.Error(err)
-			return nil, err
-		}
-
-		list = append(list, &ps)
+// productRelations are tables joined when products are filtered by their columns, e.g. `category.name`
+func productRelations() map[string]db.Relation {
+	return map[string]db.Relation{
+		"category": {Table: categoryTableScheme, Alias: categoryAlias, ForeignKey: "category_id"},
+		"currency": {Table: currencyTableScheme, Alias: currencyAlias, ForeignKey: "currency_id"},
 	}
-
-	return list, nil
 }
 
-func (s *ProductDAO) Create(ctx context.Context, dto *CreateProductStorageDTO) error {
-	sql, args, buildError := s.queryBuilder.
-	 Insert(tableScheme).
-	 Columns(
-		"id",
-		"name",
-		"description",
-		"image_id",
-		"price",
-		"currency_id",
-		"rating",
-		"category_id",
-		"specification",
-		"created_at",
-		"updated_at",
-	 ).Values(
-		dto.ID,
-		dto.Name,
-		dto.Description,
-		dto.ImageID,
-		dto.Price,
-		dto.CurrencyID,
-		dto.Rating,
-		dto.CategoryID,
-		dto.Specification,
-		dto.CreatedAt,
-		dto.UpdatedAt,
-	 ).PlaceholderFormat(sq.Dollar).ToSql()
-
-	 logger := logging.WithFields(ctx, map[string]interface{}{
-		"sql":   sql,
-		"table": tableScheme,
-		"args":  args, 
-	})
-	if buildError != nil {
-		buildError = db.ErrCreateQuery(buildError)
-		logger.Error(buildError)
-		return buildError
+// productSortExpressions maps sortable fields to SQL expressions
+func productSortExpressions() map[string]string {
+	expressions := map[string]string{
+		"id":          tableAlias + ".id",
+		"price":       tableAlias + ".price",
+		"rating":      tableAlias + ".rating",
+		"category_id": tableAlias + ".category_id",
+		"status":      tableAlias + ".status",
+		"created_at":  tableAlias + ".created_at",
+		"updated_at":  tableAlias + ".updated_at",
 	}
-
-	if exec, execErr := s.client.Exec(ctx, sql, args...); execErr != nil {
-		execErr = db.ErrDoQuery(execErr)
-		logger.Error(execErr)
-		return execErr
-	} else if exec.RowsAffected() == 0 || !exec.Insert() {
-		execErr = db.ErrDoQuery(errors.New("product was not created. 0 raws were affected"))
-		logger.Error(execErr)
-		return execErr
+	for field, expr := range productExpressions() {
+		expressions[field] = expr
 	}
+	return expressions
+}
 
-	return nil
+// productSortJSONPaths maps JSONB fields which can be sorted by nested paths
+func productSortJSONPaths() map[string]string {
+	return map[string]string{
+		"specification": tableAlias + ".specification",
+	}
 }
 
-func (s *ProductDAO) One(ctx context.Context, id string) (*ProductStorage, error) {
-	sql, args, buildErr := s.queryBuilder.
-		Select("id").
-		Columns(
-			"name",
-			"description",
+// productTable describes product read with name and description in the best locale
+// of the request fallback chain. Base columns are used when no translation is found.
+func productTable() postgresql.Table[*ProductStorage] {
+	return postgresql.Table[*ProductStorage]{
+		Name:       tableScheme,
+		Alias:      tableAlias,
+		PrimaryKey: "id",
+		Columns: []string{
+			"id",
+			localizedName,
+			localizedDescription,
 			"image_id",
 			"price",
 			"currency_id",
@@ -168,105 +111,99 @@ func (s *ProductDAO) One(ctx context.Context, id string) (*ProductStorage, error
 			"specification",
 			"created_at",
 			"updated_at",
-		).
-		From(tableScheme).
-		Where(sq.Eq{"id": id}).ToSql()
-
-	logger := logging.WithFields(ctx, map[string]interface{}{
-		"sql":   sql,
-		"table": tableScheme,
-		"args":  args,
-	})
-	if buildErr != nil {
-		buildErr = db.ErrCreateQuery(buildErr)
-		logger.Error(buildErr)
-		return nil, buildErr
+			localizedLocale,
+			"status",
+			effectivePrice,
+		},
+		Join: func(ctx context.Context, query sq.SelectBuilder) sq.SelectBuilder {
+			return joinEffectivePrice(joinTranslation(ctx, query))
+		},
+		Scan: func(row pgx.Row) (*ProductStorage, error) {
+			var ps ProductStorage
+			return &ps, row.Scan(
+				&ps.ID,
+				&ps.Name,
+				&ps.Description,
+				&ps.ImageID,
+				&ps.Price,
+				&ps.CurrencyID,
+				&ps.Rating,
+				&ps.CategoryID,
+				&ps.Specification,
+				&ps.CreatedAt,
+				&ps.UpdatedAt,
+				&ps.Locale,
+				&ps.Status,
+				&ps.EffectivePrice,
+			)
+		},
 	}
+}
 
-	var ps ProductStorage
+// joinEffectivePrice joins current effective price of product as `ep`
+func joinEffectivePrice(query sq.SelectBuilder) sq.SelectBuilder {
+	return promotion.JoinEffectivePrice(query, tableAlias, effectivePriceAlias, time.Now())
+}
 
-	err := s.client.QueryRow(ctx, sql, args...).Scan(
-		&ps.ID,
-		&ps.Name,
-		&ps.Description,
-		&ps.ImageID,
-		&ps.Price,
-		&ps.CurrencyID,
-		&ps.Rating,
-		&ps.CategoryID,
-		&ps.Specification,
-		&ps.CreatedAt,
-		&ps.UpdatedAt,
-	)
-	if err != nil {
-		err = db.ErrDoQuery(err)
-		logger.Error(err)
-		return nil, err
+// joinTranslation joins the best translation of the request fallback chain as `tr`
+func joinTranslation(ctx context.Context, query sq.SelectBuilder) sq.SelectBuilder {
+	locales := locale.LocalesFromContext(ctx)
+	if locales == nil {
+		locales = []string{}
 	}
 
-	return &ps, nil
+	return query.LeftJoin(
+		"LATERAL (SELECT t.locale, t.name, t.description FROM "+translationTableScheme+" t "+
+			"WHERE t.product_id = "+tableAlias+".id AND t.locale = ANY(?::text[]) "+
+			"ORDER BY array_position(?::text[], t.locale) LIMIT 1) "+translationAlias+" ON TRUE",
+		locales, locales,
+	)
 }
 
-func (s *ProductDAO) Update(ctx context.Context, id string, m map[string]interface{}) error {
-	sql, args, buildErr := s.queryBuilder.
-		Update(tableScheme).
-		SetMap(m).
-		Where(sq.Eq{"id": id}).
-		PlaceholderFormat(sq.Dollar).
-	ToSql()
-
-	logger := logging.WithFields(ctx, map[string]interface{}{
-		"sql":   sql,
-		"table": tableScheme,
-		"args":  args,
+func (s *ProductDAO) All(ctx context.Context, filtering filter.Filterable, sorting sort.Sortable) ([]*ProductStorage, error) {
+	sortDB := db.NewSortOptions(sorting).
+		WithExpressions(productSortExpressions()).
+		WithJSONPaths(productSortJSONPaths())
+	filterDB := db.NewFilters(filtering).
+		WithExpressions(productExpressions()).
+		WithRelations(productRelations())
+
+	return s.products.List(postgresql.ReadReplica(ctx), func(query sq.SelectBuilder) sq.SelectBuilder {
+		return sortDB.Sort(filterDB.Enrich(query, tableAlias), tableAlias)
 	})
-	if buildErr != nil {
-		buildErr = db.ErrCreateQuery(buildErr)
-		logger.Error(buildErr)
-		return buildErr
-	}
-
-	if exec, execErr := s.client.Exec(ctx, sql, args...); execErr != nil {
-		execErr = db.ErrDoQuery(execErr)
-		logger.Error(execErr)
-		return execErr
-	} else if exec.RowsAffected() == 0 || !exec.Update() {
-		execErr = db.ErrDoQuery(errors.New("product was not updated. 0 rows were affected"))
-		logger.Error(execErr)
-		return execErr
-	}
-
-	return nil
 }
 
-func (s *ProductDAO) Delete(ctx context.Context, id string) error {
-	sql, args, buildErr := s.queryBuilder.
-		Delete(tableScheme).
-		Where(sq.Eq{"id": id}).
-		ToSql()
-
-	logger := logging.WithFields(ctx, map[string]interface{}{
-		"sql":   sql,
-		"table": tableScheme,
-		"args":  args,
+func (s *ProductDAO) Create(ctx context.Context, dto *CreateProductStorageDTO) error {
+	return s.products.Insert(ctx, map[string]interface{}{
+		"id":            dto.ID,
+		"name":          dto.Name,
+		"description":   dto.Description,
+		"image_id":      dto.ImageID,
+		"price":         dto.Price,
+		"currency_id":   dto.CurrencyID,
+		"rating":        dto.Rating,
+		"category_id":   dto.CategoryID,
+		"specification": dto.Specification,
+		"status":        dto.Status,
+		"created_at":    dto.CreatedAt,
+		"updated_at":    dto.UpdatedAt,
 	})
-	if buildErr != nil {
-		buildErr = db.ErrCreateQuery(buildErr)
-		logger.Error(buildErr)
-		return buildErr
-	}
+}
 
-	if exec, execErr := s.client.Exec(ctx, sql, args...); execErr != nil {
-		execErr = db.ErrDoQuery(execErr)
-		logger.Error(execErr)
-		return execErr
-	} else if exec.RowsAffected() == 0 || !exec.Delete() {
-		execErr = db.ErrDoQuery(errors.New("product was not deleted. 0 rows were affected"))
-		logger.Error(execErr)
-		return execErr
-	}
+func (s *ProductDAO) One(ctx context.Context, id string) (*ProductStorage, error) {
+	return s.products.One(postgresql.ReadReplica(ctx), id)
+}
 
-	return nil
+// OnePrimary читает продукт с primary: значения реплики могут отставать,
+// а по ним проверяется переход и строится compare-and-swap
+func (s *ProductDAO) OnePrimary(ctx context.Context, id string) (*ProductStorage, error) {
+	return s.products.One(ctx, id)
 }
 
+func (s *ProductDAO) Update(ctx context.Context, id string, m map[string]interface{}) error {
+	return s.products.Update(ctx, id, m)
+}
 
+func (s *ProductDAO) Delete(ctx context.Context, id string) error {
+	return s.products.Delete(ctx, id)
+}
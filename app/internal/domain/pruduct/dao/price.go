@@ -0,0 +1,297 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	db "github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql/model"
+	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	sq "github.com/Masterminds/squirrel"
+)
+
+const (
+	priceHistoryTable        = "product_price_history"
+	priceHistoryTableScheme  = scheme + "." + priceHistoryTable
+	priceScheduleTable       = "product_price_schedule"
+	priceScheduleTableScheme = scheme + "." + priceScheduleTable
+)
+
+// claimPriceSchedulesSQL claims due price schedules the same way as claimStatusSchedulesSQL
+const claimPriceSchedulesSQL = `WITH due AS (
+	SELECT id FROM ` + priceScheduleTableScheme + `
+	WHERE executed_at IS NULL AND run_at <= $1 AND (claimed_until IS NULL OR claimed_until < now())
+	ORDER BY run_at
+	LIMIT $2
+	FOR UPDATE SKIP LOCKED
+), claimed AS (
+	UPDATE ` + priceScheduleTableScheme + ` s SET claimed_until = now() + make_interval(secs => $3)
+	FROM due WHERE s.id = due.id
+	RETURNING s.id, s.product_id, s.price, s.actor, s.run_at, s.created_at, s.executed_at, s.error
+)
+SELECT * FROM claimed ORDER BY run_at`
+
+// ChangePrice меняет цену продукта, если текущая цена равна from
+func (s *ProductDAO) ChangePrice(ctx context.Context, id string, from, to uint64) error {
+	sql, args, buildErr := s.queryBuilder.
+		Update(tableScheme).
+		Set("price", to).
+		Set("updated_at", time.Now().UTC().Format(time.RFC3339)).
+		Where(sq.Eq{"id": id, "price": from}).
+		ToSql()
+
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": tableScheme,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if buildErr != nil {
+		buildErr = db.ErrCreateQuery(buildErr)
+		logger.Error(buildErr)
+		return buildErr
+	}
+
+	if exec, execErr := s.client.Exec(ctx, sql, args...); execErr != nil {
+		execErr = db.ErrDoQuery(execErr)
+		logger.Error(execErr)
+		return execErr
+	} else if exec.RowsAffected() == 0 {
+		execErr = db.ErrDoQuery(errors.Wrap(postgresql.ErrNotFound, "product price was not changed. 0 rows were affected"))
+		logger.Error(execErr)
+		return execErr
+	}
+
+	return nil
+}
+
+// CreatePriceChange добавляет запись в историю цены
+func (s *ProductDAO) CreatePriceChange(ctx context.Context, dto *PriceChangeStorage) error {
+	sql, args, buildErr := s.queryBuilder.
+		Insert(priceHistoryTableScheme).
+		Columns(
+			"id",
+			"product_id",
+			"old_price",
+			"new_price",
+			"actor",
+			"source",
+			"changed_at",
+		).Values(
+		dto.ID,
+		dto.ProductID,
+		dto.OldPrice,
+		dto.NewPrice,
+		dto.Actor,
+		dto.Source,
+		dto.ChangedAt,
+	).ToSql()
+
+	return s.exec(ctx, priceHistoryTableScheme, sql, args, buildErr)
+}
+
+// PriceHistory возвращает историю цены продукта в хронологическом порядке
+func (s *ProductDAO) PriceHistory(ctx context.Context, productID string) ([]*PriceChangeStorage, error) {
+	sql, args, buildErr := s.queryBuilder.
+		Select("id").
+		Columns(
+			"product_id",
+			"old_price",
+			"new_price",
+			"actor",
+			"source",
+			"changed_at",
+		).
+		From(priceHistoryTableScheme).
+		Where(sq.Eq{"product_id": productID}).
+		OrderBy("changed_at", "id").
+		ToSql()
+
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": priceHistoryTableScheme,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if buildErr != nil {
+		buildErr = db.ErrCreateQuery(buildErr)
+		logger.Error(buildErr)
+		return nil, buildErr
+	}
+
+	rows, err := s.client.Query(ctx, sql, args...)
+	if err != nil {
+		err = db.ErrDoQuery(err)
+		logger.Error(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	list := make([]*PriceChangeStorage, 0)
+
+	for rows.Next() {
+		pc := PriceChangeStorage{}
+		if err = rows.Scan(
+			&pc.ID,
+			&pc.ProductID,
+			&pc.OldPrice,
+			&pc.NewPrice,
+			&pc.Actor,
+			&pc.Source,
+			&pc.ChangedAt,
+		); err != nil {
+			err = db.ErrScan(err)
+			logger.Error(err)
+			return nil, err
+		}
+
+		list = append(list, &pc)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = db.ErrDoQuery(err)
+		logger.Error(err)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// CreatePriceSchedule сохраняет отложенное изменение цены
+func (s *ProductDAO) CreatePriceSchedule(ctx context.Context, dto *PriceScheduleStorage) error {
+	sql, args, buildErr := s.queryBuilder.
+		Insert(priceScheduleTableScheme).
+		Columns(
+			"id",
+			"product_id",
+			"price",
+			"actor",
+			"run_at",
+			"created_at",
+		).Values(
+		dto.ID,
+		dto.ProductID,
+		dto.Price,
+		dto.Actor,
+		dto.RunAt,
+		dto.CreatedAt,
+	).ToSql()
+
+	return s.exec(ctx, priceScheduleTableScheme, sql, args, buildErr)
+}
+
+// PriceSchedules возвращает отложенные изменения цены продукта
+func (s *ProductDAO) PriceSchedules(ctx context.Context, productID string) ([]*PriceScheduleStorage, error) {
+	return s.priceSchedules(ctx, s.selectPriceSchedules().
+		Where(sq.Eq{"product_id": productID}).
+		OrderBy("run_at"))
+}
+
+// DuePriceSchedules захватывает невыполненные изменения цены, время которых наступило,
+// другие экземпляры не получат их, пока захват не истечет
+func (s *ProductDAO) DuePriceSchedules(ctx context.Context, now time.Time, limit uint64) ([]*PriceScheduleStorage, error) {
+	return s.priceSchedules(ctx, sq.Expr(claimPriceSchedulesSQL, now.UTC(), limit, scheduleClaimTimeout.Seconds()))
+}
+
+// CompletePriceSchedule отмечает изменение цены выполненным. errText пишется, если изменение не удалось
+func (s *ProductDAO) CompletePriceSchedule(ctx context.Context, id string, errText *string) error {
+	sql, args, buildErr := s.queryBuilder.
+		Update(priceScheduleTableScheme).
+		Set("executed_at", time.Now().UTC()).
+		Set("error", errText).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+
+	return s.exec(ctx, priceScheduleTableScheme, sql, args, buildErr)
+}
+
+func (s *ProductDAO) selectPriceSchedules() sq.SelectBuilder {
+	return s.queryBuilder.
+		Select("id").
+		Columns(
+			"product_id",
+			"price",
+			"actor",
+			"run_at",
+			"created_at",
+			"executed_at",
+			"error",
+		).
+		From(priceScheduleTableScheme)
+}
+
+func (s *ProductDAO) priceSchedules(ctx context.Context, query sq.Sqlizer) ([]*PriceScheduleStorage, error) {
+	sql, args, buildErr := query.ToSql()
+
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": priceScheduleTableScheme,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if buildErr != nil {
+		buildErr = db.ErrCreateQuery(buildErr)
+		logger.Error(buildErr)
+		return nil, buildErr
+	}
+
+	rows, err := s.client.Query(ctx, sql, args...)
+	if err != nil {
+		err = db.ErrDoQuery(err)
+		logger.Error(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	list := make([]*PriceScheduleStorage, 0)
+
+	for rows.Next() {
+		ps := PriceScheduleStorage{}
+		if err = rows.Scan(
+			&ps.ID,
+			&ps.ProductID,
+			&ps.Price,
+			&ps.Actor,
+			&ps.RunAt,
+			&ps.CreatedAt,
+			&ps.ExecutedAt,
+			&ps.Error,
+		); err != nil {
+			err = db.ErrScan(err)
+			logger.Error(err)
+			return nil, err
+		}
+
+		list = append(list, &ps)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = db.ErrDoQuery(err)
+		logger.Error(err)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// exec выполняет построенный запрос, для которого не важно число затронутых строк
+func (s *ProductDAO) exec(ctx context.Context, table, sql string, args []interface{}, buildErr error) error {
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": table,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if buildErr != nil {
+		buildErr = db.ErrCreateQuery(buildErr)
+		logger.Error(buildErr)
+		return buildErr
+	}
+
+	if _, execErr := s.client.Exec(ctx, sql, args...); execErr != nil {
+		execErr = db.ErrDoQuery(execErr)
+		logger.Error(execErr)
+		return execErr
+	}
+
+	return nil
+}
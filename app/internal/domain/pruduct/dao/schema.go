@@ -0,0 +1,25 @@
+package dao
+
+import "github.com/HollyEllmo/my-first-go-project/pkg/migrate"
+
+// ProductSchema is columns of product table read and written by ProductDAO,
+// database is checked against it at startup to catch schema drift before scan errors
+func ProductSchema() migrate.Table {
+	return migrate.Table{
+		Name: tableScheme,
+		Columns: []migrate.Column{
+			{Name: "id", Type: "uuid"},
+			{Name: "name", Type: "text"},
+			{Name: "description", Type: "text"},
+			{Name: "image_id", Type: "uuid"},
+			{Name: "price", Type: "bigint"},
+			{Name: "currency_id", Type: "integer"},
+			{Name: "rating", Type: "integer"},
+			{Name: "category_id", Type: "integer"},
+			{Name: "specification", Type: "jsonb"},
+			{Name: "created_at", Type: "timestamp with time zone"},
+			{Name: "updated_at", Type: "timestamp with time zone"},
+			{Name: "status", Type: "text"},
+		},
+	}
+}
@@ -0,0 +1,215 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	db "github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql/model"
+	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	sq "github.com/Masterminds/squirrel"
+)
+
+const (
+	scheduleTable       = "product_status_schedule"
+	scheduleTableScheme = scheme + "." + scheduleTable
+
+	// scheduleClaimTimeout is period claimed schedule isn't picked up by other scheduler instances
+	scheduleClaimTimeout = 5 * time.Minute
+)
+
+// claimStatusSchedulesSQL claims up to $2 schedules due at $1 for $3 seconds. Schedules claimed by other
+// instances are skipped, not waited for, so every schedule runs once.
+const claimStatusSchedulesSQL = `WITH due AS (
+	SELECT id FROM ` + scheduleTableScheme + `
+	WHERE executed_at IS NULL AND run_at <= $1 AND (claimed_until IS NULL OR claimed_until < now())
+	ORDER BY run_at
+	LIMIT $2
+	FOR UPDATE SKIP LOCKED
+), claimed AS (
+	UPDATE ` + scheduleTableScheme + ` s SET claimed_until = now() + make_interval(secs => $3)
+	FROM due WHERE s.id = due.id
+	RETURNING s.id, s.product_id, s.status, s.run_at, s.created_at, s.executed_at, s.error
+)
+SELECT * FROM claimed ORDER BY run_at`
+
+// ChangeStatus меняет статус продукта, если текущий статус равен from
+func (s *ProductDAO) ChangeStatus(ctx context.Context, id, from, to string) error {
+	sql, args, buildErr := s.queryBuilder.
+		Update(tableScheme).
+		Set("status", to).
+		Set("updated_at", time.Now().UTC().Format(time.RFC3339)).
+		Where(sq.Eq{"id": id, "status": from}).
+		ToSql()
+
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": tableScheme,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if buildErr != nil {
+		buildErr = db.ErrCreateQuery(buildErr)
+		logger.Error(buildErr)
+		return buildErr
+	}
+
+	if exec, execErr := s.client.Exec(ctx, sql, args...); execErr != nil {
+		execErr = db.ErrDoQuery(execErr)
+		logger.Error(execErr)
+		return execErr
+	} else if exec.RowsAffected() == 0 {
+		execErr = db.ErrDoQuery(errors.Wrap(postgresql.ErrNotFound, "product status was not changed. 0 rows were affected"))
+		logger.Error(execErr)
+		return execErr
+	}
+
+	return nil
+}
+
+// CreateStatusSchedule сохраняет отложенную смену статуса
+func (s *ProductDAO) CreateStatusSchedule(ctx context.Context, dto *StatusScheduleStorage) error {
+	sql, args, buildErr := s.queryBuilder.
+		Insert(scheduleTableScheme).
+		Columns(
+			"id",
+			"product_id",
+			"status",
+			"run_at",
+			"created_at",
+		).Values(
+		dto.ID,
+		dto.ProductID,
+		dto.Status,
+		dto.RunAt,
+		dto.CreatedAt,
+	).ToSql()
+
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": scheduleTableScheme,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if buildErr != nil {
+		buildErr = db.ErrCreateQuery(buildErr)
+		logger.Error(buildErr)
+		return buildErr
+	}
+
+	if _, execErr := s.client.Exec(ctx, sql, args...); execErr != nil {
+		execErr = db.ErrDoQuery(execErr)
+		logger.Error(execErr)
+		return execErr
+	}
+
+	return nil
+}
+
+// StatusSchedules возвращает отложенные смены статуса продукта
+func (s *ProductDAO) StatusSchedules(ctx context.Context, productID string) ([]*StatusScheduleStorage, error) {
+	return s.statusSchedules(ctx, s.selectStatusSchedules().
+		Where(sq.Eq{"product_id": productID}).
+		OrderBy("run_at"))
+}
+
+// DueStatusSchedules захватывает невыполненные смены статуса, время которых наступило,
+// другие экземпляры не получат их, пока захват не истечет
+func (s *ProductDAO) DueStatusSchedules(ctx context.Context, now time.Time, limit uint64) ([]*StatusScheduleStorage, error) {
+	return s.statusSchedules(ctx, sq.Expr(claimStatusSchedulesSQL, now.UTC(), limit, scheduleClaimTimeout.Seconds()))
+}
+
+// CompleteStatusSchedule отмечает смену статуса выполненной. errText пишется, если смена не удалась
+func (s *ProductDAO) CompleteStatusSchedule(ctx context.Context, id string, errText *string) error {
+	sql, args, buildErr := s.queryBuilder.
+		Update(scheduleTableScheme).
+		Set("executed_at", time.Now().UTC()).
+		Set("error", errText).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": scheduleTableScheme,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if buildErr != nil {
+		buildErr = db.ErrCreateQuery(buildErr)
+		logger.Error(buildErr)
+		return buildErr
+	}
+
+	if _, execErr := s.client.Exec(ctx, sql, args...); execErr != nil {
+		execErr = db.ErrDoQuery(execErr)
+		logger.Error(execErr)
+		return execErr
+	}
+
+	return nil
+}
+
+func (s *ProductDAO) selectStatusSchedules() sq.SelectBuilder {
+	return s.queryBuilder.
+		Select("id").
+		Columns(
+			"product_id",
+			"status",
+			"run_at",
+			"created_at",
+			"executed_at",
+			"error",
+		).
+		From(scheduleTableScheme)
+}
+
+func (s *ProductDAO) statusSchedules(ctx context.Context, query sq.Sqlizer) ([]*StatusScheduleStorage, error) {
+	sql, args, buildErr := query.ToSql()
+
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": scheduleTableScheme,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if buildErr != nil {
+		buildErr = db.ErrCreateQuery(buildErr)
+		logger.Error(buildErr)
+		return nil, buildErr
+	}
+
+	rows, err := s.client.Query(ctx, sql, args...)
+	if err != nil {
+		err = db.ErrDoQuery(err)
+		logger.Error(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	list := make([]*StatusScheduleStorage, 0)
+
+	for rows.Next() {
+		ss := StatusScheduleStorage{}
+		if err = rows.Scan(
+			&ss.ID,
+			&ss.ProductID,
+			&ss.Status,
+			&ss.RunAt,
+			&ss.CreatedAt,
+			&ss.ExecutedAt,
+			&ss.Error,
+		); err != nil {
+			err = db.ErrScan(err)
+			logger.Error(err)
+			return nil, err
+		}
+
+		list = append(list, &ss)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = db.ErrDoQuery(err)
+		logger.Error(err)
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,118 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	db "github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql/model"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	sq "github.com/Masterminds/squirrel"
+)
+
+// UpsertTranslation создает или обновляет перевод продукта для локали
+func (s *ProductDAO) UpsertTranslation(ctx context.Context, dto *ProductTranslationStorage) error {
+	sql, args, buildErr := s.queryBuilder.
+		Insert(translationTableScheme).
+		Columns(
+			"product_id",
+			"locale",
+			"name",
+			"description",
+			"created_at",
+			"updated_at",
+		).Values(
+		dto.ProductID,
+		dto.Locale,
+		dto.Name,
+		dto.Description,
+		dto.CreatedAt,
+		dto.UpdatedAt,
+	).
+		Suffix("ON CONFLICT (product_id, locale) DO UPDATE SET " +
+			"name = EXCLUDED.name, description = EXCLUDED.description, updated_at = EXCLUDED.updated_at").
+		ToSql()
+
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": translationTableScheme,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if buildErr != nil {
+		buildErr = db.ErrCreateQuery(buildErr)
+		logger.Error(buildErr)
+		return buildErr
+	}
+
+	if _, execErr := s.client.Exec(ctx, sql, args...); execErr != nil {
+		execErr = db.ErrDoQuery(execErr)
+		logger.Error(execErr)
+		return execErr
+	}
+
+	return nil
+}
+
+// Translations возвращает все переводы продукта
+func (s *ProductDAO) Translations(ctx context.Context, productID string) ([]*ProductTranslationStorage, error) {
+	sql, args, buildErr := s.queryBuilder.
+		Select("product_id").
+		Columns(
+			"locale",
+			"name",
+			"description",
+			"created_at",
+			"updated_at",
+		).
+		From(translationTableScheme).
+		Where(sq.Eq{"product_id": productID}).
+		OrderBy("locale").
+		ToSql()
+
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": translationTableScheme,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if buildErr != nil {
+		buildErr = db.ErrCreateQuery(buildErr)
+		logger.Error(buildErr)
+		return nil, buildErr
+	}
+
+	rows, err := s.client.Query(ctx, sql, args...)
+	if err != nil {
+		err = db.ErrDoQuery(err)
+		logger.Error(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	list := make([]*ProductTranslationStorage, 0)
+
+	for rows.Next() {
+		ts := ProductTranslationStorage{}
+		if err = rows.Scan(
+			&ts.ProductID,
+			&ts.Locale,
+			&ts.Name,
+			&ts.Description,
+			&ts.CreatedAt,
+			&ts.UpdatedAt,
+		); err != nil {
+			err = db.ErrScan(err)
+			logger.Error(err)
+			return nil, err
+		}
+
+		list = append(list, &ts)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = db.ErrDoQuery(err)
+		logger.Error(err)
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,35 @@
+package model
+
+// FacetsOptions настраивает вычисление фасетов
+type FacetsOptions struct {
+	// PriceBounds are ascending bucket boundaries, price facet is skipped when empty
+	PriceBounds            []uint64
+	SpecificationKeysLimit uint64
+}
+
+type FacetValue struct {
+	Value string
+	Count uint64
+}
+
+// PriceBucket диапазон цен [From, To). Nil From или To означает открытую границу
+type PriceBucket struct {
+	From  *uint64
+	To    *uint64
+	Count uint64
+}
+
+type Facets struct {
+	Categories        []FacetValue
+	Prices            []PriceBucket
+	Ratings           []FacetValue
+	SpecificationKeys []FacetValue
+}
+
+// DistinctValues значения поля у отфильтрованных продуктов, начиная с самых частых
+type DistinctValues struct {
+	Field  string
+	Values []FacetValue
+	// Truncated is set when field takes more values than requested top
+	Truncated bool
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/HollyEllmo/my-first-go-project/internal/controller/grpc/types"
 	"github.com/HollyEllmo/my-first-go-project/pkg/api/filter"
 	"github.com/HollyEllmo/my-first-go-project/pkg/api/sort"
+	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
 	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
 	pb_prod_products "github.com/HollyEllmo/my-proto-repo/gen/go/prod_service/products/v1"
 )
@@ -14,72 +15,123 @@ const (
 	priceFilterField        = "price"
 	ratingFilterField      = "rating"
 	categoryIDFilterField = "category_id"
+	StatusFilterField     = "status"
+	effectivePriceFilterField = "effective_price"
+	// imageIDFilterField allows to filter products with or without image using `is null` and `is not null`
+	imageIDFilterField     = "image_id"
+	categoryNameFilterField = "category.name"
+	currencyNameFilterField = "currency.name"
+	currencySymbolFilterField = "currency.symbol"
 )
 
-func productsFilterFields() map[string]string {
+// ProductsFilterFields returns filterable product fields with their data types
+func ProductsFilterFields() map[string]string {
 	return map[string]string{
 		nameFilterField: filter.DataTypeStr,
 		descriptionFilterField: filter.DataTypeStr,
-		priceFilterField:      filter.DataTypeStr,
+		priceFilterField:      filter.DataTypeInt,
 		ratingFilterField:      filter.DataTypeInt,
-		categoryIDFilterField:  filter.DataTypeStr,
+		categoryIDFilterField:  filter.DataTypeInt,
+		StatusFilterField:      filter.DataTypeStr,
+		effectivePriceFilterField: filter.DataTypeInt,
+		imageIDFilterField:        filter.DataTypeStr,
+		categoryNameFilterField:   filter.DataTypeStr,
+		currencyNameFilterField:   filter.DataTypeStr,
+		currencySymbolFilterField: filter.DataTypeStr,
 	}
 }
 
-func ProductsSort(req *pb_prod_products.AllProductsRequest) sort.Sortable {
+// ProductsSortFields returns fields products can be sorted by, `specification.*` allows
+// any path inside specification, e.g. `specification.weight`
+func ProductsSortFields() []string {
+	return []string{
+		"id",
+		nameFilterField,
+		descriptionFilterField,
+		priceFilterField,
+		effectivePriceFilterField,
+		ratingFilterField,
+		categoryIDFilterField,
+		StatusFilterField,
+		"created_at",
+		"updated_at",
+		"specification.*",
+	}
+}
+
+// ProductsDistinctFields returns fields distinct values of which can be requested
+func ProductsDistinctFields() []string {
+	return []string{
+		categoryIDFilterField,
+		categoryNameFilterField,
+		"currency_id",
+		currencyNameFilterField,
+		currencySymbolFilterField,
+		ratingFilterField,
+		StatusFilterField,
+	}
+}
+
+func ProductsSort(req *pb_prod_products.AllProductsRequest) (sort.Sortable, error) {
 	field := req.GetSort().GetField()
-	return sort.NewOptions(field)
+	return sort.Parse(field, ProductsSortFields())
 }
 
-func ProductsFilter(req *pb_prod_products.AllProductsRequest) filter.Filterable {
+// ProductsFilter builds filter from request, all invalid fields are reported at once
+func ProductsFilter(req *pb_prod_products.AllProductsRequest) (filter.Filterable, error) {
+	// request without body has neither pagination nor filters
+	if req == nil {
+		return filter.NewOptions(0, 0, ProductsFilterFields()), nil
+	}
+
 	options := filter.NewOptions(
 		req.GetPagination().GetLimit(),
 		req.GetPagination().GetOffset(),
-		productsFilterFields(),
+		ProductsFilterFields(),
 	)
-	if req != nil {
-		return options
-	}
 
+	var err error
 	name := req.GetName()
 	if name != nil {
 		operator := types.StringOperatorFromPB(name.GetOp())
-		addFilterField(nameFilterField, name.GetVal(), operator, options)
+		err = addFilterField(nameFilterField, name.GetVal(), operator, options, err)
 	}
 
 	rating := req.GetRating()
 	if rating != nil {
 		operator := types.IntOperatorFromPB(rating.GetOp())
-		addFilterField(ratingFilterField, rating.GetVal(), operator, options)
+		err = addFilterField(ratingFilterField, rating.GetVal(), operator, options, err)
 	}
 	description := req.GetDescription()
 	if description != nil {
 		operator := types.StringOperatorFromPB(description.GetOp())
-		addFilterField(descriptionFilterField, description.GetVal(), operator, options)
+		err = addFilterField(descriptionFilterField, description.GetVal(), operator, options, err)
 	}
 	price := req.GetPrice()
 	if price != nil {
 		operator := types.IntOperatorFromPB(price.GetOp())
-		addFilterField(priceFilterField, price.GetVal(), operator, options)
+		err = addFilterField(priceFilterField, price.GetVal(), operator, options, err)
 	}
 	categoryId := req.GetCategoryId()
 	if categoryId != nil {
 		operator := types.IntOperatorFromPB(categoryId.GetOp())
-		addFilterField(categoryIDFilterField, categoryId.GetVal(), operator, options)
+		err = addFilterField(categoryIDFilterField, categoryId.GetVal(), operator, options, err)
 	}
 
-	return options
+	return options, err
 }
 
-func addFilterField(name, value string, 
+// addFilterField adds field to options and appends its problems to errs
+func addFilterField(name, value string,
 	operator filter.Operator,
 	options filter.Filterable,
-	) {
+	errs error,
+) error {
 	err := options.AddField(name, operator, value)
-		if err != nil {
-			logging.GetLogger().WithError(err).Errorf("failed to add filter field. name=%s, operator=%s, value=%s",
-				name, operator, value)
-		}
+	if err != nil {
+		logging.GetLogger().WithError(err).Errorf("failed to add filter field. name=%s, operator=%s, value=%s",
+			name, operator, value)
+		return errors.Append(errs, err)
+	}
+	return errs
 }
-
-
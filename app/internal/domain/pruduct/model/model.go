@@ -34,17 +34,31 @@ func NewProduct(
 }
 
 type Product struct {
-	ID            string 
-	Name          string 
-	Description   string 
-	ImageID       *string 
-	Price         uint64 
-	CurrencyID    uint32 
-	Rating        uint32 
-	CategoryID    uint32 // Changed to uint32 for consistency with proto definition
-	Specification string 
-	CreatedAt     time.Time 
-	UpdatedAt     *time.Time 
+	ID             string 
+	Name           string 
+	Description    string 
+	ImageID        *string 
+	Price          uint64 
+	CurrencyID     uint32 
+	Rating         uint32 
+	CategoryID     uint32 // Changed to uint32 for consistency with proto definition
+	Specification  string 
+	CreatedAt      time.Time 
+	UpdatedAt      *time.Time 
+	// Locale of Name and Description, empty when product has no suitable translation
+	Locale         string
+	Status         string
+	// EffectivePrice is current unit price after promotions
+	EffectivePrice uint64
+}
+
+type ProductTranslation struct {
+	ProductID   string
+	Locale      string
+	Name        string
+	Description string
+	CreatedAt   time.Time
+	UpdatedAt   *time.Time
 }
 
 func (p Product) ToProto() *pb_prod_products.Product {
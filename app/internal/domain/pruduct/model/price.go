@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+const (
+	PriceSourceCreate    = "create"
+	PriceSourceUpdate    = "update"
+	PriceSourceScheduled = "scheduled"
+
+	// AnonymousActor is recorded when change is made without authenticated user
+	AnonymousActor = "anonymous"
+)
+
+// PriceChange запись истории изменения цены продукта
+type PriceChange struct {
+	ID        string
+	ProductID string
+	// OldPrice is nil for the first price of product
+	OldPrice  *uint64
+	NewPrice  uint64
+	Actor     string
+	Source    string
+	ChangedAt time.Time
+}
+
+// PriceStatistics статистика цены. Статистика за 30 дней учитывает цену,
+// действовавшую на начало периода.
+type PriceStatistics struct {
+	Current    uint64
+	Min        uint64
+	Max        uint64
+	Min30d     uint64
+	Max30d     uint64
+	Changes30d uint64
+}
+
+type PriceHistory struct {
+	Changes    []*PriceChange
+	Statistics PriceStatistics
+}
+
+// PriceSchedule отложенное изменение цены продукта
+type PriceSchedule struct {
+	ID         string
+	ProductID  string
+	Price      uint64
+	Actor      string
+	RunAt      time.Time
+	CreatedAt  time.Time
+	ExecutedAt *time.Time
+	Error      *string
+}
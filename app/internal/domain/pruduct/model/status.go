@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+const (
+	StatusDraft     = "draft"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// StatusSchedule отложенная смена статуса продукта
+type StatusSchedule struct {
+	ID         string
+	ProductID  string
+	Status     string
+	RunAt      time.Time
+	CreatedAt  time.Time
+	ExecutedAt *time.Time
+	Error      *string
+}
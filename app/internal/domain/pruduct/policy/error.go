@@ -0,0 +1,44 @@
+package policy
+
+import (
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
+)
+
+// FieldViolation is product field rejected by database constraint
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// constraintViolations maps constraints of product tables to violated fields
+var constraintViolations = map[string]FieldViolation{
+	"positive_price":                      {Field: "price", Description: "price must not be negative"},
+	"valid_rating":                        {Field: "rating", Description: "rating must not be greater than 5"},
+	"valid_status":                        {Field: "status", Description: "status must be draft, published or archived"},
+	"valid_locale":                        {Field: "locale", Description: "locale must be lower case"},
+	"product_category_id_fkey":            {Field: "category_id", Description: "category does not exist"},
+	"product_currency_id_fkey":            {Field: "currency_id", Description: "currency does not exist"},
+	"product_pkey":                        {Field: "id", Description: "product with this id already exists"},
+	"product_translation_product_id_fkey": {Field: "product_id", Description: "product does not exist"},
+}
+
+// Violations returns product fields which violate database constraints according to err,
+// nil when err isn't constraint violation
+func Violations(err error) []FieldViolation {
+	var dbErr *postgresql.Error
+	if !errors.As(err, &dbErr) ||
+		!errors.Is(dbErr, postgresql.ErrCheckViolation) &&
+			!errors.Is(dbErr, postgresql.ErrForeignKeyViolation) &&
+			!errors.Is(dbErr, postgresql.ErrAlreadyExists) {
+		return nil
+	}
+
+	if v, ok := constraintViolations[dbErr.Constraint]; ok {
+		return []FieldViolation{v}
+	}
+	if dbErr.Column != "" {
+		return []FieldViolation{{Field: dbErr.Column, Description: dbErr.Kind.Error()}}
+	}
+	return nil
+}
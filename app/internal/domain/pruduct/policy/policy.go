@@ -2,33 +2,110 @@ package policy
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/HollyEllmo/my-first-go-project/internal/controller/dto"
+	promotion "github.com/HollyEllmo/my-first-go-project/internal/domain/promotion/model"
 	"github.com/HollyEllmo/my-first-go-project/internal/domain/pruduct/model"
 	"github.com/HollyEllmo/my-first-go-project/pkg/api/filter"
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/jwt"
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/locale"
 	"github.com/HollyEllmo/my-first-go-project/pkg/api/sort"
 	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
 )
 
+var (
+	ErrUnsupportedLocale   = errors.New("unsupported locale")
+	ErrProductNotFound     = errors.New("product not found")
+	ErrPermissionDenied    = errors.New("permission denied")
+	ErrUnknownStatus       = errors.New("unknown product status")
+	ErrForbiddenTransition = errors.New("forbidden product status transition")
+	ErrScheduleInPast      = errors.New("schedule time must be in the future")
+	ErrUnsupportedField    = errors.New("unsupported field")
+	ErrEmptyName           = errors.New("name must not be empty")
+)
+
 type productService interface {
 	All(ctx context.Context, filtering filter.Filterable, sorting sort.Sortable) ([]*model.Product, error)
 	Create(ctx context.Context, dto *dto.CreateProductDTO) (*model.Product, error)
 	One(ctx context.Context, id string) (*model.Product, error)
+	OnePrimary(ctx context.Context, id string) (*model.Product, error)
 	Delete(ctx context.Context, id string) error
 	Update(ctx context.Context, id string, dto *dto.UpdateProductDTO) error
+	UpsertTranslation(ctx context.Context, productID string, dto *dto.ProductTranslationDTO) error
+	Translations(ctx context.Context, productID string) ([]*model.ProductTranslation, error)
+	ChangeStatus(ctx context.Context, id, from, to string) error
+	ScheduleStatus(ctx context.Context, id, status string, runAt time.Time) (*model.StatusSchedule, error)
+	StatusSchedules(ctx context.Context, productID string) ([]*model.StatusSchedule, error)
+	DueStatusSchedules(ctx context.Context, now time.Time, limit uint64) ([]*model.StatusSchedule, error)
+	CompleteStatusSchedule(ctx context.Context, id string, scheduleErr error) error
+	Facets(ctx context.Context, filtering filter.Filterable, opts model.FacetsOptions) (*model.Facets, error)
+	Count(ctx context.Context, filtering filter.Filterable) (uint64, error)
+	DistinctValues(ctx context.Context, filtering filter.Filterable, field string, top uint64) (*model.DistinctValues, error)
+	ChangePrice(ctx context.Context, id string, from, to uint64, actor, source string) error
+	PriceHistory(ctx context.Context, id string, now time.Time) (*model.PriceHistory, error)
+	SchedulePrice(ctx context.Context, id string, price uint64, actor string, runAt time.Time) (*model.PriceSchedule, error)
+	PriceSchedules(ctx context.Context, id string) ([]*model.PriceSchedule, error)
+	DuePriceSchedules(ctx context.Context, now time.Time, limit uint64) ([]*model.PriceSchedule, error)
+	CompletePriceSchedule(ctx context.Context, id string, scheduleErr error) error
+}
+
+type pricingService interface {
+	EffectivePrice(ctx context.Context, productID string, categoryID uint32, list uint64, at time.Time, quantity uint32) (*promotion.Price, error)
+}
+
+type localeNegotiator interface {
+	IsSupported(locale string) bool
 }
 
 type ProductPolicy struct {
 	productService productService
+	pricing        pricingService
+	locales        localeNegotiator
+	editorRoles    []uint64
 }
 
-func NewProductPolicy(productService productService) *ProductPolicy {
+func NewProductPolicy(
+	productService productService,
+	pricing pricingService,
+	locales localeNegotiator,
+	editorRoles []uint64,
+) *ProductPolicy {
 	return &ProductPolicy{
 		productService: productService,
+		pricing:        pricing,
+		locales:        locales,
+		editorRoles:    editorRoles,
 	}
 }
 
+// isEditor reports whether request is made by user who can see products in any status
+func (p *ProductPolicy) isEditor(ctx context.Context) bool {
+	return jwt.HasRole(ctx, p.editorRoles...)
+}
+
+// restrictVisibility limits filtering to published products unless user is editor
+func (p *ProductPolicy) restrictVisibility(ctx context.Context, filtering filter.Filterable) error {
+	if p.isEditor(ctx) {
+		return nil
+	}
+
+	err := filtering.AddField(model.StatusFilterField, filter.OperatorEq, model.StatusPublished)
+	if err != nil {
+		return errors.Wrap(err, "filtering.AddField")
+	}
+
+	return nil
+}
+
 func (p *ProductPolicy) All(ctx context.Context, filtering filter.Filterable, sorting sort.Sortable) ([]*model.Product, error) {
+	if err := p.restrictVisibility(ctx, filtering); err != nil {
+		return nil, err
+	}
+
 	products, err := p.productService.All(ctx, filtering, sorting)
 	if err != nil {
 		return nil, errors.Wrap(err, "productService.All")
@@ -38,6 +115,10 @@ func (p *ProductPolicy) All(ctx context.Context, filtering filter.Filterable, so
 }
 
 func (p *ProductPolicy) CreateProduct(ctx context.Context, d *dto.CreateProductDTO) (*model.Product, error) {
+	if !p.isEditor(ctx) {
+		return nil, ErrPermissionDenied
+	}
+
 	return p.productService.Create(ctx, d)
 }
 
@@ -46,15 +127,115 @@ func (p *ProductPolicy) One(ctx context.Context, id string) (*model.Product, err
 	if err != nil {
 		return nil, errors.Wrap(err, "productService.One")
 	}
+	if product.Status != model.StatusPublished && !p.isEditor(ctx) {
+		return nil, ErrProductNotFound
+	}
 
 	return product, nil
 }
 
 func (p *ProductPolicy) Delete(ctx context.Context, id string) error {
-   return p.productService.Delete(ctx, id)
+	if !p.isEditor(ctx) {
+		return ErrPermissionDenied
+	}
+
+	return p.productService.Delete(ctx, id)
 }
 
 func (p *ProductPolicy) Update(ctx context.Context, id string, d *dto.UpdateProductDTO) error {
-   return p.productService.Update(ctx, id, d)
+	if !p.isEditor(ctx) {
+		return ErrPermissionDenied
+	}
+
+	return p.productService.Update(ctx, id, d)
+}
+
+func (p *ProductPolicy) UpsertTranslation(ctx context.Context, productID string, d *dto.ProductTranslationDTO) error {
+	d.Locale = locale.Normalize(d.Locale)
+	if !p.locales.IsSupported(d.Locale) {
+		return ErrUnsupportedLocale
+	}
+	if strings.TrimSpace(d.Name) == "" {
+		return ErrEmptyName
+	}
+
+	err := p.productService.UpsertTranslation(ctx, productID, d)
+	if err != nil {
+		return errors.Wrap(err, "productService.UpsertTranslation")
+	}
+
+	return nil
+}
+
+func (p *ProductPolicy) Translations(ctx context.Context, productID string) ([]*model.ProductTranslation, error) {
+	// переводы видны только вместе с продуктом
+	if _, err := p.One(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	translations, err := p.productService.Translations(ctx, productID)
+	if err != nil {
+		return nil, errors.Wrap(err, "productService.Translations")
+	}
+
+	return translations, nil
+}
+
+func (p *ProductPolicy) Facets(ctx context.Context, filtering filter.Filterable, opts model.FacetsOptions) (*model.Facets, error) {
+	if err := p.restrictVisibility(ctx, filtering); err != nil {
+		return nil, err
+	}
+
+	facets, err := p.productService.Facets(ctx, filtering, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "productService.Facets")
+	}
+
+	return facets, nil
+}
+
+// Count считает видимые пользователю продукты, подходящие под фильтр
+func (p *ProductPolicy) Count(ctx context.Context, filtering filter.Filterable) (uint64, error) {
+	if err := p.restrictVisibility(ctx, filtering); err != nil {
+		return 0, err
+	}
+
+	count, err := p.productService.Count(ctx, filtering)
+	if err != nil {
+		return 0, errors.Wrap(err, "productService.Count")
+	}
+
+	return count, nil
 }
 
+// DistinctValues возвращает значения поля из model.ProductsDistinctFields у видимых пользователю продуктов
+func (p *ProductPolicy) DistinctValues(ctx context.Context, filtering filter.Filterable, field string, top uint64) (*model.DistinctValues, error) {
+	if !slices.Contains(model.ProductsDistinctFields(), field) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedField, field)
+	}
+	if err := p.restrictVisibility(ctx, filtering); err != nil {
+		return nil, err
+	}
+
+	values, err := p.productService.DistinctValues(ctx, filtering, field, top)
+	if err != nil {
+		return nil, errors.Wrap(err, "productService.DistinctValues")
+	}
+
+	return values, nil
+}
+
+// EffectivePrice вычисляет цену единицы продукта с учетом промоакций в момент at
+func (p *ProductPolicy) EffectivePrice(ctx context.Context, id string, at time.Time, quantity uint32) (*promotion.Price, error) {
+	product, err := p.One(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := p.pricing.EffectivePrice(ctx, product.ID, product.CategoryID, product.Price, at, quantity)
+	if err != nil {
+		return nil, errors.Wrap(err, "pricing.EffectivePrice")
+	}
+
+	return price, nil
+}
@@ -0,0 +1,110 @@
+package policy
+
+import (
+	"context"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/internal/domain/pruduct/model"
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/jwt"
+	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+)
+
+// ChangePrice меняет цену продукта сразу
+func (p *ProductPolicy) ChangePrice(ctx context.Context, id string, price uint64) error {
+	if !p.isEditor(ctx) {
+		return ErrPermissionDenied
+	}
+
+	return p.changePrice(ctx, id, price, jwt.ActorFromContext(ctx, model.AnonymousActor), model.PriceSourceUpdate)
+}
+
+// SchedulePrice планирует смену цены продукта на время runAt
+func (p *ProductPolicy) SchedulePrice(ctx context.Context, id string, price uint64, runAt time.Time) (*model.PriceSchedule, error) {
+	if !p.isEditor(ctx) {
+		return nil, ErrPermissionDenied
+	}
+	if !runAt.After(time.Now()) {
+		return nil, ErrScheduleInPast
+	}
+
+	if _, err := p.productService.One(ctx, id); err != nil {
+		return nil, errors.Wrap(err, "productService.One")
+	}
+
+	schedule, err := p.productService.SchedulePrice(ctx, id, price, jwt.ActorFromContext(ctx, model.AnonymousActor), runAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "productService.SchedulePrice")
+	}
+
+	return schedule, nil
+}
+
+func (p *ProductPolicy) PriceSchedules(ctx context.Context, id string) ([]*model.PriceSchedule, error) {
+	if !p.isEditor(ctx) {
+		return nil, ErrPermissionDenied
+	}
+
+	schedules, err := p.productService.PriceSchedules(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "productService.PriceSchedules")
+	}
+
+	return schedules, nil
+}
+
+func (p *ProductPolicy) PriceHistory(ctx context.Context, id string) (*model.PriceHistory, error) {
+	if !p.isEditor(ctx) {
+		return nil, ErrPermissionDenied
+	}
+
+	history, err := p.productService.PriceHistory(ctx, id, time.Now())
+	if err != nil {
+		return nil, errors.Wrap(err, "productService.PriceHistory")
+	}
+
+	return history, nil
+}
+
+// RunDuePriceSchedules выполняет наступившие смены цены и возвращает их количество
+func (p *ProductPolicy) RunDuePriceSchedules(ctx context.Context, now time.Time, limit uint64) (int, error) {
+	schedules, err := p.productService.DuePriceSchedules(ctx, now, limit)
+	if err != nil {
+		return 0, errors.Wrap(err, "productService.DuePriceSchedules")
+	}
+
+	for _, schedule := range schedules {
+		changeErr := p.changePrice(ctx, schedule.ProductID, schedule.Price, schedule.Actor, model.PriceSourceScheduled)
+		if changeErr != nil {
+			logging.WithFields(ctx, map[string]interface{}{
+				"schedule_id": schedule.ID,
+				"product_id":  schedule.ProductID,
+				"price":       schedule.Price,
+			}).WithError(changeErr).Warn("scheduled price change failed")
+		}
+
+		if err = p.productService.CompletePriceSchedule(ctx, schedule.ID, changeErr); err != nil {
+			return 0, errors.Wrap(err, "productService.CompletePriceSchedule")
+		}
+	}
+
+	return len(schedules), nil
+}
+
+func (p *ProductPolicy) changePrice(ctx context.Context, id string, price uint64, actor, source string) error {
+	product, err := p.productService.OnePrimary(ctx, id)
+	if err != nil {
+		return errors.Wrap(err, "productService.OnePrimary")
+	}
+
+	if product.Price == price {
+		return nil
+	}
+
+	err = p.productService.ChangePrice(ctx, id, product.Price, price, actor, source)
+	if err != nil {
+		return errors.Wrap(err, "productService.ChangePrice")
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/internal/domain/pruduct/model"
+	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+)
+
+// statusTransitions описывает допустимые переходы между статусами продукта
+var statusTransitions = map[string][]string{
+	model.StatusDraft:     {model.StatusPublished, model.StatusArchived},
+	model.StatusPublished: {model.StatusDraft, model.StatusArchived},
+	model.StatusArchived:  {model.StatusDraft},
+}
+
+func validateStatus(status string) error {
+	if _, ok := statusTransitions[status]; !ok {
+		return ErrUnknownStatus
+	}
+	return nil
+}
+
+func validateTransition(from, to string) error {
+	for _, allowed := range statusTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s -> %s", ErrForbiddenTransition, from, to)
+}
+
+// ChangeStatus меняет статус продукта сразу
+func (p *ProductPolicy) ChangeStatus(ctx context.Context, id, status string) error {
+	if !p.isEditor(ctx) {
+		return ErrPermissionDenied
+	}
+	if err := validateStatus(status); err != nil {
+		return err
+	}
+
+	return p.changeStatus(ctx, id, status)
+}
+
+// ScheduleStatus планирует смену статуса продукта на время runAt.
+// Допустимость перехода проверяется в момент выполнения.
+func (p *ProductPolicy) ScheduleStatus(ctx context.Context, id, status string, runAt time.Time) (*model.StatusSchedule, error) {
+	if !p.isEditor(ctx) {
+		return nil, ErrPermissionDenied
+	}
+	if err := validateStatus(status); err != nil {
+		return nil, err
+	}
+	if !runAt.After(time.Now()) {
+		return nil, ErrScheduleInPast
+	}
+
+	if _, err := p.productService.One(ctx, id); err != nil {
+		return nil, errors.Wrap(err, "productService.One")
+	}
+
+	schedule, err := p.productService.ScheduleStatus(ctx, id, status, runAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "productService.ScheduleStatus")
+	}
+
+	return schedule, nil
+}
+
+func (p *ProductPolicy) StatusSchedules(ctx context.Context, id string) ([]*model.StatusSchedule, error) {
+	if !p.isEditor(ctx) {
+		return nil, ErrPermissionDenied
+	}
+
+	schedules, err := p.productService.StatusSchedules(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "productService.StatusSchedules")
+	}
+
+	return schedules, nil
+}
+
+// RunDueStatusSchedules выполняет наступившие смены статуса и возвращает их количество
+func (p *ProductPolicy) RunDueStatusSchedules(ctx context.Context, now time.Time, limit uint64) (int, error) {
+	schedules, err := p.productService.DueStatusSchedules(ctx, now, limit)
+	if err != nil {
+		return 0, errors.Wrap(err, "productService.DueStatusSchedules")
+	}
+
+	for _, schedule := range schedules {
+		changeErr := p.changeStatus(ctx, schedule.ProductID, schedule.Status)
+		if changeErr != nil {
+			logging.WithFields(ctx, map[string]interface{}{
+				"schedule_id": schedule.ID,
+				"product_id":  schedule.ProductID,
+				"status":      schedule.Status,
+			}).WithError(changeErr).Warn("scheduled status change failed")
+		}
+
+		if err = p.productService.CompleteStatusSchedule(ctx, schedule.ID, changeErr); err != nil {
+			return 0, errors.Wrap(err, "productService.CompleteStatusSchedule")
+		}
+	}
+
+	return len(schedules), nil
+}
+
+func (p *ProductPolicy) changeStatus(ctx context.Context, id, status string) error {
+	product, err := p.productService.OnePrimary(ctx, id)
+	if err != nil {
+		return errors.Wrap(err, "productService.OnePrimary")
+	}
+
+	if err = validateTransition(product.Status, status); err != nil {
+		return err
+	}
+
+	err = p.productService.ChangeStatus(ctx, id, product.Status, status)
+	if err != nil {
+		return errors.Wrap(err, "productService.ChangeStatus")
+	}
+
+	return nil
+}
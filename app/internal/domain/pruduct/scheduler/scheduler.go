@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+)
+
+// RunFunc выполняет не более limit наступивших отложенных изменений и возвращает их количество
+type RunFunc func(ctx context.Context, now time.Time, limit uint64) (int, error)
+
+// Task отложенные изменения одного вида, например смены статуса или цены
+type Task struct {
+	Name string
+	Run  RunFunc
+}
+
+// Scheduler периодически выполняет отложенные изменения продуктов
+type Scheduler struct {
+	tasks     []Task
+	interval  time.Duration
+	batchSize uint64
+}
+
+func NewScheduler(interval time.Duration, batchSize uint64, tasks ...Task) *Scheduler {
+	return &Scheduler{
+		tasks:     tasks,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run блокируется до отмены контекста
+func (s *Scheduler) Run(ctx context.Context) error {
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"interval":   s.interval,
+		"batch_size": s.batchSize,
+		"tasks":      len(s.tasks),
+	})
+	logger.Println("product scheduler started")
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Println("product scheduler stopped")
+			return nil
+		case now := <-ticker.C:
+			for _, task := range s.tasks {
+				s.tick(ctx, task, now)
+			}
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context, task Task, now time.Time) {
+	for {
+		n, err := task.Run(ctx, now, s.batchSize)
+		if err != nil {
+			logging.WithError(ctx, err).Errorf("failed to run product %s schedules", task.Name)
+			return
+		}
+		if n > 0 {
+			logging.Infof(ctx, "executed %d product %s schedules", n, task.Name)
+		}
+		// неполная пачка означает, что наступивших изменений больше нет
+		if n == 0 || uint64(n) < s.batchSize {
+			return
+		}
+	}
+}
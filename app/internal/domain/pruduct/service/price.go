@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/internal/domain/pruduct/dao"
+	"github.com/HollyEllmo/my-first-go-project/internal/domain/pruduct/model"
+	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
+)
+
+const priceStatisticsDays = 30
+
+// convertPriceChangeStorageToModel конвертирует PriceChangeStorage в модель PriceChange
+func convertPriceChangeStorageToModel(pc *dao.PriceChangeStorage) *model.PriceChange {
+	var oldPrice *uint64
+	if pc.OldPrice.Valid {
+		price := uint64(pc.OldPrice.Int64)
+		oldPrice = &price
+	}
+
+	return &model.PriceChange{
+		ID:        pc.ID,
+		ProductID: pc.ProductID,
+		OldPrice:  oldPrice,
+		NewPrice:  pc.NewPrice,
+		Actor:     pc.Actor,
+		Source:    pc.Source,
+		ChangedAt: pc.ChangedAt,
+	}
+}
+
+// convertPriceScheduleStorageToModel конвертирует PriceScheduleStorage в модель PriceSchedule
+func convertPriceScheduleStorageToModel(ps *dao.PriceScheduleStorage) *model.PriceSchedule {
+	createdAt := time.Now()
+	if ps.CreatedAt.Valid {
+		createdAt = ps.CreatedAt.Time
+	}
+
+	var executedAt *time.Time
+	if ps.ExecutedAt.Valid {
+		executedAt = &ps.ExecutedAt.Time
+	}
+
+	var errText *string
+	if ps.Error.Valid {
+		errText = &ps.Error.String
+	}
+
+	return &model.PriceSchedule{
+		ID:         ps.ID,
+		ProductID:  ps.ProductID,
+		Price:      ps.Price,
+		Actor:      ps.Actor,
+		RunAt:      ps.RunAt,
+		CreatedAt:  createdAt,
+		ExecutedAt: executedAt,
+		Error:      errText,
+	}
+}
+
+func convertPriceSchedulesStorageToModel(list []*dao.PriceScheduleStorage) []*model.PriceSchedule {
+	schedules := make([]*model.PriceSchedule, len(list))
+	for i, ps := range list {
+		schedules[i] = convertPriceScheduleStorageToModel(ps)
+	}
+	return schedules
+}
+
+// ChangePrice меняет цену с from на to и пишет изменение в историю
+func (s *Service) ChangePrice(ctx context.Context, id string, from, to uint64, actor, source string) error {
+	return s.tx.Do(ctx, func(ctx context.Context) error {
+		err := s.repository.ChangePrice(ctx, id, from, to)
+		if err != nil {
+			return errors.Wrap(err, "repository.ChangePrice")
+		}
+
+		err = s.repository.CreatePriceChange(ctx, dao.NewPriceChangeStorage(id, &from, to, actor, source))
+		if err != nil {
+			return errors.Wrap(err, "repository.CreatePriceChange")
+		}
+
+		return nil
+	})
+}
+
+// PriceHistory возвращает историю цены продукта со статистикой на момент now
+func (s *Service) PriceHistory(ctx context.Context, id string, now time.Time) (*model.PriceHistory, error) {
+	list, err := s.repository.PriceHistory(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.PriceHistory")
+	}
+
+	changes := make([]*model.PriceChange, len(list))
+	for i, pc := range list {
+		changes[i] = convertPriceChangeStorageToModel(pc)
+	}
+
+	return &model.PriceHistory{
+		Changes:    changes,
+		Statistics: priceStatistics(changes, now.AddDate(0, 0, -priceStatisticsDays)),
+	}, nil
+}
+
+// priceStatistics считает статистику по истории, упорядоченной по времени.
+// Цена, действовавшая на момент since, входит в статистику периода.
+func priceStatistics(changes []*model.PriceChange, since time.Time) model.PriceStatistics {
+	var stats model.PriceStatistics
+	if len(changes) == 0 {
+		return stats
+	}
+
+	stats.Min, stats.Max = changes[0].NewPrice, changes[0].NewPrice
+	periodStarted := false
+	for i, c := range changes {
+		stats.Min = min(stats.Min, c.NewPrice)
+		stats.Max = max(stats.Max, c.NewPrice)
+
+		if c.ChangedAt.Before(since) {
+			// цена действует на начало периода, если следующее изменение было уже в периоде
+			if i+1 < len(changes) && changes[i+1].ChangedAt.Before(since) {
+				continue
+			}
+		} else {
+			stats.Changes30d++
+		}
+
+		if !periodStarted {
+			stats.Min30d, stats.Max30d = c.NewPrice, c.NewPrice
+			periodStarted = true
+		}
+		stats.Min30d = min(stats.Min30d, c.NewPrice)
+		stats.Max30d = max(stats.Max30d, c.NewPrice)
+	}
+	stats.Current = changes[len(changes)-1].NewPrice
+
+	return stats
+}
+
+func (s *Service) SchedulePrice(ctx context.Context, id string, price uint64, actor string, runAt time.Time) (*model.PriceSchedule, error) {
+	storageDTO := dao.NewPriceScheduleStorage(id, price, actor, runAt)
+
+	err := s.repository.CreatePriceSchedule(ctx, storageDTO)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.CreatePriceSchedule")
+	}
+
+	return convertPriceScheduleStorageToModel(storageDTO), nil
+}
+
+func (s *Service) PriceSchedules(ctx context.Context, id string) ([]*model.PriceSchedule, error) {
+	list, err := s.repository.PriceSchedules(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.PriceSchedules")
+	}
+
+	return convertPriceSchedulesStorageToModel(list), nil
+}
+
+func (s *Service) DuePriceSchedules(ctx context.Context, now time.Time, limit uint64) ([]*model.PriceSchedule, error) {
+	list, err := s.repository.DuePriceSchedules(ctx, now, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.DuePriceSchedules")
+	}
+
+	return convertPriceSchedulesStorageToModel(list), nil
+}
+
+func (s *Service) CompletePriceSchedule(ctx context.Context, id string, scheduleErr error) error {
+	var errText *string
+	if scheduleErr != nil {
+		text := scheduleErr.Error()
+		errText = &text
+	}
+
+	err := s.repository.CompletePriceSchedule(ctx, id, errText)
+	if err != nil {
+		return errors.Wrap(err, "repository.CompletePriceSchedule")
+	}
+
+	return nil
+}
@@ -3,12 +3,15 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/HollyEllmo/my-first-go-project/internal/controller/dto"
 	"github.com/HollyEllmo/my-first-go-project/internal/domain/pruduct/dao"
 	"github.com/HollyEllmo/my-first-go-project/internal/domain/pruduct/model"
 	"github.com/HollyEllmo/my-first-go-project/pkg/api/filter"
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/jwt"
 	"github.com/HollyEllmo/my-first-go-project/pkg/api/sort"
 	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
 	"github.com/mitchellh/mapstructure"
@@ -55,24 +58,78 @@ func convertProductStorageToModel(ps *dao.ProductStorage) *model.Product {
 		Specification: specificationStr,
 		CreatedAt:     createdAt,
 		UpdatedAt:     updatedAt,
+		Locale:        ps.Locale,
+		Status:        ps.Status,
+		EffectivePrice: ps.EffectivePrice,
+	}
+}
+
+// convertTranslationStorageToModel конвертирует ProductTranslationStorage в модель ProductTranslation
+func convertTranslationStorageToModel(ts *dao.ProductTranslationStorage) *model.ProductTranslation {
+	var updatedAt *time.Time
+	if ts.UpdatedAt.Valid {
+		if parsed, err := time.Parse(time.RFC3339, ts.UpdatedAt.String); err == nil {
+			updatedAt = &parsed
+		}
+	}
+
+	createdAt := time.Now()
+	if ts.CreatedAt.Valid {
+		if parsed, err := time.Parse(time.RFC3339, ts.CreatedAt.String); err == nil {
+			createdAt = parsed
+		}
+	}
+
+	return &model.ProductTranslation{
+		ProductID:   ts.ProductID,
+		Locale:      ts.Locale,
+		Name:        ts.Name,
+		Description: ts.Description,
+		CreatedAt:   createdAt,
+		UpdatedAt:   updatedAt,
 	}
 }
 
 type repository interface {
 	All(ctx context.Context, filtering filter.Filterable, sorting sort.Sortable) ([]*dao.ProductStorage, error)
 	One(ctx context.Context, id string) (*dao.ProductStorage, error)
+	OnePrimary(ctx context.Context, id string) (*dao.ProductStorage, error)
 	Create(ctx context.Context, dto *dao.CreateProductStorageDTO) error
 	Delete(ctx context.Context, id string) error
 	Update(ctx context.Context, id string, dm map[string]interface{}) error
+	UpsertTranslation(ctx context.Context, dto *dao.ProductTranslationStorage) error
+	Translations(ctx context.Context, productID string) ([]*dao.ProductTranslationStorage, error)
+	ChangeStatus(ctx context.Context, id, from, to string) error
+	CreateStatusSchedule(ctx context.Context, dto *dao.StatusScheduleStorage) error
+	StatusSchedules(ctx context.Context, productID string) ([]*dao.StatusScheduleStorage, error)
+	DueStatusSchedules(ctx context.Context, now time.Time, limit uint64) ([]*dao.StatusScheduleStorage, error)
+	CompleteStatusSchedule(ctx context.Context, id string, errText *string) error
+	Facets(ctx context.Context, filtering filter.Filterable, priceBounds []uint64, specKeysLimit uint64) (*dao.FacetsStorage, error)
+	Count(ctx context.Context, filtering filter.Filterable) (uint64, error)
+	DistinctValues(ctx context.Context, filtering filter.Filterable, field string, limit uint64) ([]dao.FacetValueStorage, error)
+	ChangePrice(ctx context.Context, id string, from, to uint64) error
+	CreatePriceChange(ctx context.Context, dto *dao.PriceChangeStorage) error
+	PriceHistory(ctx context.Context, productID string) ([]*dao.PriceChangeStorage, error)
+	CreatePriceSchedule(ctx context.Context, dto *dao.PriceScheduleStorage) error
+	PriceSchedules(ctx context.Context, productID string) ([]*dao.PriceScheduleStorage, error)
+	DuePriceSchedules(ctx context.Context, now time.Time, limit uint64) ([]*dao.PriceScheduleStorage, error)
+	CompletePriceSchedule(ctx context.Context, id string, errText *string) error
+}
+
+// transactor выполняет fn в транзакции, которую репозиторий берет из контекста
+type transactor interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type Service struct {
 	repository repository
+	tx         transactor
 }
 
-func NewProductService(repository repository) *Service {
+func NewProductService(repository repository, tx transactor) *Service {
 	return &Service{
 		repository: repository,
+		tx:         tx,
 	}
 }
 
@@ -93,12 +150,25 @@ func (s *Service) All(ctx context.Context, filtering filter.Filterable, sorting
 
 func (s *Service) Create(ctx context.Context, d *dto.CreateProductDTO) (*model.Product, error) {
 	createProductStorageDTO := dao.NewCreateProductStorageDTO(d)
-	
-	err := s.repository.Create(ctx, createProductStorageDTO)
-	if err != nil {
-		return nil, err
-	}
-	one, err := s.repository.One(ctx, createProductStorageDTO.ID)
+	// Новый продукт не виден в публичных списках до публикации
+	createProductStorageDTO.Status = model.StatusDraft
+
+	// Продукт, начальная цена в истории и чтение созданной записи выполняются атомарно
+	var one *dao.ProductStorage
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		err := s.repository.Create(ctx, createProductStorageDTO)
+		if err != nil {
+			return err
+		}
+		err = s.repository.CreatePriceChange(ctx, dao.NewPriceChangeStorage(
+			createProductStorageDTO.ID, nil, createProductStorageDTO.Price, jwt.ActorFromContext(ctx, model.AnonymousActor), model.PriceSourceCreate,
+		))
+		if err != nil {
+			return errors.Wrap(err, "repository.CreatePriceChange")
+		}
+		one, err = s.repository.One(ctx, createProductStorageDTO.ID)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -118,6 +188,16 @@ func (s *Service) One(ctx context.Context, id string) (*model.Product, error) {
 	return product, nil
 }
 
+// OnePrimary возвращает актуальное состояние продукта, см. dao.ProductDAO.OnePrimary
+func (s *Service) OnePrimary(ctx context.Context, id string) (*model.Product, error) {
+	one, err := s.repository.OnePrimary(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.OnePrimary")
+	}
+
+	return convertProductStorageToModel(one), nil
+}
+
 func (s *Service) Delete(ctx context.Context, id string) error {
 	return s.repository.Delete(ctx, id)
 }
@@ -131,6 +211,259 @@ func (s *Service) Update(ctx context.Context, id string, d *dto.UpdateProductDTO
 		return  errors.Wrap(err, "mapstructure.Decode UpdateProductDTO")
 	}
 
-	// Обновляем продукт в репозитории
-	return s.repository.Update(ctx, id, updateProductMap)
-}
\ No newline at end of file
+	// Изменение продукта и запись в историю цены выполняются атомарно
+	return s.tx.Do(ctx, func(ctx context.Context) error {
+		// Старая цена нужна для истории изменения цены
+		var current *dao.ProductStorage
+		if d.Price != nil {
+			current, err = s.repository.One(ctx, id)
+			if err != nil {
+				return errors.Wrap(err, "repository.One")
+			}
+		}
+
+		// Обновляем продукт в репозитории
+		err = s.repository.Update(ctx, id, updateProductMap)
+		if err != nil {
+			return err
+		}
+
+		if current != nil && current.Price != *d.Price {
+			err = s.repository.CreatePriceChange(ctx, dao.NewPriceChangeStorage(
+				id, &current.Price, *d.Price, jwt.ActorFromContext(ctx, model.AnonymousActor), model.PriceSourceUpdate,
+			))
+			if err != nil {
+				return errors.Wrap(err, "repository.CreatePriceChange")
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *Service) UpsertTranslation(ctx context.Context, productID string, d *dto.ProductTranslationDTO) error {
+	// Проверяем, что продукт существует
+	if _, err := s.repository.One(ctx, productID); err != nil {
+		return errors.Wrap(err, "repository.One")
+	}
+
+	err := s.repository.UpsertTranslation(ctx, dao.NewProductTranslationStorage(productID, d))
+	if err != nil {
+		return errors.Wrap(err, "repository.UpsertTranslation")
+	}
+
+	return nil
+}
+
+func (s *Service) Translations(ctx context.Context, productID string) ([]*model.ProductTranslation, error) {
+	dbTranslations, err := s.repository.Translations(ctx, productID)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.Translations")
+	}
+
+	translations := make([]*model.ProductTranslation, len(dbTranslations))
+	for i, ts := range dbTranslations {
+		translations[i] = convertTranslationStorageToModel(ts)
+	}
+
+	return translations, nil
+}
+
+// convertScheduleStorageToModel конвертирует StatusScheduleStorage в модель StatusSchedule
+func convertScheduleStorageToModel(ss *dao.StatusScheduleStorage) *model.StatusSchedule {
+	createdAt := time.Now()
+	if ss.CreatedAt.Valid {
+		createdAt = ss.CreatedAt.Time
+	}
+
+	var executedAt *time.Time
+	if ss.ExecutedAt.Valid {
+		executedAt = &ss.ExecutedAt.Time
+	}
+
+	var errText *string
+	if ss.Error.Valid {
+		errText = &ss.Error.String
+	}
+
+	return &model.StatusSchedule{
+		ID:         ss.ID,
+		ProductID:  ss.ProductID,
+		Status:     ss.Status,
+		RunAt:      ss.RunAt,
+		CreatedAt:  createdAt,
+		ExecutedAt: executedAt,
+		Error:      errText,
+	}
+}
+
+func convertSchedulesStorageToModel(list []*dao.StatusScheduleStorage) []*model.StatusSchedule {
+	schedules := make([]*model.StatusSchedule, len(list))
+	for i, ss := range list {
+		schedules[i] = convertScheduleStorageToModel(ss)
+	}
+	return schedules
+}
+
+func (s *Service) ChangeStatus(ctx context.Context, id, from, to string) error {
+	err := s.repository.ChangeStatus(ctx, id, from, to)
+	if err != nil {
+		return errors.Wrap(err, "repository.ChangeStatus")
+	}
+
+	return nil
+}
+
+func (s *Service) ScheduleStatus(ctx context.Context, id, status string, runAt time.Time) (*model.StatusSchedule, error) {
+	storageDTO := dao.NewStatusScheduleStorage(id, status, runAt)
+
+	err := s.repository.CreateStatusSchedule(ctx, storageDTO)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.CreateStatusSchedule")
+	}
+
+	return convertScheduleStorageToModel(storageDTO), nil
+}
+
+func (s *Service) StatusSchedules(ctx context.Context, productID string) ([]*model.StatusSchedule, error) {
+	list, err := s.repository.StatusSchedules(ctx, productID)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.StatusSchedules")
+	}
+
+	return convertSchedulesStorageToModel(list), nil
+}
+
+func (s *Service) DueStatusSchedules(ctx context.Context, now time.Time, limit uint64) ([]*model.StatusSchedule, error) {
+	list, err := s.repository.DueStatusSchedules(ctx, now, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.DueStatusSchedules")
+	}
+
+	return convertSchedulesStorageToModel(list), nil
+}
+
+func (s *Service) CompleteStatusSchedule(ctx context.Context, id string, scheduleErr error) error {
+	var errText *string
+	if scheduleErr != nil {
+		text := scheduleErr.Error()
+		errText = &text
+	}
+
+	err := s.repository.CompleteStatusSchedule(ctx, id, errText)
+	if err != nil {
+		return errors.Wrap(err, "repository.CompleteStatusSchedule")
+	}
+
+	return nil
+}
+
+const defaultSpecificationKeysLimit = 20
+
+func (s *Service) Facets(ctx context.Context, filtering filter.Filterable, opts model.FacetsOptions) (*model.Facets, error) {
+	bounds := slices.Clone(opts.PriceBounds)
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+
+	specKeysLimit := opts.SpecificationKeysLimit
+	if specKeysLimit == 0 {
+		specKeysLimit = defaultSpecificationKeysLimit
+	}
+
+	dbFacets, err := s.repository.Facets(ctx, filtering, bounds, specKeysLimit)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.Facets")
+	}
+
+	return &model.Facets{
+		Categories:        convertFacetValues(dbFacets.Categories),
+		Prices:            convertPriceBuckets(dbFacets.PriceBuckets, bounds),
+		Ratings:           convertFacetValues(dbFacets.Ratings),
+		SpecificationKeys: convertFacetValues(dbFacets.SpecificationKeys),
+	}, nil
+}
+
+func (s *Service) Count(ctx context.Context, filtering filter.Filterable) (uint64, error) {
+	count, err := s.repository.Count(ctx, filtering)
+	if err != nil {
+		return 0, errors.Wrap(err, "repository.Count")
+	}
+	return count, nil
+}
+
+// DistinctValues возвращает top самых частых значений поля, 0 означает все значения
+func (s *Service) DistinctValues(ctx context.Context, filtering filter.Filterable, field string, top uint64) (*model.DistinctValues, error) {
+	// Лишнее значение показывает, что список обрезан
+	limit := top
+	if top > 0 {
+		limit = top + 1
+	}
+
+	dbValues, err := s.repository.DistinctValues(ctx, filtering, field, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "repository.DistinctValues")
+	}
+
+	truncated := top > 0 && uint64(len(dbValues)) > top
+	if truncated {
+		dbValues = dbValues[:top]
+	}
+
+	return &model.DistinctValues{
+		Field:     field,
+		Values:    convertFacetValues(dbValues),
+		Truncated: truncated,
+	}, nil
+}
+
+func convertFacetValues(list []dao.FacetValueStorage) []model.FacetValue {
+	values := make([]model.FacetValue, 0, len(list))
+	for _, fv := range list {
+		if !fv.Value.Valid {
+			continue
+		}
+		values = append(values, model.FacetValue{Value: fv.Value.String, Count: fv.Count})
+	}
+	return values
+}
+
+// convertPriceBuckets переводит номера width_bucket в границы диапазонов.
+// Бакет i покрывает [bounds[i-1], bounds[i]), бакет 0 - цены ниже первой границы.
+func convertPriceBuckets(list []dao.FacetValueStorage, bounds []uint64) []model.PriceBucket {
+	buckets := make([]model.PriceBucket, 0, len(list))
+	for _, fv := range list {
+		if !fv.Value.Valid {
+			continue
+		}
+		i, err := strconv.Atoi(fv.Value.String)
+		if err != nil || i < 0 || i > len(bounds) {
+			continue
+		}
+
+		bucket := model.PriceBucket{Count: fv.Count}
+		if i > 0 {
+			bucket.From = &bounds[i-1]
+		}
+		if i < len(bounds) {
+			bucket.To = &bounds[i]
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	slices.SortFunc(buckets, func(a, b model.PriceBucket) int {
+		switch {
+		case a.From == nil:
+			return -1
+		case b.From == nil:
+			return 1
+		case *a.From < *b.From:
+			return -1
+		case *a.From > *b.From:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	return buckets
+}
@@ -0,0 +1,17 @@
+BEGIN;
+
+-- TABLES --
+
+CREATE TABLE public.product_translation
+(
+    product_id UUID NOT NULL REFERENCES public.product(id) ON DELETE CASCADE,
+    locale TEXT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    PRIMARY KEY (product_id, locale),
+    CONSTRAINT valid_locale CHECK (locale = lower(locale))
+);
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP TABLE IF EXISTS public.product_status_schedule;
+
+ALTER TABLE IF EXISTS public.product
+    DROP COLUMN IF EXISTS status;
+
+COMMIT;
@@ -0,0 +1,28 @@
+BEGIN;
+
+-- TABLES --
+
+ALTER TABLE public.product
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'draft',
+    ADD CONSTRAINT valid_status CHECK (status IN ('draft', 'published', 'archived'));
+
+-- products created before lifecycle was introduced were already visible
+UPDATE public.product SET status = 'published';
+
+CREATE INDEX product_status_idx ON public.product (status);
+
+CREATE TABLE public.product_status_schedule
+(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES public.product(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    run_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ,
+    executed_at TIMESTAMPTZ,
+    error TEXT,
+    CONSTRAINT valid_status CHECK (status IN ('draft', 'published', 'archived'))
+);
+
+CREATE INDEX product_status_schedule_due_idx ON public.product_status_schedule (run_at) WHERE executed_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,34 @@
+BEGIN;
+
+-- TABLES --
+
+CREATE TABLE public.promotion
+(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    percent NUMERIC(5, 2),
+    amount BIGINT,
+    buy_quantity INT,
+    get_quantity INT,
+    product_id UUID REFERENCES public.product(id) ON DELETE CASCADE,
+    category_id INT REFERENCES public.category(id) ON DELETE CASCADE,
+    priority INT NOT NULL DEFAULT 0,
+    stackable BOOLEAN NOT NULL DEFAULT FALSE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT valid_kind CHECK (kind IN ('percentage', 'fixed', 'buy_n_get_m')),
+    CONSTRAINT valid_percentage CHECK (kind <> 'percentage' OR (percent > 0 AND percent <= 100)),
+    CONSTRAINT valid_fixed CHECK (kind <> 'fixed' OR amount > 0),
+    CONSTRAINT valid_buy_n_get_m CHECK (kind <> 'buy_n_get_m' OR (buy_quantity > 0 AND get_quantity > 0)),
+    CONSTRAINT single_scope CHECK (product_id IS NULL OR category_id IS NULL),
+    CONSTRAINT valid_window CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX promotion_product_idx ON public.promotion (product_id);
+CREATE INDEX promotion_category_idx ON public.promotion (category_id);
+CREATE INDEX promotion_window_idx ON public.promotion (starts_at, ends_at);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS public.product_price_schedule;
+DROP TABLE IF EXISTS public.product_price_history;
+
+COMMIT;
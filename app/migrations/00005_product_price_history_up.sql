@@ -0,0 +1,40 @@
+BEGIN;
+
+-- TABLES --
+
+CREATE TABLE public.product_price_history
+(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES public.product(id) ON DELETE CASCADE,
+    old_price BIGINT,
+    new_price BIGINT NOT NULL,
+    actor TEXT NOT NULL,
+    source TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT valid_source CHECK (source IN ('create', 'update', 'scheduled'))
+);
+
+CREATE INDEX product_price_history_product_idx ON public.product_price_history (product_id, changed_at);
+
+CREATE TABLE public.product_price_schedule
+(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES public.product(id) ON DELETE CASCADE,
+    price BIGINT NOT NULL,
+    actor TEXT NOT NULL,
+    run_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ,
+    executed_at TIMESTAMPTZ,
+    error TEXT,
+    CONSTRAINT positive_price CHECK (price >= 0)
+);
+
+CREATE INDEX product_price_schedule_due_idx ON public.product_price_schedule (run_at) WHERE executed_at IS NULL;
+
+-- current prices are the first history entries
+INSERT INTO public.product_price_history (product_id, old_price, new_price, actor, source, changed_at)
+SELECT id, NULL, price, 'migration', 'create', COALESCE(created_at, now())
+FROM public.product
+WHERE price IS NOT NULL;
+
+COMMIT;
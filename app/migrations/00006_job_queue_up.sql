@@ -0,0 +1,33 @@
+BEGIN;
+
+-- TABLES --
+
+CREATE TABLE public.job
+(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    type TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    status TEXT NOT NULL DEFAULT 'pending',
+    -- at most one pending or running job of type has the same unique key
+    unique_key TEXT,
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL,
+    run_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- running job whose lock expired was lost by its worker and is returned to queue
+    locked_by TEXT,
+    locked_until TIMESTAMPTZ,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ,
+    CONSTRAINT valid_job_status CHECK (status IN ('pending', 'running', 'succeeded', 'failed', 'cancelled')),
+    CONSTRAINT positive_max_attempts CHECK (max_attempts > 0)
+);
+
+CREATE INDEX job_due_idx ON public.job (run_at) WHERE status = 'pending';
+CREATE INDEX job_lock_idx ON public.job (locked_until) WHERE status = 'running';
+CREATE INDEX job_finished_idx ON public.job (finished_at) WHERE finished_at IS NOT NULL;
+CREATE UNIQUE INDEX job_unique_key_idx ON public.job (type, unique_key)
+    WHERE unique_key IS NOT NULL AND status IN ('pending', 'running');
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+-- schedule claimed by scheduler instance isn't picked up by others until claim expires,
+-- expired claim means instance was lost before schedule completed
+ALTER TABLE public.product_status_schedule
+    ADD COLUMN claimed_until TIMESTAMPTZ;
+
+COMMIT;
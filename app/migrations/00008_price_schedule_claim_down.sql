@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE IF EXISTS public.product_price_schedule
+    DROP COLUMN IF EXISTS claimed_until;
+
+COMMIT;
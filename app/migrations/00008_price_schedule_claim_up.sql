@@ -0,0 +1,7 @@
+BEGIN;
+
+-- see 00007_status_schedule_claim
+ALTER TABLE public.product_price_schedule
+    ADD COLUMN claimed_until TIMESTAMPTZ;
+
+COMMIT;
@@ -0,0 +1,9 @@
+// Package migrations embeds SQL migrations of the service, see pkg/migrate
+package migrations
+
+import "embed"
+
+// FS contains `<version>_<name>_up.sql` and `<version>_<name>_down.sql` files
+//
+//go:embed *.sql
+var FS embed.FS
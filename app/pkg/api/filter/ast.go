@@ -0,0 +1,71 @@
+package filter
+
+// Node is a node of filter expression tree: *Condition, *And, *Or or *Not
+type Node interface {
+	node()
+}
+
+// Condition compares one field with a value
+type Condition struct {
+	Field
+	// Pos is 1-based character position of the condition in the filter expression, 0 when added by AddField
+	Pos int
+	// OperatorPos and ValuePos are positions of operator and of each value, 0 when added by AddField
+	OperatorPos int
+	ValuePos    []int
+}
+
+// valuePos returns position of i-th value, operator position when condition has no such value
+func (c *Condition) valuePos(i int) int {
+	if i < len(c.ValuePos) {
+		return c.ValuePos[i]
+	}
+	return c.OperatorPos
+}
+
+// And is true when all of Nodes are true
+type And struct {
+	Nodes []Node
+}
+
+// Or is true when any of Nodes is true
+type Or struct {
+	Nodes []Node
+}
+
+// Not negates Node
+type Not struct {
+	Node Node
+}
+
+func (*Condition) node() {}
+func (*And) node()       {}
+func (*Or) node()        {}
+func (*Not) node()       {}
+
+// Conditions returns all conditions of the tree in order of appearance
+func Conditions(n Node) []*Condition {
+	var conditions []*Condition
+	Walk(n, func(c *Condition) {
+		conditions = append(conditions, c)
+	})
+	return conditions
+}
+
+// Walk calls fn for every condition of the tree
+func Walk(n Node, fn func(c *Condition)) {
+	switch n := n.(type) {
+	case *Condition:
+		fn(n)
+	case *And:
+		for _, child := range n.Nodes {
+			Walk(child, fn)
+		}
+	case *Or:
+		for _, child := range n.Nodes {
+			Walk(child, fn)
+		}
+	case *Not:
+		Walk(n.Node, fn)
+	}
+}
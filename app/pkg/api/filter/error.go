@@ -1,7 +1,67 @@
 package filter
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+)
 
 var (
 	ErrBadOperator = errors.New("bad operator")
+	ErrBadSyntax   = errors.New("bad filter syntax")
 )
+
+// ParseError describes invalid filter expression or condition.
+// Pos is 1-based character position of the problem, 0 for conditions added by AddField.
+// Field is name of the field condition refers to, empty for syntax errors.
+type ParseError struct {
+	Pos   int
+	Field string
+	Err   error
+}
+
+func newParseError(pos int, msg string) *ParseError {
+	return &ParseError{Pos: pos, Err: errors.New(msg)}
+}
+
+func (e *ParseError) Error() string {
+	if e.Pos == 0 {
+		return fmt.Sprintf("%s: %v", e.Field, e.Err)
+	}
+	return fmt.Sprintf("%v at position %d", e.Err, e.Pos)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// FieldViolation is a problem with filter condition suitable for InvalidArgument error details
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// Violations returns problems of all filter conditions in err, nil when err is not a filter error
+func Violations(err error) []FieldViolation {
+	errs := []error{err}
+	var merr *multierror.Error
+	if errors.As(err, &merr) {
+		errs = merr.WrappedErrors()
+	}
+
+	var violations []FieldViolation
+	for _, e := range errs {
+		var parseErr *ParseError
+		if !errors.As(e, &parseErr) {
+			continue
+		}
+		field := parseErr.Field
+		if field == "" {
+			field = "filter"
+		}
+		violations = append(violations, FieldViolation{Field: field, Description: parseErr.Error()})
+	}
+
+	return violations
+}
@@ -0,0 +1,407 @@
+package filter
+
+import (
+	"cmp"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/sort"
+)
+
+// Evaluator applies filter expressions and sort keys to Go values with the same semantics
+// as SQL built by pkg/client/postgresql/model:
+//   - comparisons follow SQL three-valued logic, a condition on NULL is neither true nor false,
+//     so `not (rating gt 3)` doesn't match items without rating;
+//   - `like`, `starts with` ignore case by Unicode rules, `cslike` doesn't, values are matched literally;
+//   - values of date fields are truncated to date in UTC, as in Postgres session with UTC time zone;
+//   - NULLs are sorted last in ascending and first in descending order unless set explicitly,
+//     `id` is added as the last sort key when item has it.
+//
+// Strings are compared and sorted by bytes, which matches Postgres only with C collation.
+// Paths inside JSON fields are not supported.
+//
+// Item fields are read with Accessor when item implements it, otherwise from struct fields
+// with `filter` tag, or `json` tag when there is no `filter` tag. Nil pointers and
+// driver.Valuer returning nil are NULL.
+type Evaluator struct{}
+
+// Accessor returns value of field, ok is false when item has no such field
+type Accessor interface {
+	FilterValue(field string) (value interface{}, ok bool)
+}
+
+const tieBreakerField = "id"
+
+func NewEvaluator() *Evaluator {
+	return &Evaluator{}
+}
+
+// Match reports whether item satisfies expression, nil expression matches everything
+func (e *Evaluator) Match(node Node, item interface{}) (bool, error) {
+	if node == nil {
+		return true, nil
+	}
+	result, err := e.eval(node, item)
+	return result == truthTrue, err
+}
+
+// Apply returns items which match filter, sorted by sorting and paginated with filter limit and offset
+func Apply[T any](e *Evaluator, items []T, filtering Filterable, sorting sort.Sortable) ([]T, error) {
+	var expression Node
+	if filtering != nil {
+		expression = filtering.Expression()
+	}
+
+	result := make([]T, 0, len(items))
+	for _, item := range items {
+		ok, err := e.Match(expression, item)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, item)
+		}
+	}
+
+	if sorting != nil {
+		if err := e.Sort(result, sorting.Keys()); err != nil {
+			return nil, err
+		}
+	}
+
+	if filtering == nil || filtering.Limit() == 0 {
+		return result, nil
+	}
+	offset := min(filtering.Offset(), uint64(len(result)))
+	end := min(offset+filtering.Limit(), uint64(len(result)))
+	return result[offset:end], nil
+}
+
+// Sort sorts slice in place by keys followed by `id` tie-breaker
+func (e *Evaluator) Sort(slice interface{}, keys []sort.Key) error {
+	rv := reflect.ValueOf(slice)
+	if rv.Kind() != reflect.Slice {
+		return fmt.Errorf("sort: %T is not a slice", slice)
+	}
+	if rv.Len() == 0 {
+		return nil
+	}
+
+	keys = slices.Clone(keys)
+	if !slices.ContainsFunc(keys, func(k sort.Key) bool { return k.Field == tieBreakerField }) {
+		if _, ok := fieldValue(rv.Index(0).Interface(), tieBreakerField); ok {
+			keys = append(keys, sort.Key{Field: tieBreakerField, Order: sort.OrderASC})
+		}
+	}
+
+	// values are normalized once, so errors are reported before sorting
+	values := make([][]interface{}, rv.Len())
+	for i := range values {
+		values[i] = make([]interface{}, len(keys))
+		for k, key := range keys {
+			raw, ok := fieldValue(rv.Index(i).Interface(), key.Field)
+			if !ok {
+				return fmt.Errorf("unknown sort field:`%s`", key.Field)
+			}
+			// like ORDER BY of plain column, sort values are not truncated to date
+			v, err := normalize(raw, "")
+			if err != nil {
+				return fmt.Errorf("sort field `%s`: %w", key.Field, err)
+			}
+			values[i][k] = v
+		}
+	}
+
+	order := make([]int, rv.Len())
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		for k, key := range keys {
+			if c := compareKey(values[a][k], values[b][k], key); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+
+	sorted := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+	for i, idx := range order {
+		sorted.Index(i).Set(rv.Index(idx))
+	}
+	reflect.Copy(rv, sorted)
+	return nil
+}
+
+func compareKey(a, b interface{}, key sort.Key) int {
+	desc := strings.EqualFold(string(key.Order), string(sort.OrderDESC))
+	nullsFirst := desc
+	switch {
+	case strings.EqualFold(string(key.Nulls), string(sort.NullsFirst)):
+		nullsFirst = true
+	case strings.EqualFold(string(key.Nulls), string(sort.NullsLast)):
+		nullsFirst = false
+	}
+
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		if nullsFirst {
+			return -1
+		}
+		return 1
+	case b == nil:
+		if nullsFirst {
+			return 1
+		}
+		return -1
+	}
+
+	c := compare(a, b)
+	if desc {
+		return -c
+	}
+	return c
+}
+
+// truth is SQL three-valued logic value
+type truth int8
+
+const (
+	truthFalse truth = iota
+	truthTrue
+	truthUnknown
+)
+
+func truthOf(b bool) truth {
+	if b {
+		return truthTrue
+	}
+	return truthFalse
+}
+
+func (e *Evaluator) eval(node Node, item interface{}) (truth, error) {
+	switch n := node.(type) {
+	case *And:
+		result := truthTrue
+		for _, child := range n.Nodes {
+			t, err := e.eval(child, item)
+			if err != nil {
+				return truthFalse, err
+			}
+			if t == truthFalse {
+				return truthFalse, nil
+			}
+			if t == truthUnknown {
+				result = truthUnknown
+			}
+		}
+		return result, nil
+	case *Or:
+		result := truthFalse
+		for _, child := range n.Nodes {
+			t, err := e.eval(child, item)
+			if err != nil {
+				return truthFalse, err
+			}
+			if t == truthTrue {
+				return truthTrue, nil
+			}
+			if t == truthUnknown {
+				result = truthUnknown
+			}
+		}
+		return result, nil
+	case *Not:
+		t, err := e.eval(n.Node, item)
+		if err != nil || t == truthUnknown {
+			return t, err
+		}
+		return truthOf(t == truthFalse), nil
+	case *Condition:
+		return e.condition(n.Field, item)
+	}
+	return truthFalse, fmt.Errorf("unknown filter node %T", node)
+}
+
+func (e *Evaluator) condition(f Field, item interface{}) (truth, error) {
+	raw, ok := fieldValue(item, f.Name)
+	if !ok {
+		return truthFalse, fmt.Errorf("unknown param:`%s`", f.Name)
+	}
+	v, err := normalize(raw, f.Type)
+	if err != nil {
+		return truthFalse, fmt.Errorf("field `%s`: %w", f.Name, err)
+	}
+
+	switch f.Operator {
+	case OperatorIsNull:
+		return truthOf(v == nil), nil
+	case OperatorIsNotNull:
+		return truthOf(v != nil), nil
+	}
+	if v == nil {
+		return truthUnknown, nil
+	}
+
+	args := make([]interface{}, len(f.Args))
+	for i, arg := range f.Args {
+		if t, ok := arg.(time.Time); ok && (f.Type == DataTypeDate || f.Type == DataTypeTimeArray) {
+			// date arguments are sent in their own time zone, unlike values of timestamptz columns
+			args[i] = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if args[i], err = normalize(arg, f.Type); err != nil {
+			return truthFalse, fmt.Errorf("field `%s`: %w", f.Name, err)
+		}
+	}
+
+	switch f.Operator {
+	case OperatorEq:
+		return truthOf(compare(v, args[0]) == 0), nil
+	case OperatorNotEq:
+		return truthOf(compare(v, args[0]) != 0), nil
+	case OperatorLowerThan:
+		return truthOf(compare(v, args[0]) < 0), nil
+	case OperatorLowerThanEq:
+		return truthOf(compare(v, args[0]) <= 0), nil
+	case OperatorGreaterThan:
+		return truthOf(compare(v, args[0]) > 0), nil
+	case OperatorGreaterThanEq:
+		return truthOf(compare(v, args[0]) >= 0), nil
+	case OperatorBetween:
+		return truthOf(compare(v, args[0]) >= 0 && compare(v, args[1]) <= 0), nil
+	case OperatorIn:
+		return truthOf(slices.ContainsFunc(args, func(a interface{}) bool { return compare(v, a) == 0 })), nil
+	case OperatorNotIn:
+		return truthOf(!slices.ContainsFunc(args, func(a interface{}) bool { return compare(v, a) == 0 })), nil
+	case OperatorLike:
+		return truthOf(strings.Contains(strings.ToLower(fmt.Sprint(v)), strings.ToLower(fmt.Sprint(args[0])))), nil
+	case OperatorCaseSensitiveLike:
+		return truthOf(strings.Contains(fmt.Sprint(v), fmt.Sprint(args[0]))), nil
+	case OperatorStartsWith:
+		return truthOf(strings.HasPrefix(strings.ToLower(fmt.Sprint(v)), strings.ToLower(fmt.Sprint(args[0])))), nil
+	}
+	return truthFalse, fmt.Errorf("%w: `%s`", ErrBadOperator, f.Operator)
+}
+
+// normalize converts value to int64, bool, string or time.Time according to data type, nil is NULL
+func normalize(v interface{}, dType string) (interface{}, error) {
+	if valuer, ok := v.(driver.Valuer); ok {
+		var err error
+		if v, err = valuer.Value(); err != nil {
+			return nil, err
+		}
+	}
+	if v == nil {
+		return nil, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+
+	if t, ok := rv.Interface().(time.Time); ok {
+		if dType == DataTypeDate || dType == DataTypeTimeArray {
+			t = t.UTC()
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+		return t, nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("value %d overflows bigint", rv.Uint())
+		}
+		return int64(rv.Uint()), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.String:
+		if dType == DataTypeDate || dType == DataTypeTimeArray || dType == DataTypeInt || dType == DataTypeBool {
+			parsed, err := parseValue(dType, rv.String())
+			if err != nil {
+				return nil, err
+			}
+			return normalize(parsed, dType)
+		}
+		return rv.String(), nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T", v)
+}
+
+// compare compares normalized values of the same type, false is lower than true
+func compare(a, b interface{}) int {
+	switch av := a.(type) {
+	case int64:
+		if bv, ok := b.(int64); ok {
+			return cmp.Compare(av, bv)
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv)
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			switch {
+			case av == bv:
+				return 0
+			case !av:
+				return -1
+			}
+			return 1
+		}
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			return av.Compare(bv)
+		}
+	}
+	// mismatched types are compared by text like implicit casts in SQL
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// fieldValue reads field of item by Accessor or struct tags
+func fieldValue(item interface{}, name string) (interface{}, bool) {
+	if accessor, ok := item.(Accessor); ok {
+		return accessor.FilterValue(name)
+	}
+
+	rv := reflect.ValueOf(item)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag, ok := sf.Tag.Lookup("filter")
+		if !ok {
+			tag = sf.Tag.Get("json")
+		}
+		if tagName, _, _ := strings.Cut(tag, ","); tagName == name {
+			return rv.Field(i).Interface(), true
+		}
+	}
+	return nil, false
+}
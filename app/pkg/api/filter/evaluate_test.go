@@ -0,0 +1,152 @@
+package filter
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/sort"
+)
+
+type evalItem struct {
+	ID        string    `filter:"id"`
+	Name      string    `json:"name"`
+	Rating    *int64    `filter:"rating"`
+	Active    bool      `filter:"active"`
+	CreatedAt time.Time `filter:"created_at"`
+}
+
+var evalTypes = map[string]string{
+	"id":         DataTypeStr,
+	"name":       DataTypeStr,
+	"rating":     DataTypeInt,
+	"active":     DataTypeBool,
+	"created_at": DataTypeDate,
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func evalItems() []evalItem {
+	msk := time.FixedZone("MSK", 3*60*60)
+	return []evalItem{
+		{ID: "1", Name: "Red Apple", Rating: ptr[int64](5), Active: true, CreatedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{ID: "2", Name: "green apple", Rating: ptr[int64](3), CreatedAt: time.Date(2024, 3, 2, 1, 0, 0, 0, msk)},
+		{ID: "3", Name: "Ёлка", Active: true, CreatedAt: time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)},
+		{ID: "4", Name: "pear", Rating: ptr[int64](1), CreatedAt: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+	}
+}
+
+func TestEvaluatorMatch(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{filter: `rating gt 2`, want: []string{"1", "2"}},
+		// условие на NULL не истинно и не ложно
+		{filter: `not (rating gt 2)`, want: []string{"4"}},
+		{filter: `rating neq 3`, want: []string{"1", "4"}},
+		{filter: `rating not in 1,3`, want: []string{"1"}},
+		{filter: `rating is null`, want: []string{"3"}},
+		{filter: `rating is not null`, want: []string{"1", "2", "4"}},
+		{filter: `rating gt 2 or rating is null`, want: []string{"1", "2", "3"}},
+		// unknown AND false = false, NOT false = true
+		{filter: `not (rating gt 2 and active eq false)`, want: []string{"1", "3", "4"}},
+		// unknown OR false = unknown, NOT unknown = unknown
+		{filter: `not (rating gt 2 or active eq false)`, want: nil},
+		{filter: `not (rating gt 2 or active eq true)`, want: []string{"4"}},
+		{filter: `name like APPLE`, want: []string{"1", "2"}},
+		{filter: `name cslike Apple`, want: []string{"1"}},
+		{filter: `name starts with ёл`, want: []string{"3"}},
+		{filter: `name eq "Red Apple"`, want: []string{"1"}},
+		{filter: `active eq true`, want: []string{"1", "3"}},
+		// даты сравниваются по дню в UTC
+		{filter: `created_at eq 2024-03-01`, want: []string{"1", "2"}},
+		{filter: `created_at between 2024-03-02,2024-03-05`, want: []string{"3", "4"}},
+		{filter: `rating between 1,3`, want: []string{"2", "4"}},
+	}
+
+	e := NewEvaluator()
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			o := NewOptions(0, 0, evalTypes)
+			if err := o.AddFullField(tt.filter); err != nil {
+				t.Fatalf("AddFullField() error = %v", err)
+			}
+
+			var got []string
+			for _, item := range evalItems() {
+				ok, err := e.Match(o.Expression(), item)
+				if err != nil {
+					t.Fatalf("Match() error = %v", err)
+				}
+				if ok {
+					got = append(got, item.ID)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluatorMatchUnknownField(t *testing.T) {
+	node := &Condition{Field: Field{Name: "color", Operator: OperatorEq, Values: []string{"red"}, Args: []interface{}{"red"}}}
+	if _, err := NewEvaluator().Match(node, evalItems()[0]); err == nil {
+		t.Error("Match() error = nil, want unknown param")
+	}
+}
+
+func TestEvaluatorSort(t *testing.T) {
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{sort: "rating", want: []string{"4", "2", "1", "3"}},
+		{sort: "-rating", want: []string{"3", "1", "2", "4"}},
+		{sort: "rating:nulls_first", want: []string{"3", "4", "2", "1"}},
+		{sort: "-rating:nulls_last", want: []string{"1", "2", "4", "3"}},
+		{sort: "active,-id", want: []string{"4", "2", "3", "1"}},
+		{sort: "-created_at", want: []string{"4", "3", "2", "1"}},
+	}
+
+	e := NewEvaluator()
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			items := evalItems()
+			if err := e.Sort(items, sort.NewOptions(tt.sort).Keys()); err != nil {
+				t.Fatalf("Sort() error = %v", err)
+			}
+
+			got := make([]string, len(items))
+			for i, item := range items {
+				got[i] = item.ID
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Sort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	o := NewOptions(2, 1, evalTypes)
+	if err := o.AddFullField(`rating is not null`); err != nil {
+		t.Fatalf("AddFullField() error = %v", err)
+	}
+
+	items, err := Apply(NewEvaluator(), evalItems(), o, sort.NewOptions("-rating"))
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	got := make([]string, len(items))
+	for i, item := range items {
+		got[i] = item.ID
+	}
+	if want := []string{"2", "4"}; !slices.Equal(got, want) {
+		t.Errorf("Apply() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,40 @@
+package filter
+
+import (
+	"context"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// MetadataFilter carries filter queries of gRPC request, several queries are joined with AND
+	MetadataFilter = "x-filter"
+	// MetadataFilterSyntax is syntax of MetadataFilter queries, SyntaxExpression by default
+	MetadataFilterSyntax = "x-filter-syntax"
+)
+
+// AddIncomingMetadata adds filter queries of incoming gRPC request metadata to options.
+// Problems of all queries are returned at once.
+func AddIncomingMetadata(ctx context.Context, options Filterable) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	var syntax string
+	if values := md.Get(MetadataFilterSyntax); len(values) > 0 {
+		syntax = values[0]
+	}
+
+	var result error
+	for _, rawValue := range md.Get(MetadataFilter) {
+		if err := options.AddQuery(syntax, rawValue); err != nil {
+			if errors.Is(err, ErrBadSyntax) {
+				return &ParseError{Field: MetadataFilterSyntax, Err: err}
+			}
+			result = errors.Append(result, err)
+		}
+	}
+	return result
+}
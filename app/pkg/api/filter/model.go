@@ -2,7 +2,11 @@ package filter
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
 )
 
 type Operator string
@@ -23,14 +27,57 @@ const (
 	OperatorGreaterThan   = "gt"
 	OperatorGreaterThanEq = "ge"
 	OperatorIn            = "in"
-	OperatorLike          = "like"
+	OperatorNotIn         = "not in"
+	OperatorBetween       = "between"
+	OperatorIsNull        = "is null"
+	OperatorIsNotNull     = "is not null"
+	// OperatorLike matches values containing the substring ignoring case
+	OperatorLike = "like"
+	// OperatorCaseSensitiveLike matches values containing the substring
+	OperatorCaseSensitiveLike = "cslike"
+	// OperatorStartsWith matches values starting with the prefix ignoring case
+	OperatorStartsWith = "starts with"
+
+	// SyntaxExpression is syntax of Parse, e.g. `name eq "red apple" and price lt 100`
+	SyntaxExpression = "expression"
+	// SyntaxRSQL is syntax of ParseRSQL, e.g. `name=="red apple";price<100`
+	SyntaxRSQL = "rsql"
 )
 
+// anyNumberOfValues is arity of operators which take one or more values
+const anyNumberOfValues = -1
+
+// operatorArity is number of values each registered operator takes
+var operatorArity = map[string]int{
+	OperatorEq:                1,
+	OperatorNotEq:             1,
+	OperatorLowerThan:         1,
+	OperatorLowerThanEq:       1,
+	OperatorGreaterThan:       1,
+	OperatorGreaterThanEq:     1,
+	OperatorIn:                anyNumberOfValues,
+	OperatorNotIn:             anyNumberOfValues,
+	OperatorBetween:           2,
+	OperatorIsNull:            0,
+	OperatorIsNotNull:         0,
+	OperatorLike:              1,
+	OperatorCaseSensitiveLike: 1,
+	OperatorStartsWith:        1,
+}
+
+// Operators returns registered operators in alphabetical order
+func Operators() []string {
+	return slices.Sorted(maps.Keys(operatorArity))
+}
+
 type Filterable interface {
 	Limit() uint64
 	Offset() uint64
 	Fields() []Field
+	Expression() Node
 	AddFullField(rawValue string) error
+	AddRSQL(rawValue string) error
+	AddQuery(syntax, rawValue string) error
 	AddField(name string, operator Operator, value string) error
 }
 
@@ -39,7 +86,8 @@ type Opts struct {
 	limit       uint64
 	offset      uint64
 	filterTypes map[string]string
-	fields      []Field
+	// nodes are joined with AND
+	nodes []Node
 }
 
 func NewOptions(limit, offset uint64, filterTypes map[string]string) *Opts {
@@ -51,8 +99,13 @@ func NewOptions(limit, offset uint64, filterTypes map[string]string) *Opts {
 }
 
 type Field struct {
-	Name     string
-	Value    string
+	Name string
+	// Value is the only value of operator, or Values joined with comma
+	Value string
+	// Values are all values of operator, none for `is null` and `is not null`
+	Values []string
+	// Args are Values parsed according to Type
+	Args     []interface{}
 	Operator string
 	Type     string
 }
@@ -65,91 +118,160 @@ func (o *Opts) Offset() uint64 {
 	return o.offset
 }
 
+// Fields returns conditions of all added expressions regardless of how they are combined
 func (o *Opts) Fields() []Field {
-	return o.fields
+	var fields []Field
+	for _, c := range Conditions(o.Expression()) {
+		fields = append(fields, c.Field)
+	}
+	return fields
+}
+
+// Expression returns all added conditions joined with AND, or nil when nothing was added
+func (o *Opts) Expression() Node {
+	switch len(o.nodes) {
+	case 0:
+		return nil
+	case 1:
+		return o.nodes[0]
+	}
+	return &And{Nodes: o.nodes}
 }
 
+// AddFullField parses filter expression, e.g. `name eq "red apple" and (rating ge 4 or price lt 100)`,
+// and joins it with already added ones using AND.
+// All problems of conditions are returned at once as multierror of *ParseError.
 func (o *Opts) AddFullField(rawValue string) error {
-	split := strings.Split(rawValue, " ")
-	name := split[0]
-	operator := split[1]
-	value := split[2]
+	node, err := Parse(rawValue)
+	if err != nil {
+		return err
+	}
+	return o.addNode(node)
+}
 
-	err := validateOperator(operator)
+// AddRSQL parses RSQL query, e.g. `name==foo*;price=gt=100,rating==5`, and joins it
+// with already added ones using AND. Problems are reported the same way as by AddFullField.
+func (o *Opts) AddRSQL(rawValue string) error {
+	node, err := ParseRSQL(rawValue)
 	if err != nil {
 		return err
 	}
-	dType, ok := o.filterTypes[name]
-	if !ok {
-		return fmt.Errorf("unknown param:`%s`", value)
+	return o.addNode(node)
+}
+
+// AddQuery adds query of the syntax, SyntaxExpression when syntax is empty
+func (o *Opts) AddQuery(syntax, rawValue string) error {
+	switch syntax {
+	case "", SyntaxExpression:
+		return o.AddFullField(rawValue)
+	case SyntaxRSQL:
+		return o.AddRSQL(rawValue)
 	}
-	if checkIsValueTuple(value) {
-		dType = DataTypeArray
-		if dType == DataTypeDate {
-			dType = DataTypeTimeArray
+	return fmt.Errorf("%w `%s`", ErrBadSyntax, syntax)
+}
+
+// addNode resolves data types and parses values of all conditions of the tree
+func (o *Opts) addNode(node Node) error {
+	var result error
+	Walk(node, func(c *Condition) {
+		field, problems := o.newField(c)
+		for _, problem := range problems {
+			result = errors.Append(result, problem)
 		}
+		c.Field = field
+	})
+	if result != nil {
+		return result
+	}
+
+	o.nodes = append(o.nodes, node)
+	return nil
+}
+
+// AddField adds condition joined with already added ones using AND.
+// Values of list operators are separated with comma.
+func (o *Opts) AddField(name string, operator Operator, value string) error {
+	var values []string
+	switch arity := operatorArity[string(operator)]; {
+	case arity == 0:
+	case arity == 1:
+		values = []string{value}
+	default:
+		values = strings.Split(value, ",")
 	}
 
-	if (dType == DataTypeArray || dType == DataTypeTimeArray) && operator != OperatorIn {
-		return fmt.Errorf("with array type name you can use only `in` operator. wrong query param:`%s=%s`",
-			name, rawValue)
+	c := &Condition{Field: Field{Name: name, Operator: string(operator), Values: values}}
+	field, problems := o.newField(c)
+	if len(problems) > 0 {
+		var result error
+		for _, problem := range problems {
+			result = errors.Append(result, problem)
+		}
+		return result
 	}
 
-	o.fields = append(o.fields, Field{
-		Name:     name,
-		Value:    value,
-		Operator: operator,
-		Type:     dType,
-	})
+	c.Field = field
+	o.nodes = append(o.nodes, c)
 	return nil
 }
 
-func (o *Opts) AddField(name string, operator Operator, value string) error {
-	err := validateOperator(string(operator))
+// newField validates condition and parses its values, returning all found problems.
+// Each problem is located at the token which caused it: field name, operator or value.
+func (o *Opts) newField(c *Condition) (Field, []*ParseError) {
+	name, operator, values := c.Name, c.Operator, c.Values
+	problemAt := func(pos int, err error) *ParseError {
+		return &ParseError{Pos: pos, Field: name, Err: err}
+	}
+
+	err := validateOperator(operator)
 	if err != nil {
-		return err
+		return Field{}, []*ParseError{problemAt(c.OperatorPos, fmt.Errorf("%w `%s`", err, operator))}
 	}
 	dType, ok := o.filterTypes[name]
 	if !ok {
-		return fmt.Errorf("unknown param:`%s`", value)
+		return Field{}, []*ParseError{problemAt(c.Pos, fmt.Errorf("unknown param:`%s`", name))}
+	}
+
+	var problems []*ParseError
+	switch arity := operatorArity[operator]; {
+	case arity == anyNumberOfValues && len(values) == 0:
+		problems = append(problems, problemAt(c.OperatorPos, fmt.Errorf("operator `%s` requires at least one value", operator)))
+	case arity != anyNumberOfValues && len(values) != arity:
+		// лишнее значение указываем на первом из них, нехватку значений - на операторе
+		err = fmt.Errorf("operator `%s` requires %d value(s), got %d", operator, arity, len(values))
+		problems = append(problems, problemAt(c.valuePos(arity), err))
+	}
+	if err = validateOperatorType(operator, dType); err != nil {
+		problems = append(problems, problemAt(c.OperatorPos, err))
 	}
-	if checkIsValueTuple(value) {
-		dType = DataTypeArray
-		if dType == DataTypeDate {
-			dType = DataTypeTimeArray
+
+	args := make([]interface{}, 0, len(values))
+	for i, value := range values {
+		arg, err := parseValue(dType, value)
+		if err != nil {
+			problems = append(problems, problemAt(c.valuePos(i), err))
+			continue
 		}
+		args = append(args, arg)
 	}
 
-	if (dType == DataTypeArray || dType == DataTypeTimeArray) && operator != OperatorIn {
-		return fmt.Errorf("with array type name you can use only `in` operator. wrong query param:`%s, %s, %s`",
-			name, operator, value)
+	if len(problems) > 0 {
+		return Field{}, problems
 	}
 
-	o.fields = append(o.fields, Field{
+	return Field{
 		Name:     name,
-		Value:    value,
-		Operator: string(operator),
+		Value:    strings.Join(values, ","),
+		Values:   values,
+		Args:     args,
+		Operator: operator,
 		Type:     dType,
-	})
-	return nil
-}
-
-func checkIsValueTuple(value string) bool {
-	split := strings.Split(value, ",")
-	return len(split) != 1
+	}, nil
 }
 
+// validateOperator rejects operators which are not registered in operatorArity
 func validateOperator(operator string) error {
-	switch operator {
-	case OperatorEq:
-	case OperatorLike:
-	case OperatorNotEq:
-	case OperatorLowerThan:
-	case OperatorLowerThanEq:
-	case OperatorGreaterThan:
-	case OperatorGreaterThanEq:
-	case OperatorIn:
-	default:
+	if _, ok := operatorArity[operator]; !ok {
 		return ErrBadOperator
 	}
 	return nil
@@ -0,0 +1,229 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	keywordAnd = "and"
+	keywordOr  = "or"
+	keywordNot = "not"
+)
+
+// Parse parses filter expression into a tree. Grammar:
+//
+//	expr      = and { "or" and }
+//	and       = unary { "and" unary }
+//	unary     = "not" unary | "(" expr ")" | condition
+//	condition = name operator [ value { "," value } ]
+//	operator  = word | "not in" | "is null" | "is not null" | "starts with"
+//
+// Keywords are case-insensitive, values may be quoted to contain spaces and delimiters.
+// Conditions are returned without data types, they are resolved by Opts.
+func Parse(input string) (Node, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, newParseError(p.peek().pos, "empty filter")
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, unexpected(t, "`and`, `or` or end of input")
+	}
+
+	return node, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (Node, error) {
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	or := &Or{Nodes: []Node{node}}
+	for p.peek().is(keywordOr) {
+		p.next()
+		node, err = p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or.Nodes = append(or.Nodes, node)
+	}
+
+	if len(or.Nodes) == 1 {
+		return or.Nodes[0], nil
+	}
+	return or, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	node, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	and := &And{Nodes: []Node{node}}
+	for p.peek().is(keywordAnd) {
+		p.next()
+		node, err = p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		and.Nodes = append(and.Nodes, node)
+	}
+
+	if len(and.Nodes) == 1 {
+		return and.Nodes[0], nil
+	}
+	return and, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	t := p.peek()
+	switch {
+	case t.is(keywordNot):
+		p.next()
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Node: node}, nil
+	case t.kind == tokenLParen:
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, unexpected(closing, fmt.Sprintf("`)` closing `(` at position %d", t.pos))
+		}
+		return node, nil
+	default:
+		return p.parseCondition()
+	}
+}
+
+func (p *parser) parseCondition() (Node, error) {
+	name := p.next()
+	if name.kind != tokenWord || isKeyword(name) {
+		return nil, unexpected(name, "field name")
+	}
+
+	operatorPos := p.peek().pos
+	operator, err := p.parseOperator()
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	var valuePos []int
+	if operatorArity[operator] != 0 {
+		for {
+			value := p.next()
+			if value.kind != tokenWord && value.kind != tokenString {
+				return nil, unexpected(value, "value")
+			}
+			values = append(values, value.text)
+			valuePos = append(valuePos, value.pos)
+
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.next()
+		}
+	}
+
+	return &Condition{
+		Field: Field{
+			Name:     name.text,
+			Operator: operator,
+			Value:    strings.Join(values, ","),
+			Values:   values,
+		},
+		Pos:         name.pos,
+		OperatorPos: operatorPos,
+		ValuePos:    valuePos,
+	}, nil
+}
+
+// parseOperator reads operator which may consist of several words
+func (p *parser) parseOperator() (string, error) {
+	first := p.next()
+	switch {
+	case first.is("is"):
+		if p.peek().is(keywordNot) {
+			p.next()
+			if err := p.expectWord("null"); err != nil {
+				return "", err
+			}
+			return OperatorIsNotNull, nil
+		}
+		if err := p.expectWord("null"); err != nil {
+			return "", err
+		}
+		return OperatorIsNull, nil
+	case first.is(keywordNot):
+		if err := p.expectWord("in"); err != nil {
+			return "", err
+		}
+		return OperatorNotIn, nil
+	case first.is("starts"):
+		if err := p.expectWord("with"); err != nil {
+			return "", err
+		}
+		return OperatorStartsWith, nil
+	case first.kind != tokenWord || isKeyword(first):
+		return "", unexpected(first, "operator")
+	}
+
+	operator := strings.ToLower(first.text)
+	if _, ok := operatorArity[operator]; !ok {
+		return "", &ParseError{Pos: first.pos, Err: fmt.Errorf("%w `%s`", ErrBadOperator, first.text)}
+	}
+	return operator, nil
+}
+
+func (p *parser) expectWord(word string) error {
+	if t := p.next(); !t.is(word) {
+		return unexpected(t, fmt.Sprintf("`%s`", word))
+	}
+	return nil
+}
+
+func isKeyword(t token) bool {
+	return t.is(keywordAnd) || t.is(keywordOr) || t.is(keywordNot)
+}
+
+func unexpected(t token, expected string) error {
+	found := t.kind.String()
+	if t.kind == tokenWord || t.kind == tokenString {
+		found = fmt.Sprintf("`%s`", t.text)
+	}
+	return newParseError(t.pos, fmt.Sprintf("expected %s, found %s", expected, found))
+}
@@ -0,0 +1,271 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// render prints tree in prefix form, e.g. `(and (name eq a) (not (price lt 1,2)))`
+func render(n Node) string {
+	switch n := n.(type) {
+	case *Condition:
+		if len(n.Values) == 0 {
+			return fmt.Sprintf("(%s %s)", n.Name, n.Operator)
+		}
+		return fmt.Sprintf("(%s %s %s)", n.Name, n.Operator, strings.Join(n.Values, ","))
+	case *And:
+		return renderList("and", n.Nodes)
+	case *Or:
+		return renderList("or", n.Nodes)
+	case *Not:
+		return "(not " + render(n.Node) + ")"
+	}
+	return fmt.Sprintf("%T", n)
+}
+
+func renderList(op string, nodes []Node) string {
+	parts := make([]string, len(nodes))
+	for i, node := range nodes {
+		parts[i] = render(node)
+	}
+	return "(" + op + " " + strings.Join(parts, " ") + ")"
+}
+
+// parseErrors returns all *ParseError of err in order
+func parseErrors(t *testing.T, err error) []*ParseError {
+	t.Helper()
+	errs := []error{err}
+	var merr *multierror.Error
+	if errors.As(err, &merr) {
+		errs = merr.WrappedErrors()
+	}
+
+	result := make([]*ParseError, 0, len(errs))
+	for _, e := range errs {
+		var parseErr *ParseError
+		if !errors.As(e, &parseErr) {
+			t.Fatalf("error %v is not *ParseError", e)
+		}
+		result = append(result, parseErr)
+	}
+	return result
+}
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []token
+	}{
+		{
+			input: `name eq "red apple"`,
+			want: []token{
+				{kind: tokenWord, text: "name", pos: 1},
+				{kind: tokenWord, text: "eq", pos: 6},
+				{kind: tokenString, text: "red apple", pos: 9},
+				{kind: tokenEOF, pos: 20},
+			},
+		},
+		{
+			input: `(a in 1,'x\'y')`,
+			want: []token{
+				{kind: tokenLParen, text: "(", pos: 1},
+				{kind: tokenWord, text: "a", pos: 2},
+				{kind: tokenWord, text: "in", pos: 4},
+				{kind: tokenWord, text: "1", pos: 7},
+				{kind: tokenComma, text: ",", pos: 8},
+				{kind: tokenString, text: "x'y", pos: 9},
+				{kind: tokenRParen, text: ")", pos: 15},
+				{kind: tokenEOF, pos: 16},
+			},
+		},
+		{
+			// позиции считаются в символах, а не байтах
+			input: `имя eq яблоко`,
+			want: []token{
+				{kind: tokenWord, text: "имя", pos: 1},
+				{kind: tokenWord, text: "eq", pos: 5},
+				{kind: tokenWord, text: "яблоко", pos: 8},
+				{kind: tokenEOF, pos: 14},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := lex(tt.input)
+			if err != nil {
+				t.Fatalf("lex() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("lex() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: `name eq "red`, want: "unterminated string at position 9"},
+		{input: `name eq 'a\`, want: "unfinished escape sequence at position 11"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := lex(tt.input)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("lex() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: `name eq apple`, want: `(name eq apple)`},
+		{input: `a eq 1 and b eq 2 or c eq 3`, want: `(or (and (a eq 1) (b eq 2)) (c eq 3))`},
+		{input: `a eq 1 and (b eq 2 or c eq 3)`, want: `(and (a eq 1) (or (b eq 2) (c eq 3)))`},
+		{input: `NOT not a eq 1`, want: `(not (not (a eq 1)))`},
+		{input: `a IS NOT NULL and b is null`, want: `(and (a is not null) (b is null))`},
+		{input: `a not in 1, 2 or b STARTS WITH "x y"`, want: `(or (a not in 1,2) (b starts with x y))`},
+		{input: `a between 1,2`, want: `(a between 1,2)`},
+		{input: `a eq "and"`, want: `(a eq and)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := render(node); got != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePositions(t *testing.T) {
+	node, err := Parse(`name eq "a b" and  price in 1, 2`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	conditions := Conditions(node)
+	want := []struct {
+		pos, operatorPos int
+		valuePos         []int
+	}{
+		{pos: 1, operatorPos: 6, valuePos: []int{9}},
+		{pos: 20, operatorPos: 26, valuePos: []int{29, 32}},
+	}
+	if len(conditions) != len(want) {
+		t.Fatalf("Parse() has %d conditions, want %d", len(conditions), len(want))
+	}
+	for i, c := range conditions {
+		if c.Pos != want[i].pos || c.OperatorPos != want[i].operatorPos || !slices.Equal(c.ValuePos, want[i].valuePos) {
+			t.Errorf("condition %d positions = %d, %d, %v, want %+v", i, c.Pos, c.OperatorPos, c.ValuePos, want[i])
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: ``, want: "empty filter at position 1"},
+		{input: `  `, want: "empty filter at position 3"},
+		{input: `name`, want: "expected operator, found end of input at position 5"},
+		{input: `name eq`, want: "expected value, found end of input at position 8"},
+		{input: `name foo 1`, want: "bad operator `foo` at position 6"},
+		{input: `and eq 1`, want: "expected field name, found `and` at position 1"},
+		{input: `(a eq 1`, want: "expected `)` closing `(` at position 1, found end of input at position 8"},
+		{input: `a eq 1 b eq 2`, want: "expected `and`, `or` or end of input, found `b` at position 8"},
+		{input: `a is not 1`, want: "expected `null`, found `1` at position 10"},
+		{input: `a in 1,`, want: "expected value, found end of input at position 8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptsErrorPositions(t *testing.T) {
+	types := map[string]string{
+		"name":   DataTypeStr,
+		"price":  DataTypeInt,
+		"active": DataTypeBool,
+	}
+
+	type problem struct {
+		pos   int
+		field string
+	}
+	tests := []struct {
+		input string
+		want  []problem
+	}{
+		{input: `color eq red`, want: []problem{{pos: 1, field: "color"}}},
+		{input: `name eq a and price eq x`, want: []problem{{pos: 24, field: "price"}}},
+		{input: `price in 1, x, 3, y`, want: []problem{{pos: 13, field: "price"}, {pos: 19, field: "price"}}},
+		{input: `price eq 1, 2`, want: []problem{{pos: 13, field: "price"}}},
+		{input: `active gt true`, want: []problem{{pos: 8, field: "active"}}},
+		{input: `price like 1`, want: []problem{{pos: 7, field: "price"}}},
+		{input: `price between 1 or name eq a`, want: []problem{{pos: 7, field: "price"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			err := NewOptions(10, 0, types).AddFullField(tt.input)
+			if err == nil {
+				t.Fatal("AddFullField() error = nil")
+			}
+
+			var got []problem
+			for _, e := range parseErrors(t, err) {
+				got = append(got, problem{pos: e.Pos, field: e.Field})
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("AddFullField() problems = %+v, want %+v (%v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestAddField(t *testing.T) {
+	types := map[string]string{"price": DataTypeInt, "tags": DataTypeArray}
+
+	o := NewOptions(10, 0, types)
+	if err := o.AddField("price", OperatorIn, "1,2"); err != nil {
+		t.Fatalf("AddField() error = %v", err)
+	}
+	fields := o.Fields()
+	if len(fields) != 1 || !slices.Equal(fields[0].Args, []interface{}{int64(1), int64(2)}) {
+		t.Errorf("Fields() = %+v, want price in 1,2", fields)
+	}
+
+	err := o.AddField("tags", OperatorEq, "x")
+	problems := parseErrors(t, err)
+	if len(problems) != 1 || problems[0].Pos != 0 || problems[0].Field != "tags" {
+		t.Errorf("AddField() error = %v, want one problem of tags without position", err)
+	}
+	if got := err.Error(); !strings.Contains(got, "tags: with array type name") {
+		t.Errorf("AddField() error = %q, want field prefix", got)
+	}
+}
@@ -0,0 +1,391 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	rsqlWildcard = "*"
+	rsqlReserved = `"'();,=!~<>`
+)
+
+// rsqlOperators maps RSQL/FIQL comparison operators to filter operators.
+// `==` with wildcards and `=isnull=` are resolved by value, see rsqlCondition.
+var rsqlOperators = map[string]string{
+	"==":        OperatorEq,
+	"!=":        OperatorNotEq,
+	"=lt=":      OperatorLowerThan,
+	"<":         OperatorLowerThan,
+	"=le=":      OperatorLowerThanEq,
+	"<=":        OperatorLowerThanEq,
+	"=gt=":      OperatorGreaterThan,
+	">":         OperatorGreaterThan,
+	"=ge=":      OperatorGreaterThanEq,
+	">=":        OperatorGreaterThanEq,
+	"=in=":      OperatorIn,
+	"=out=":     OperatorNotIn,
+	"=between=": OperatorBetween,
+	"=like=":    OperatorLike,
+	"=isnull=":  OperatorIsNull,
+}
+
+// ParseRSQL parses RSQL/FIQL query, e.g. `name==foo*;price=gt=100,rating==5`, into the same tree as Parse.
+// Grammar:
+//
+//	or         = and { ( "," | "or" ) and }
+//	and        = constraint { ( ";" | "and" ) constraint }
+//	constraint = "(" or ")" | selector operator arguments
+//	operator   = "==" | "!=" | "<" | "<=" | ">" | ">=" | "=" name "="
+//	arguments  = "(" value { "," value } ")" | value
+//
+// Unquoted `==` values with wildcards match ignoring case: `foo*` is `starts with`, `*foo*` is `like`.
+// `=isnull=true` and `=isnull=false` are `is null` and `is not null`.
+func ParseRSQL(input string) (Node, error) {
+	p := &rsqlParser{input: []rune(input)}
+	if p.skipSpaces(); p.eof() {
+		return nil, newParseError(p.position(), "empty filter")
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpaces(); !p.eof() {
+		return nil, p.unexpected("`;`, `,` or end of input")
+	}
+
+	return node, nil
+}
+
+type rsqlParser struct {
+	input []rune
+	pos   int
+}
+
+type rsqlValue struct {
+	text   string
+	quoted bool
+	// pos is 1-based position of the value in the input
+	pos int
+}
+
+func (p *rsqlParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+// position returns 1-based position of the current character
+func (p *rsqlParser) position() int {
+	return p.pos + 1
+}
+
+func (p *rsqlParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *rsqlParser) skipSpaces() {
+	for !p.eof() && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// keyword consumes `and` or `or` written as a word followed by space or parenthesis
+func (p *rsqlParser) keyword(word string) bool {
+	end := p.pos + len(word)
+	if end >= len(p.input) || !strings.EqualFold(string(p.input[p.pos:end]), word) {
+		return false
+	}
+	if next := p.input[end]; !unicode.IsSpace(next) && next != '(' {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+func (p *rsqlParser) parseOr() (Node, error) {
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	or := &Or{Nodes: []Node{node}}
+	for {
+		p.skipSpaces()
+		if p.peek() == ',' {
+			p.pos++
+		} else if !p.keyword(keywordOr) {
+			break
+		}
+		if node, err = p.parseAnd(); err != nil {
+			return nil, err
+		}
+		or.Nodes = append(or.Nodes, node)
+	}
+
+	if len(or.Nodes) == 1 {
+		return or.Nodes[0], nil
+	}
+	return or, nil
+}
+
+func (p *rsqlParser) parseAnd() (Node, error) {
+	node, err := p.parseConstraint()
+	if err != nil {
+		return nil, err
+	}
+
+	and := &And{Nodes: []Node{node}}
+	for {
+		p.skipSpaces()
+		if p.peek() == ';' {
+			p.pos++
+		} else if !p.keyword(keywordAnd) {
+			break
+		}
+		if node, err = p.parseConstraint(); err != nil {
+			return nil, err
+		}
+		and.Nodes = append(and.Nodes, node)
+	}
+
+	if len(and.Nodes) == 1 {
+		return and.Nodes[0], nil
+	}
+	return and, nil
+}
+
+func (p *rsqlParser) parseConstraint() (Node, error) {
+	p.skipSpaces()
+	if p.peek() != '(' {
+		return p.parseComparison()
+	}
+
+	open := p.position()
+	p.pos++
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpaces(); p.peek() != ')' {
+		return nil, p.unexpected(fmt.Sprintf("`)` closing `(` at position %d", open))
+	}
+	p.pos++
+	return node, nil
+}
+
+func (p *rsqlParser) parseComparison() (Node, error) {
+	pos := p.position()
+	selector := p.readUnreserved()
+	if selector == "" {
+		return nil, p.unexpected("field name")
+	}
+
+	p.skipSpaces()
+	operatorPos := p.position()
+	operator, err := p.parseOperator()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	values, err := p.parseArguments()
+	if err != nil {
+		return nil, err
+	}
+
+	field, err := rsqlCondition(selector, operator, values)
+	if err != nil {
+		return nil, &ParseError{Pos: operatorPos, Field: selector, Err: err}
+	}
+
+	var valuePos []int
+	if len(field.Values) > 0 {
+		for _, value := range values {
+			valuePos = append(valuePos, value.pos)
+		}
+	}
+	return &Condition{Field: field, Pos: pos, OperatorPos: operatorPos, ValuePos: valuePos}, nil
+}
+
+func (p *rsqlParser) parseOperator() (string, error) {
+	start := p.pos
+	switch p.peek() {
+	case '=':
+		p.pos++
+		if p.peek() == '=' {
+			p.pos++
+			break
+		}
+		for !p.eof() && unicode.IsLetter(p.input[p.pos]) {
+			p.pos++
+		}
+		if p.peek() != '=' || p.pos == start+1 {
+			p.pos = start
+			return "", p.unexpected("operator")
+		}
+		p.pos++
+	case '!':
+		p.pos++
+		if p.peek() != '=' {
+			p.pos = start
+			return "", p.unexpected("operator")
+		}
+		p.pos++
+	case '<', '>':
+		p.pos++
+		if p.peek() == '=' {
+			p.pos++
+		}
+	default:
+		return "", p.unexpected("operator")
+	}
+
+	operator := strings.ToLower(string(p.input[start:p.pos]))
+	if _, ok := rsqlOperators[operator]; !ok {
+		return "", &ParseError{Pos: start + 1, Err: fmt.Errorf("%w `%s`", ErrBadOperator, operator)}
+	}
+	return operator, nil
+}
+
+func (p *rsqlParser) parseArguments() ([]rsqlValue, error) {
+	if p.peek() != '(' {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return []rsqlValue{value}, nil
+	}
+
+	open := p.position()
+	p.pos++
+	var values []rsqlValue
+	for {
+		p.skipSpaces()
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		p.skipSpaces()
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	if p.peek() != ')' {
+		return nil, p.unexpected(fmt.Sprintf("`,` or `)` closing `(` at position %d", open))
+	}
+	p.pos++
+	return values, nil
+}
+
+// parseValue reads unreserved characters or string enclosed in single or double quotes,
+// `\` escapes the next character of quoted string
+func (p *rsqlParser) parseValue() (rsqlValue, error) {
+	start := p.position()
+	quote := p.peek()
+	if quote != '"' && quote != '\'' {
+		text := p.readUnreserved()
+		if text == "" {
+			return rsqlValue{}, p.unexpected("value")
+		}
+		return rsqlValue{text: text, pos: start}, nil
+	}
+
+	var sb strings.Builder
+	for p.pos++; !p.eof(); p.pos++ {
+		r := p.input[p.pos]
+		if r == '\\' {
+			p.pos++
+			if p.eof() {
+				return rsqlValue{}, newParseError(p.position(), "unfinished escape sequence")
+			}
+			sb.WriteRune(p.input[p.pos])
+			continue
+		}
+		if r == quote {
+			p.pos++
+			return rsqlValue{text: sb.String(), quoted: true, pos: start}, nil
+		}
+		sb.WriteRune(r)
+	}
+	return rsqlValue{}, newParseError(start, "unterminated string")
+}
+
+func (p *rsqlParser) readUnreserved() string {
+	start := p.pos
+	for !p.eof() && !unicode.IsSpace(p.input[p.pos]) && !strings.ContainsRune(rsqlReserved, p.input[p.pos]) {
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+func (p *rsqlParser) unexpected(expected string) error {
+	found := "end of input"
+	if !p.eof() {
+		found = fmt.Sprintf("`%c`", p.input[p.pos])
+	}
+	return newParseError(p.position(), fmt.Sprintf("expected %s, found %s", expected, found))
+}
+
+// rsqlCondition converts RSQL comparison into condition without data type, it's resolved by Opts
+func rsqlCondition(selector, rsqlOperator string, args []rsqlValue) (Field, error) {
+	operator := rsqlOperators[rsqlOperator]
+	values := make([]string, len(args))
+	wildcard := false
+	for i, arg := range args {
+		values[i] = arg.text
+		wildcard = wildcard || !arg.quoted && strings.Contains(arg.text, rsqlWildcard)
+	}
+
+	switch {
+	case operator == OperatorIsNull:
+		if len(values) != 1 {
+			return Field{}, fmt.Errorf("operator `%s` requires true or false", rsqlOperator)
+		}
+		switch strings.ToLower(values[0]) {
+		case "true":
+		case "false":
+			operator = OperatorIsNotNull
+		default:
+			return Field{}, fmt.Errorf("operator `%s` requires true or false, got `%s`", rsqlOperator, values[0])
+		}
+		values = nil
+	case wildcard && operator != OperatorEq:
+		return Field{}, fmt.Errorf("wildcards are supported only by `==`")
+	case wildcard && len(values) == 1:
+		var err error
+		operator, values[0], err = rsqlWildcardOperator(values[0])
+		if err != nil {
+			return Field{}, err
+		}
+	}
+
+	return Field{
+		Name:     selector,
+		Operator: operator,
+		Value:    strings.Join(values, ","),
+		Values:   values,
+	}, nil
+}
+
+// rsqlWildcardOperator resolves `prefix*` and `*substring*` patterns
+func rsqlWildcardOperator(pattern string) (string, string, error) {
+	inner := strings.TrimSuffix(pattern, rsqlWildcard)
+	switch {
+	case inner == pattern || inner == "":
+	case strings.HasPrefix(inner, rsqlWildcard):
+		substring := strings.TrimPrefix(inner, rsqlWildcard)
+		if substring != "" && !strings.Contains(substring, rsqlWildcard) {
+			return OperatorLike, substring, nil
+		}
+	case !strings.Contains(inner, rsqlWildcard):
+		return OperatorStartsWith, inner, nil
+	}
+	return "", "", fmt.Errorf("unsupported wildcard pattern `%s`, use `prefix*` or `*substring*`", pattern)
+}
@@ -0,0 +1,100 @@
+package filter
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseRSQL(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: `name==apple`, want: `(name eq apple)`},
+		{input: `a==1;b!=2,c=gt=3`, want: `(or (and (a eq 1) (b neq 2)) (c gt 3))`},
+		{input: `a==1;(b==2,c==3)`, want: `(and (a eq 1) (or (b eq 2) (c eq 3)))`},
+		{input: `a==1 and b==2 or c==3`, want: `(or (and (a eq 1) (b eq 2)) (c eq 3))`},
+		{input: `a<1;a<=2;a>3;a>=4`, want: `(and (a lt 1) (a le 2) (a gt 3) (a ge 4))`},
+		{input: `a=IN=(1, 2);b=out=(x)`, want: `(and (a in 1,2) (b not in x))`},
+		{input: `a=between=(1,5)`, want: `(a between 1,5)`},
+		{input: `name==foo*`, want: `(name starts with foo)`},
+		{input: `name==*foo*`, want: `(name like foo)`},
+		{input: `name=="foo*"`, want: `(name eq foo*)`},
+		{input: `name=like=foo`, want: `(name like foo)`},
+		{input: `a=isnull=true;b=isnull=FALSE`, want: `(and (a is null) (b is not null))`},
+		{input: `name=='red \'apple\''`, want: `(name eq red 'apple')`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node, err := ParseRSQL(tt.input)
+			if err != nil {
+				t.Fatalf("ParseRSQL() error = %v", err)
+			}
+			if got := render(node); got != tt.want {
+				t.Errorf("ParseRSQL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRSQLErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: ``, want: "empty filter at position 1"},
+		{input: `==1`, want: "expected field name, found `=` at position 1"},
+		{input: `name`, want: "expected operator, found end of input at position 5"},
+		{input: `name=foo=1`, want: "bad operator `=foo=` at position 5"},
+		{input: `name==`, want: "expected value, found end of input at position 7"},
+		{input: `a=in=(1,2`, want: "expected `,` or `)` closing `(` at position 6, found end of input at position 10"},
+		{input: `(a==1`, want: "expected `)` closing `(` at position 1, found end of input at position 6"},
+		{input: `a==1)`, want: "expected `;`, `,` or end of input, found `)` at position 5"},
+		{input: `name=="red`, want: "unterminated string at position 7"},
+		{input: `a=isnull=maybe`, want: "operator `=isnull=` requires true or false, got `maybe` at position 2"},
+		{input: `a=gt=x*`, want: "wildcards are supported only by `==` at position 2"},
+		{input: `a==f*o*`, want: "unsupported wildcard pattern `f*o*`, use `prefix*` or `*substring*` at position 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := ParseRSQL(tt.input)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("ParseRSQL() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRSQLErrorPositions(t *testing.T) {
+	types := map[string]string{"name": DataTypeStr, "price": DataTypeInt}
+
+	tests := []struct {
+		input string
+		want  []int
+	}{
+		{input: `name==a;price=in=(1,x,"y")`, want: []int{21, 23}},
+		{input: `price==x`, want: []int{8}},
+		{input: `price=between=(1)`, want: []int{6}},
+		{input: `price==1*`, want: []int{6}},
+		{input: `color==red`, want: []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			err := NewOptions(10, 0, types).AddRSQL(tt.input)
+			if err == nil {
+				t.Fatal("AddRSQL() error = nil")
+			}
+
+			var got []int
+			for _, e := range parseErrors(t, err) {
+				got = append(got, e.Pos)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("AddRSQL() positions = %v, want %v (%v)", got, tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// dateLayouts are accepted formats of DataTypeDate and DataTypeTimeArray values
+var dateLayouts = []string{time.DateOnly, time.RFC3339}
+
+// parseValue converts raw value into Go value of the data type
+func parseValue(dType, value string) (interface{}, error) {
+	switch dType {
+	case DataTypeInt:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value `%s` is not an integer", value)
+		}
+		return v, nil
+	case DataTypeBool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("value `%s` is not a boolean", value)
+		}
+		return v, nil
+	case DataTypeDate, DataTypeTimeArray:
+		for _, layout := range dateLayouts {
+			if v, err := time.Parse(layout, value); err == nil {
+				return v, nil
+			}
+		}
+		return nil, fmt.Errorf("value `%s` is not a date, expected YYYY-MM-DD or RFC3339", value)
+	default:
+		return value, nil
+	}
+}
+
+// validateOperatorType checks that operator can be applied to fields of the data type
+func validateOperatorType(operator, dType string) error {
+	switch operator {
+	case OperatorLike, OperatorCaseSensitiveLike, OperatorStartsWith:
+		if dType != DataTypeStr {
+			return fmt.Errorf("operator `%s` can be used only with string fields", operator)
+		}
+	case OperatorLowerThan, OperatorLowerThanEq, OperatorGreaterThan, OperatorGreaterThanEq, OperatorBetween:
+		if dType == DataTypeBool {
+			return fmt.Errorf("operator `%s` can't be used with boolean fields", operator)
+		}
+	}
+
+	if (dType == DataTypeArray || dType == DataTypeTimeArray) && operator != OperatorIn && operator != OperatorNotIn {
+		return fmt.Errorf("with array type name you can use only `in` and `not in` operators, got `%s`", operator)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package jwt
+
+import "context"
+
+type (
+	ctxUserID struct{}
+	ctxRoleID struct{}
+)
+
+// contextWithClaims adds authenticated user of token claims to context
+func contextWithClaims(ctx context.Context, claims *CustomClaims) context.Context {
+	ctx = context.WithValue(ctx, ctxUserID{}, claims.UserID)
+	return ContextWithRoleID(ctx, claims.RoleID)
+}
+
+// ContextWithRoleID adds authenticated user role to context
+func ContextWithRoleID(ctx context.Context, roleID uint64) context.Context {
+	return context.WithValue(ctx, ctxRoleID{}, roleID)
+}
+
+// RoleIDFromContext returns authenticated user role from context
+func RoleIDFromContext(ctx context.Context) (uint64, bool) {
+	roleID, ok := ctx.Value(ctxRoleID{}).(uint64)
+	return roleID, ok
+}
+
+// HasRole reports whether authenticated user has one of the roles
+func HasRole(ctx context.Context, roles ...uint64) bool {
+	roleID, ok := RoleIDFromContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, role := range roles {
+		if role == roleID {
+			return true
+		}
+	}
+	return false
+}
+
+// ActorFromContext returns authenticated user id or fallback when request is anonymous
+func ActorFromContext(ctx context.Context, fallback string) string {
+	if userID, err := GetUserID(ctx); err == nil {
+		return userID
+	}
+	return fallback
+}
@@ -6,6 +6,8 @@ import (
 	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
 	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type AuthInterceptor struct {
@@ -21,20 +23,23 @@ func (i *AuthInterceptor) AuthorizeHandler(ctx context.Context) (context.Context
 	fromContext := grpc.ServerTransportStreamFromContext(ctx)
 	method := fromContext.Method()
 
-	accessibleRoles, ok := i.roles[method]
-	if !ok {
-		// everyone can access
-		return ctx, nil
-	}
+	// if method has no roles everyone can access, but token is still parsed when present
+	accessibleRoles, restricted := i.roles[method]
 
 	token, err := grpc_auth.AuthFromMD(ctx, "bearer")
 	if err != nil {
+		if !restricted {
+			return ctx, nil
+		}
 		return nil, err
 	}
 
 	tokenMC, err := i.jwtHelper.ParseToken(token)
 	if err != nil {
-		return nil, ErrBadToken
+		if !restricted {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, ErrBadToken.Error())
 	}
 
 	claims := i.jwtHelper.ParseMapClaims(tokenMC)
@@ -42,14 +47,17 @@ func (i *AuthInterceptor) AuthorizeHandler(ctx context.Context) (context.Context
 	grpc_ctxtags.Extract(ctx).Set("role_id", claims.RoleID)
 	grpc_ctxtags.Extract(ctx).Set("user_id", claims.UserID)
 
+	ctx = contextWithClaims(ctx, claims)
+
+	if !restricted {
+		return ctx, nil
+	}
+
 	for _, role := range accessibleRoles {
 		if role == claims.RoleID {
 			return ctx, nil
 		}
 	}
 
-	ctx = context.WithValue(ctx, "role_id", claims.RoleID)
-	ctx = context.WithValue(ctx, "user_id", claims.UserID)
-
-	return ctx, nil
+	return nil, status.Error(codes.PermissionDenied, "forbidden")
 }
@@ -8,50 +8,12 @@ import (
 
 func Middleware(h http.HandlerFunc, secretJWT string, roleID ...uint64) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		helper := NewHelper(secretJWT)
-
-		cook, err := r.Cookie(AccessTokenName)
-		if err != nil {
+		tokenClaims, reason := authenticate(w, r, NewHelper(secretJWT))
+		if tokenClaims == nil {
 			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("no cookie"))
+			w.Write([]byte(reason))
 			return
 		}
-		jwtToken := cook.Value
-
-		tokenMC, err := helper.ParseToken(jwtToken)
-		if err != nil {
-			cook, err = r.Cookie(RefreshTokenName)
-			if err != nil {
-				w.WriteHeader(http.StatusUnauthorized)
-				w.Write([]byte("bad access cookie. no refresh cookie"))
-				return
-			}
-			refreshT := cook.Value
-
-			mapClaims, err := helper.ParseToken(refreshT)
-			if err != nil {
-				w.WriteHeader(http.StatusUnauthorized)
-				w.Write([]byte("bad access and refresh cookies"))
-				return
-			}
-			claims := helper.ParseMapClaims(mapClaims)
-
-			pair, err := helper.GeneratePair(claims.UserID, claims.IssuerName, claims.RoleID)
-			if err != nil {
-				w.WriteHeader(http.StatusUnauthorized)
-				w.Write([]byte("bad access and refresh cookies"))
-				return
-			}
-
-			accessCook, refreshCook := helper.PrepareCookies(pair)
-			http.SetCookie(w, accessCook)
-			http.SetCookie(w, refreshCook)
-
-			jwtToken = pair.AccessToken
-			tokenMC, err = helper.ParseToken(jwtToken)
-		}
-
-		tokenClaims := helper.ParseMapClaims(tokenMC)
 
 		var RoleExist bool
 		for _, rID := range roleID {
@@ -67,10 +29,61 @@ func Middleware(h http.HandlerFunc, secretJWT string, roleID ...uint64) http.Han
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), "user_id", tokenClaims.UserID)
-		ctx = context.WithValue(ctx, "user_role_id", tokenClaims.RoleID)
-		h(w, r.WithContext(ctx))
+		h(w, r.WithContext(contextWithClaims(r.Context(), tokenClaims)))
+	}
+}
+
+// OptionalMiddleware adds claims of token to context when request has valid one,
+// request without token or with bad one is served as anonymous
+func OptionalMiddleware(h http.HandlerFunc, secretJWT string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if tokenClaims, _ := authenticate(w, r, NewHelper(secretJWT)); tokenClaims != nil {
+			r = r.WithContext(contextWithClaims(r.Context(), tokenClaims))
+		}
+		h(w, r)
+	}
+}
+
+// authenticate returns claims of access token, expired access token is renewed by refresh token.
+// Claims are nil with reason when request isn't authenticated.
+func authenticate(w http.ResponseWriter, r *http.Request, helper Helper) (*CustomClaims, string) {
+	cook, err := r.Cookie(AccessTokenName)
+	if err != nil {
+		return nil, "no cookie"
+	}
+	jwtToken := cook.Value
+
+	tokenMC, err := helper.ParseToken(jwtToken)
+	if err != nil {
+		cook, err = r.Cookie(RefreshTokenName)
+		if err != nil {
+			return nil, "bad access cookie. no refresh cookie"
+		}
+		refreshT := cook.Value
+
+		mapClaims, err := helper.ParseToken(refreshT)
+		if err != nil {
+			return nil, "bad access and refresh cookies"
+		}
+		claims := helper.ParseMapClaims(mapClaims)
+
+		pair, err := helper.GeneratePair(claims.UserID, claims.IssuerName, claims.RoleID)
+		if err != nil {
+			return nil, "bad access and refresh cookies"
+		}
+
+		accessCook, refreshCook := helper.PrepareCookies(pair)
+		http.SetCookie(w, accessCook)
+		http.SetCookie(w, refreshCook)
+
+		jwtToken = pair.AccessToken
+		tokenMC, err = helper.ParseToken(jwtToken)
+		if err != nil {
+			return nil, "bad access and refresh cookies"
+		}
 	}
+
+	return helper.ParseMapClaims(tokenMC), ""
 }
 
 func unauthorized(w http.ResponseWriter, err error) {
@@ -79,7 +92,7 @@ func unauthorized(w http.ResponseWriter, err error) {
 }
 
 func GetUserID(ctx context.Context) (string, error) {
-	if mr, ok := ctx.Value("user_id").(string); ok && mr != "" {
+	if mr, ok := ctx.Value(ctxUserID{}).(string); ok && mr != "" {
 		return mr, nil
 
 	}
@@ -87,9 +100,8 @@ func GetUserID(ctx context.Context) (string, error) {
 }
 
 func GetRoleID(ctx context.Context) (int, error) {
-	mr := ctx.Value("user_role_id")
-	if roleID, ok := mr.(int); ok {
-		return roleID, nil
+	if roleID, ok := RoleIDFromContext(ctx); ok {
+		return int(roleID), nil
 	}
 	return 0, fmt.Errorf("something wrong with user role id in context")
 }
@@ -0,0 +1,18 @@
+package locale
+
+import "context"
+
+type ctxLocale struct{}
+
+// ContextWithLocales adds locale fallback chain to context
+func ContextWithLocales(ctx context.Context, chain []string) context.Context {
+	return context.WithValue(ctx, ctxLocale{}, chain)
+}
+
+// LocalesFromContext returns locale fallback chain from context
+func LocalesFromContext(ctx context.Context) []string {
+	if chain, ok := ctx.Value(ctxLocale{}).([]string); ok {
+		return chain
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package locale
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnaryServerInterceptor negotiates locale chain from `accept-language` metadata
+func (n *Negotiator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var acceptLanguage string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(MetadataKey); len(values) > 0 {
+				acceptLanguage = values[0]
+			}
+		}
+
+		return handler(ContextWithLocales(ctx, n.Negotiate(acceptLanguage)), req)
+	}
+}
@@ -0,0 +1,12 @@
+package locale
+
+import "net/http"
+
+// Middleware negotiates locale chain from Accept-Language header
+func (n *Negotiator) Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chain := n.Negotiate(r.Header.Get(HeaderAcceptLanguage))
+		w.Header().Set("Content-Language", chain[0])
+		h.ServeHTTP(w, r.WithContext(ContextWithLocales(r.Context(), chain)))
+	})
+}
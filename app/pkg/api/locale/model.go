@@ -0,0 +1,117 @@
+package locale
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	HeaderAcceptLanguage = "Accept-Language"
+	MetadataKey          = "accept-language"
+)
+
+// Negotiator выбирает цепочку локалей для запроса из списка поддерживаемых
+type Negotiator struct {
+	supported     map[string]struct{}
+	defaultLocale string
+}
+
+func NewNegotiator(supported []string, defaultLocale string) *Negotiator {
+	s := make(map[string]struct{}, len(supported))
+	for _, l := range supported {
+		s[Normalize(l)] = struct{}{}
+	}
+	defaultLocale = Normalize(defaultLocale)
+	s[defaultLocale] = struct{}{}
+
+	return &Negotiator{
+		supported:     s,
+		defaultLocale: defaultLocale,
+	}
+}
+
+// Default returns default locale of negotiator
+func (n *Negotiator) Default() string {
+	return n.defaultLocale
+}
+
+// IsSupported reports whether locale is one of supported locales
+func (n *Negotiator) IsSupported(locale string) bool {
+	_, ok := n.supported[Normalize(locale)]
+	return ok
+}
+
+// Negotiate parses Accept-Language value and returns fallback chain of supported locales.
+// Every requested tag is followed by its base language (en-US -> en) and the chain always
+// ends with the default locale.
+func (n *Negotiator) Negotiate(acceptLanguage string) []string {
+	chain := make([]string, 0, 3)
+	seen := make(map[string]struct{})
+	add := func(l string) {
+		if _, ok := n.supported[l]; !ok {
+			return
+		}
+		if _, ok := seen[l]; ok {
+			return
+		}
+		seen[l] = struct{}{}
+		chain = append(chain, l)
+	}
+
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		add(tag)
+		if base, _, found := strings.Cut(tag, "-"); found {
+			add(base)
+		}
+	}
+	add(n.defaultLocale)
+
+	return chain
+}
+
+// Normalize приводит тег локали к виду "en-us"
+func Normalize(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+type weightedTag struct {
+	tag    string
+	weight float64
+}
+
+func parseAcceptLanguage(value string) []string {
+	var tags []weightedTag
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		tag, params, _ := strings.Cut(part, ";")
+		weight := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			w, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			weight = w
+		}
+
+		tag = Normalize(tag)
+		if tag == "" || tag == "*" || weight <= 0 {
+			continue
+		}
+		tags = append(tags, weightedTag{tag: tag, weight: weight})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].weight > tags[j].weight
+	})
+
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
+}
@@ -0,0 +1,62 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	ContentType = "application/problem+json"
+	// TypeDefault is used when problem has no type URI of its own
+	TypeDefault = "about:blank"
+)
+
+// Problem is RFC 7807 problem details response
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// InvalidParams is extension member listing request parameters which failed validation
+	InvalidParams []InvalidParam `json:"invalid-params,omitempty"`
+}
+
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// New creates problem of TypeDefault with status text as title
+func New(status int, detail string) *Problem {
+	return &Problem{
+		Type:   TypeDefault,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// WithInvalidParam adds invalid request parameter
+func (p *Problem) WithInvalidParam(name, reason string) *Problem {
+	p.InvalidParams = append(p.InvalidParams, InvalidParam{Name: name, Reason: reason})
+	return p
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+// Write writes problem as response, instance is set to request path when empty
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) error {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	return json.NewEncoder(w).Encode(p)
+}
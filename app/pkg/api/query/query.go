@@ -0,0 +1,232 @@
+package query
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/filter"
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/problem"
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/sort"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	"github.com/hashicorp/go-multierror"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	ParamFilter       = "filter"
+	ParamFilterSyntax = "filter_syntax"
+	ParamSort         = "sort"
+	ParamLimit        = "limit"
+	ParamOffset       = "offset"
+	ParamCursor       = "cursor"
+)
+
+// Resource describes how list query of one resource is bound
+type Resource struct {
+	// FilterFields are filterable fields with their data types
+	FilterFields map[string]string
+	// SortFields are sortable fields, see sort.Parse
+	SortFields []string
+	// DefaultLimit is used when limit is not set, 0 means no limit
+	DefaultLimit uint64
+	// MaxLimit rejects greater limits, 0 means any limit is allowed
+	MaxLimit uint64
+}
+
+// Query is bound list query. Filter carries limit and offset as well.
+type Query struct {
+	Filter filter.Filterable
+	Sort   sort.Sortable
+	Limit  uint64
+	Offset uint64
+	// fingerprint identifies filter, sort and limit, cursor can't be used with another query
+	fingerprint string
+}
+
+type cursor struct {
+	Offset      uint64 `json:"o"`
+	Fingerprint string `json:"f"`
+}
+
+// Bind parses `filter` (repeated, joined with AND) written in `filter_syntax`, `sort`, `limit`, `offset` and `cursor`
+// query parameters. Offset and cursor are mutually exclusive. All invalid parameters
+// are reported in one *problem.Problem, other errors are returned as is.
+func (res Resource) Bind(r *http.Request) (*Query, error) {
+	values := r.URL.Query()
+	p := problem.New(http.StatusBadRequest, "invalid query parameters")
+	q := &Query{Limit: res.DefaultLimit}
+
+	if raw := values.Get(ParamLimit); raw != "" {
+		limit, err := strconv.ParseUint(raw, 10, 64)
+		switch {
+		case err != nil:
+			p.WithInvalidParam(ParamLimit, fmt.Sprintf("`%s` is not a non-negative integer", raw))
+		case res.MaxLimit > 0 && limit > res.MaxLimit:
+			p.WithInvalidParam(ParamLimit, fmt.Sprintf("must not be greater than %d", res.MaxLimit))
+		default:
+			q.Limit = limit
+		}
+	}
+
+	sorting, err := sort.Parse(values.Get(ParamSort), res.SortFields)
+	if err != nil {
+		for _, e := range flatten(err) {
+			p.WithInvalidParam(ParamSort, e.Error())
+		}
+	}
+	q.Sort = sorting
+	syntax := values.Get(ParamFilterSyntax)
+	validSyntax := true
+	switch syntax {
+	case "", filter.SyntaxExpression, filter.SyntaxRSQL:
+	default:
+		validSyntax = false
+		p.WithInvalidParam(ParamFilterSyntax, fmt.Sprintf("`%s` is not one of `%s`, `%s`",
+			syntax, filter.SyntaxExpression, filter.SyntaxRSQL))
+	}
+	q.fingerprint = fingerprint(syntax, values[ParamFilter], values.Get(ParamSort), q.Limit)
+
+	rawOffset, rawCursor := values.Get(ParamOffset), values.Get(ParamCursor)
+	switch {
+	case rawOffset != "" && rawCursor != "":
+		p.WithInvalidParam(ParamCursor, "can't be used together with offset")
+	case rawOffset != "":
+		if q.Offset, err = strconv.ParseUint(rawOffset, 10, 64); err != nil {
+			p.WithInvalidParam(ParamOffset, fmt.Sprintf("`%s` is not a non-negative integer", rawOffset))
+		}
+	case rawCursor != "":
+		c, err := decodeCursor(rawCursor)
+		switch {
+		case err != nil:
+			p.WithInvalidParam(ParamCursor, "malformed cursor")
+		case c.Fingerprint != q.fingerprint:
+			p.WithInvalidParam(ParamCursor, "cursor was issued for another filter, sort or limit")
+		default:
+			q.Offset = c.Offset
+		}
+	}
+
+	filtering := filter.NewOptions(q.Limit, q.Offset, res.FilterFields)
+	for _, rawFilter := range values[ParamFilter] {
+		if !validSyntax {
+			break
+		}
+		if err = filtering.AddQuery(syntax, rawFilter); err != nil {
+			for _, e := range flatten(err) {
+				violations := filter.Violations(e)
+				if len(violations) == 0 {
+					// not a problem of input, filter would be applied partially
+					return nil, err
+				}
+				for _, v := range violations {
+					reason := v.Description
+					if v.Field != ParamFilter {
+						reason = fmt.Sprintf("%s: %s", v.Field, reason)
+					}
+					p.WithInvalidParam(ParamFilter, reason)
+				}
+			}
+		}
+	}
+	q.Filter = filtering
+
+	if len(p.InvalidParams) > 0 {
+		return nil, p
+	}
+	return q, nil
+}
+
+// NextCursor returns cursor of the next page, or empty string when page of returned items is the last one
+func (q *Query) NextCursor(returned int) string {
+	if q.Limit == 0 || uint64(returned) < q.Limit {
+		return ""
+	}
+	return encodeCursor(cursor{Offset: q.Offset + q.Limit, Fingerprint: q.fingerprint})
+}
+
+// SetNextLink sets RFC 8288 `Link` header with URL of the next page when there is one
+func SetNextLink(w http.ResponseWriter, r *http.Request, q *Query, returned int) {
+	next := q.NextCursor(returned)
+	if next == "" {
+		return
+	}
+
+	values := r.URL.Query()
+	values.Del(ParamOffset)
+	values.Set(ParamCursor, next)
+	link := url.URL{Path: r.URL.Path, RawQuery: values.Encode()}
+	w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="next"`, link.String()))
+}
+
+type ctxQuery struct{}
+
+// Middleware binds query and passes it to next handler in context, see FromContext.
+// Bad input is answered with RFC 7807 problem.
+func (res Resource) Middleware(next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		q, err := res.Bind(r)
+		if err != nil {
+			var p *problem.Problem
+			if !errors.As(err, &p) {
+				logging.WithError(r.Context(), err).Error("failed to bind query")
+				p = problem.New(http.StatusInternalServerError, "failed to bind query")
+			}
+			_ = problem.Write(w, r, p)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), ctxQuery{}, q)), params)
+	}
+}
+
+// FromContext returns query bound by Middleware
+func FromContext(ctx context.Context) (*Query, bool) {
+	q, ok := ctx.Value(ctxQuery{}).(*Query)
+	return q, ok
+}
+
+func fingerprint(syntax string, filters []string, sorting string, limit uint64) string {
+	h := sha256.New()
+	if syntax != "" && syntax != filter.SyntaxExpression {
+		h.Write([]byte(syntax))
+		h.Write([]byte{0})
+	}
+	for _, f := range filters {
+		h.Write([]byte(f))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte(sorting))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatUint(limit, 10)))
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+func encodeCursor(c cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(token string) (cursor, error) {
+	var c cursor
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(token))
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(raw, &c)
+	return c, err
+}
+
+func flatten(err error) []error {
+	if merr, ok := err.(*multierror.Error); ok {
+		return merr.WrappedErrors()
+	}
+	return []error{err}
+}
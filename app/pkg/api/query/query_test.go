@@ -0,0 +1,214 @@
+package query
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/filter"
+	"github.com/HollyEllmo/my-first-go-project/pkg/api/problem"
+)
+
+var resource = Resource{
+	FilterFields: map[string]string{"name": filter.DataTypeStr, "price": filter.DataTypeInt},
+	SortFields:   []string{"name", "price"},
+	DefaultLimit: 10,
+	MaxLimit:     100,
+}
+
+func newRequest(values url.Values) *http.Request {
+	return httptest.NewRequest(http.MethodGet, "/items?"+values.Encode(), nil)
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     url.Values
+		wantLimit  uint64
+		wantOffset uint64
+		wantFields []string
+		wantSort   string
+	}{
+		{name: "defaults", values: url.Values{}, wantLimit: 10},
+		{
+			name:       "all parameters",
+			values:     url.Values{"limit": {"5"}, "offset": {"15"}, "sort": {"-price"}, "filter": {"price gt 1", "name eq a"}},
+			wantLimit:  5,
+			wantOffset: 15,
+			wantFields: []string{"price", "name"},
+			wantSort:   "price",
+		},
+		{
+			name:       "rsql",
+			values:     url.Values{"filter_syntax": {"rsql"}, "filter": {"price=gt=1;name==a*"}},
+			wantLimit:  10,
+			wantFields: []string{"price", "name"},
+		},
+		{name: "zero limit", values: url.Values{"limit": {"0"}}, wantLimit: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := resource.Bind(newRequest(tt.values))
+			if err != nil {
+				t.Fatalf("Bind() error = %v", err)
+			}
+			if q.Limit != tt.wantLimit || q.Offset != tt.wantOffset {
+				t.Errorf("Bind() limit, offset = %d, %d, want %d, %d", q.Limit, q.Offset, tt.wantLimit, tt.wantOffset)
+			}
+			if q.Filter.Limit() != q.Limit || q.Filter.Offset() != q.Offset {
+				t.Errorf("Filter limit, offset = %d, %d, want %d, %d", q.Filter.Limit(), q.Filter.Offset(), q.Limit, q.Offset)
+			}
+
+			var fields []string
+			for _, f := range q.Filter.Fields() {
+				fields = append(fields, f.Name)
+			}
+			if !slices.Equal(fields, tt.wantFields) {
+				t.Errorf("Filter fields = %v, want %v", fields, tt.wantFields)
+			}
+			if q.Sort.Field() != tt.wantSort {
+				t.Errorf("Sort field = %q, want %q", q.Sort.Field(), tt.wantSort)
+			}
+		})
+	}
+}
+
+func TestBindInvalidParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		values url.Values
+		want   []problem.InvalidParam
+	}{
+		{
+			name:   "limit",
+			values: url.Values{"limit": {"-1"}},
+			want:   []problem.InvalidParam{{Name: ParamLimit, Reason: "`-1` is not a non-negative integer"}},
+		},
+		{
+			name:   "max limit",
+			values: url.Values{"limit": {"101"}},
+			want:   []problem.InvalidParam{{Name: ParamLimit, Reason: "must not be greater than 100"}},
+		},
+		{
+			name:   "offset",
+			values: url.Values{"offset": {"x"}},
+			want:   []problem.InvalidParam{{Name: ParamOffset, Reason: "`x` is not a non-negative integer"}},
+		},
+		{
+			name:   "offset and cursor",
+			values: url.Values{"offset": {"1"}, "cursor": {"abc"}},
+			want:   []problem.InvalidParam{{Name: ParamCursor, Reason: "can't be used together with offset"}},
+		},
+		{
+			name:   "malformed cursor",
+			values: url.Values{"cursor": {"%%%"}},
+			want:   []problem.InvalidParam{{Name: ParamCursor, Reason: "malformed cursor"}},
+		},
+		{
+			name:   "filter syntax",
+			values: url.Values{"filter_syntax": {"sql"}, "filter": {"price gt 1"}},
+			want:   []problem.InvalidParam{{Name: ParamFilterSyntax, Reason: "`sql` is not one of `expression`, `rsql`"}},
+		},
+		{
+			name:   "sort and filter",
+			values: url.Values{"sort": {"color,-color"}, "filter": {"price gt x and name like a", "("}},
+			want: []problem.InvalidParam{
+				{Name: ParamSort, Reason: "unknown sort field:`color`"},
+				{Name: ParamSort, Reason: "unknown sort field:`color`"},
+				{Name: ParamFilter, Reason: "price: value `x` is not an integer at position 10"},
+				{Name: ParamFilter, Reason: "expected field name, found end of input at position 2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := resource.Bind(newRequest(tt.values))
+			var p *problem.Problem
+			if !errors.As(err, &p) {
+				t.Fatalf("Bind() error = %v, want *problem.Problem", err)
+			}
+			if p.Status != http.StatusBadRequest {
+				t.Errorf("Bind() status = %d, want %d", p.Status, http.StatusBadRequest)
+			}
+			if !slices.Equal(p.InvalidParams, tt.want) {
+				t.Errorf("Bind() invalid params = %+v, want %+v", p.InvalidParams, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursor(t *testing.T) {
+	values := url.Values{"limit": {"2"}, "sort": {"name"}, "filter": {"price gt 1"}}
+	first, err := resource.Bind(newRequest(values))
+	if err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+
+	if next := first.NextCursor(1); next != "" {
+		t.Errorf("NextCursor() of short page = %q, want empty", next)
+	}
+	next := first.NextCursor(2)
+	if next == "" {
+		t.Fatal("NextCursor() of full page is empty")
+	}
+
+	values.Set(ParamCursor, next)
+	second, err := resource.Bind(newRequest(values))
+	if err != nil {
+		t.Fatalf("Bind() with cursor error = %v", err)
+	}
+	if second.Offset != 2 || second.Filter.Offset() != 2 {
+		t.Errorf("Bind() with cursor offset = %d, want 2", second.Offset)
+	}
+	if third := second.NextCursor(2); third == next {
+		t.Error("NextCursor() of the second page repeats the first cursor")
+	}
+
+	// курсор нельзя применить к запросу с другим фильтром, сортировкой или лимитом
+	for param, value := range map[string]string{ParamFilter: "price gt 2", ParamSort: "-name", ParamLimit: "3"} {
+		changed := url.Values{ParamCursor: {next}}
+		for k, v := range values {
+			if k != ParamCursor {
+				changed[k] = v
+			}
+		}
+		changed.Set(param, value)
+
+		_, err = resource.Bind(newRequest(changed))
+		var p *problem.Problem
+		if !errors.As(err, &p) || len(p.InvalidParams) != 1 || p.InvalidParams[0].Name != ParamCursor {
+			t.Errorf("Bind() with cursor and changed %s error = %v, want invalid cursor", param, err)
+		}
+	}
+}
+
+func TestNextCursorWithoutLimit(t *testing.T) {
+	q, err := Resource{}.Bind(newRequest(url.Values{}))
+	if err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+	if next := q.NextCursor(1000); next != "" {
+		t.Errorf("NextCursor() without limit = %q, want empty", next)
+	}
+}
+
+func TestSetNextLink(t *testing.T) {
+	r := newRequest(url.Values{"limit": {"1"}, "offset": {"3"}})
+	q, err := resource.Bind(r)
+	if err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	SetNextLink(w, r, q, 1)
+
+	want := `</items?cursor=` + q.NextCursor(1) + `&limit=1>; rel="next"`
+	if got := w.Header().Get("Link"); got != want {
+		t.Errorf("Link = %q, want %q", got, want)
+	}
+}
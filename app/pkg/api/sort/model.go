@@ -1,7 +1,10 @@
 package sort
 
 import (
+	"fmt"
 	"strings"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/errors"
 )
 
 type Order string
@@ -11,31 +14,145 @@ const (
 	OrderDESC Order = "DESC"
 )
 
+// Nulls places NULL values before or after other values, database default is used when empty
+type Nulls string
+
+const (
+	NullsFirst Nulls = "FIRST"
+	NullsLast  Nulls = "LAST"
+)
+
+const (
+	keysSeparator    = ","
+	descendingPrefix = "-"
+	nullsSeparator   = ":"
+	nullsFirstSuffix = "nulls_first"
+	nullsLastSuffix  = "nulls_last"
+	// anyPathSuffix marks allowed field which may be followed by a path, e.g. `specification.*`
+	anyPathSuffix = ".*"
+)
+
+// Key is one sort key, keys are applied in order
+type Key struct {
+	Field string
+	Order Order
+	Nulls Nulls
+}
+
 type Sortable interface {
+	// Field is field of the first key, empty when there are no keys
 	Field() string
+	// Order is order of the first key
 	Order() string
+	Keys() []Key
 }
 
 type opts struct {
-	field string
-	order string
+	keys []Key
+}
+
+// NewOptions parses sort keys like `-price,name:nulls_last` without checking field names.
+// Malformed keys are skipped.
+func NewOptions(raw string) *opts {
+	o := &opts{}
+	for _, rawKey := range strings.Split(raw, keysSeparator) {
+		if key, err := parseKey(rawKey); err == nil {
+			o.keys = append(o.keys, key)
+		}
+	}
+	return o
+}
+
+// Parse parses sort keys like `-price,name:nulls_last` allowing only given fields.
+// Field ending with `.*` allows any path under it, e.g. `specification.*` allows `specification.weight`.
+// All problems are returned at once as multierror.
+func Parse(raw string, allowed []string) (*opts, error) {
+	o := &opts{}
+	if strings.TrimSpace(raw) == "" {
+		return o, nil
+	}
+
+	var result error
+	seen := make(map[string]bool)
+	for _, rawKey := range strings.Split(raw, keysSeparator) {
+		key, err := parseKey(rawKey)
+		if err != nil {
+			result = errors.Append(result, err)
+			continue
+		}
+		if !isAllowed(key.Field, allowed) {
+			result = errors.Append(result, fmt.Errorf("unknown sort field:`%s`", key.Field))
+			continue
+		}
+		if seen[key.Field] {
+			result = errors.Append(result, fmt.Errorf("duplicate sort field:`%s`", key.Field))
+			continue
+		}
+		seen[key.Field] = true
+		o.keys = append(o.keys, key)
+	}
+
+	if result != nil {
+		return nil, result
+	}
+	return o, nil
 }
 
-func NewOptions(field string) *opts {
-	sortOrder := OrderASC
-	if strings.HasPrefix(field, "-") {
-		sortOrder = OrderDESC
-		field = strings.TrimPrefix(field, "-")
+func parseKey(raw string) (Key, error) {
+	raw = strings.TrimSpace(raw)
+	key := Key{Order: OrderASC}
+
+	if field, nulls, ok := strings.Cut(raw, nullsSeparator); ok {
+		switch strings.ToLower(nulls) {
+		case nullsFirstSuffix:
+			key.Nulls = NullsFirst
+		case nullsLastSuffix:
+			key.Nulls = NullsLast
+		default:
+			return Key{}, fmt.Errorf("wrong nulls placement:`%s`, expected `%s` or `%s`", nulls, nullsFirstSuffix, nullsLastSuffix)
+		}
+		raw = field
+	}
+
+	if strings.HasPrefix(raw, descendingPrefix) {
+		key.Order = OrderDESC
+		raw = strings.TrimPrefix(raw, descendingPrefix)
+	}
+	if raw == "" {
+		return Key{}, fmt.Errorf("empty sort field")
 	}
-	return &opts{
-		field: field,
-		order: string(sortOrder),
+
+	key.Field = raw
+	return key, nil
+}
+
+func isAllowed(field string, allowed []string) bool {
+	for _, a := range allowed {
+		if a == field {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(a, "*"); ok && strings.HasSuffix(a, anyPathSuffix) &&
+			strings.HasPrefix(field, prefix) && len(field) > len(prefix) {
+			return true
+		}
 	}
+	return false
 }
 
 func (o *opts) Field() string {
-	return o.field
+	if len(o.keys) == 0 {
+		return ""
+	}
+	return o.keys[0].Field
 }
+
 func (o *opts) Order() string {
-	return o.order
+	if len(o.keys) == 0 {
+		return string(OrderASC)
+	}
+	return string(o.keys[0].Order)
+}
+
+func (o *opts) Keys() []Key {
+	return o.keys
 }
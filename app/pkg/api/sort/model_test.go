@@ -0,0 +1,90 @@
+package sort
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+func TestParse(t *testing.T) {
+	allowed := []string{"id", "name", "price", "specification.*"}
+
+	tests := []struct {
+		raw  string
+		want []Key
+	}{
+		{raw: "", want: nil},
+		{raw: "  ", want: nil},
+		{raw: "name", want: []Key{{Field: "name", Order: OrderASC}}},
+		{raw: "-price, name", want: []Key{
+			{Field: "price", Order: OrderDESC},
+			{Field: "name", Order: OrderASC},
+		}},
+		{raw: "price:NULLS_FIRST,-name:nulls_last", want: []Key{
+			{Field: "price", Order: OrderASC, Nulls: NullsFirst},
+			{Field: "name", Order: OrderDESC, Nulls: NullsLast},
+		}},
+		{raw: "-specification.weight", want: []Key{{Field: "specification.weight", Order: OrderDESC}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := Parse(tt.raw, allowed)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !slices.Equal(got.Keys(), tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got.Keys(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	allowed := []string{"id", "name", "specification.*"}
+
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{raw: "color", want: []string{"unknown sort field:`color`"}},
+		{raw: "specification", want: []string{"unknown sort field:`specification`"}},
+		{raw: "specification.", want: []string{"unknown sort field:`specification.`"}},
+		{raw: "name,-name", want: []string{"duplicate sort field:`name`"}},
+		{raw: "-", want: []string{"empty sort field"}},
+		{raw: "name,", want: []string{"empty sort field"}},
+		{raw: "name:nulls", want: []string{"wrong nulls placement:`nulls`, expected `nulls_first` or `nulls_last`"}},
+		{raw: "color,-,id", want: []string{"unknown sort field:`color`", "empty sort field"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			_, err := Parse(tt.raw, allowed)
+			var merr *multierror.Error
+			if !errors.As(err, &merr) {
+				t.Fatalf("Parse() error = %v, want multierror", err)
+			}
+
+			var got []string
+			for _, e := range merr.WrappedErrors() {
+				got = append(got, e.Error())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Parse() errors = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewOptionsSkipsMalformedKeys(t *testing.T) {
+	got := NewOptions("-price,,name:bad,rating")
+	want := []Key{{Field: "price", Order: OrderDESC}, {Field: "rating", Order: OrderASC}}
+	if !slices.Equal(got.Keys(), want) {
+		t.Errorf("NewOptions() = %+v, want %+v", got.Keys(), want)
+	}
+	if got.Field() != "price" || got.Order() != string(OrderDESC) {
+		t.Errorf("Field(), Order() = %s, %s, want price, DESC", got.Field(), got.Order())
+	}
+}
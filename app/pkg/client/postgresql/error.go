@@ -0,0 +1,100 @@
+package postgresql
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// Kinds of classified database errors, check them with errors.Is
+var (
+	ErrNotFound            = errors.New("not found")
+	ErrAlreadyExists       = errors.New("already exists")
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	ErrCheckViolation      = errors.New("check violation")
+	ErrConflict            = errors.New("conflict")
+)
+
+// detailKey extracts column from detail of unique and foreign key violations, e.g. `Key (category_id)=(5) is not present`
+var detailKey = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+
+// Error is database error classified by Kind, it wraps both Kind and original error
+type Error struct {
+	Kind       error
+	Table      string
+	Constraint string
+	// Column is known for not-null violation or single-column keys
+	Column string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Constraint != "" {
+		return fmt.Sprintf("%s (constraint %s): %v", e.Kind, e.Constraint, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Kind, e.Err)
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// ParsePgError classifies pgx.ErrNoRows and SQLSTATE of pgconn.PgError into Error,
+// unknown errors are returned as is
+func ParsePgError(err error) error {
+	var classified *Error
+	if err == nil || errors.As(err, &classified) {
+		return err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &Error{Kind: ErrNotFound, Err: err}
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	var kind error
+	switch pgErr.Code {
+	case "23505": // unique_violation
+		kind = ErrAlreadyExists
+	case "23503": // foreign_key_violation
+		kind = ErrForeignKeyViolation
+	case "23514", "23502": // check_violation, not_null_violation
+		kind = ErrCheckViolation
+	case "40001", "40P01", "55P03": // serialization_failure, deadlock_detected, lock_not_available
+		kind = ErrConflict
+	default:
+		return err
+	}
+
+	column := pgErr.ColumnName
+	if m := detailKey.FindStringSubmatch(pgErr.Detail); column == "" && m != nil {
+		column = m[1]
+	}
+	return &Error{
+		Kind:       kind,
+		Table:      pgErr.TableName,
+		Constraint: pgErr.ConstraintName,
+		Column:     column,
+		Err:        err,
+	}
+}
+
+// ErrCreateQuery wraps error of building SQL
+func ErrCreateQuery(err error) error {
+	return fmt.Errorf("failed to create SQL Query due to error: %w", err)
+}
+
+// ErrDoQuery classifies database error, see ParsePgError
+func ErrDoQuery(err error) error {
+	return fmt.Errorf("failed to query due to error: %w", ParsePgError(err))
+}
+
+func ErrScan(err error) error {
+	return fmt.Errorf("failed to scan due to error: %w", ParsePgError(err))
+}
@@ -0,0 +1,110 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// QueryEvent describes executed statement. Rows is number of affected rows for Exec
+// and number of read rows for Query.
+type QueryEvent struct {
+	SQL      string
+	Args     []interface{}
+	Duration time.Duration
+	Rows     int64
+	Err      error
+}
+
+// QueryHook is notified after every statement of client, including statements of transactions
+type QueryHook interface {
+	AfterQuery(ctx context.Context, event QueryEvent)
+}
+
+// QueryHookFunc adapts function to QueryHook
+type QueryHookFunc func(ctx context.Context, event QueryEvent)
+
+func (f QueryHookFunc) AfterQuery(ctx context.Context, event QueryEvent) {
+	f(ctx, event)
+}
+
+// hooksLogger передает хукам события выполнения запросов из логгера pgx
+type hooksLogger struct {
+	hooks []QueryHook
+}
+
+func (l *hooksLogger) Log(ctx context.Context, _ pgx.LogLevel, msg string, data map[string]interface{}) {
+	if msg != "Query" && msg != "Exec" {
+		return
+	}
+
+	var event QueryEvent
+	event.SQL, _ = data["sql"].(string)
+	event.Args, _ = data["args"].([]interface{})
+	event.Duration, _ = data["time"].(time.Duration)
+	event.Err, _ = data["err"].(error)
+	if tag, ok := data["commandTag"].(pgconn.CommandTag); ok {
+		event.Rows = tag.RowsAffected()
+	}
+	if rows, ok := data["rowCount"].(int); ok {
+		event.Rows = int64(rows)
+	}
+
+	for _, hook := range l.hooks {
+		hook.AfterQuery(ctx, event)
+	}
+}
+
+// TraceQueries logs every statement at trace level, args are redacted
+func TraceQueries() QueryHook {
+	return QueryHookFunc(func(ctx context.Context, event QueryEvent) {
+		logger := logging.WithFields(ctx, map[string]interface{}{
+			"sql":         event.SQL,
+			"args":        RedactArgs(event.Args),
+			"duration_ms": event.Duration.Milliseconds(),
+			"rows":        event.Rows,
+		})
+		if event.Err != nil {
+			logger = logger.WithError(event.Err)
+		}
+		logger.Trace("query executed")
+	})
+}
+
+// SlowQueryLog logs statements which take threshold or longer at warning level, args are redacted
+func SlowQueryLog(threshold time.Duration) QueryHook {
+	return QueryHookFunc(func(ctx context.Context, event QueryEvent) {
+		if event.Duration < threshold {
+			return
+		}
+
+		logger := logging.WithFields(ctx, map[string]interface{}{
+			"sql":          event.SQL,
+			"args":         RedactArgs(event.Args),
+			"duration_ms":  event.Duration.Milliseconds(),
+			"threshold_ms": threshold.Milliseconds(),
+			"rows":         event.Rows,
+		})
+		if event.Err != nil {
+			logger = logger.WithError(event.Err)
+		}
+		logger.Warn("slow query")
+	})
+}
+
+// RedactArgs replaces query args with their types, so logs don't contain user data
+func RedactArgs(args []interface{}) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == nil {
+			redacted[i] = "NULL"
+			continue
+		}
+		redacted[i] = fmt.Sprintf("<%T>", arg)
+	}
+	return redacted
+}
@@ -1,27 +1,32 @@
 package model
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+)
 
 func ErrCommit(err error) error {
-	return fmt.Errorf("failed to commit Tx due to error: %v", err)
+	return fmt.Errorf("failed to commit Tx due to error: %w", postgresql.ParsePgError(err))
 }
 
 func ErrRollback(err error) error {
-	return fmt.Errorf("failed to rollback Tx due to error: %v", err)
+	return fmt.Errorf("failed to rollback Tx due to error: %w", err)
 }
 
 func ErrCreateTx(err error) error {
-	return fmt.Errorf("failed to create Tx due to error: %v", err)
+	return fmt.Errorf("failed to create Tx due to error: %w", err)
 }
 
 func ErrCreateQuery(err error) error {
-	return fmt.Errorf("failed to create SQL Query due to error: %v", err)
+	return postgresql.ErrCreateQuery(err)
 }
 
 func ErrScan(err error) error {
-	return fmt.Errorf("failed to scan due to error: %v", err)
+	return postgresql.ErrScan(err)
 }
 
+// ErrDoQuery classifies database error, see postgresql.ParsePgError
 func ErrDoQuery(err error) error {
-	return fmt.Errorf("failed to query due to error: %v", err)
-}
\ No newline at end of file
+	return postgresql.ErrDoQuery(err)
+}
@@ -2,6 +2,9 @@ package model
 
 import (
 	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/HollyEllmo/my-first-go-project/pkg/api/filter"
 	sq "github.com/Masterminds/squirrel"
@@ -13,75 +16,310 @@ type Filterable interface {
 
 type filters struct {
 	limit, offset uint64
-	fields        []Field
+	expression    filter.Node
+	expressions   map[string]string
+	relations     map[string]Relation
 }
 
+// Relation is a to-one table joined to filter by its columns with `relation.column` fields,
+// e.g. relation `category` makes `category.name` filterable
+type Relation struct {
+	Table string
+	Alias string
+	// ForeignKey is column of base table referencing Key of joined table
+	ForeignKey string
+	// Key is referenced column, `id` when empty
+	Key string
+}
+
+const relationSep = "."
+
+var columnName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
 func NewFilters(options filter.Filterable) *filters {
-	var fs []Field
-	var ff Field
-	for _, f := range options.Fields() {
-		ff = Field{
-			Name:     f.Name,
-			Operator: f.Operator,
-			Value:    f.Value,
-			Type:     f.Type,
-		}
-		fs = append(fs, ff)
-	}
+	return &filters{limit: options.Limit(), offset: options.Offset(), expression: options.Expression()}
+}
 
-	return &filters{limit: options.Limit(), offset: options.Offset(), fields: fs}
+// WithExpressions sets SQL expressions which are used instead of `alias.name` for given fields
+func (f *filters) WithExpressions(expressions map[string]string) *filters {
+	f.expressions = expressions
+	return f
+}
+
+// WithRelations sets tables which can be joined to filter by their columns
+func (f *filters) WithRelations(relations map[string]Relation) *filters {
+	f.relations = relations
+	return f
 }
 
 func (f *filters) Enrich(query sq.SelectBuilder, alias string) sq.SelectBuilder {
-	if len(f.fields) == 0 {
+	query = Where(f.Join(query, alias), f, alias)
+	if f.limit == 0 {
 		return query
 	}
+	return query.Limit(f.limit).Offset(f.offset)
+}
+
+// whereBuilder is SELECT, UPDATE or DELETE query builder
+type whereBuilder[B any] interface {
+	Where(pred interface{}, args ...interface{}) B
+}
+
+// Where adds filter conditions to SELECT, UPDATE or DELETE query. Relations aren't joined and limit
+// isn't applied, UPDATE and DELETE filtered by relations or expressions of joined tables use Scope.
+func Where[B whereBuilder[B]](query B, f *filters, alias string) B {
+	if where := f.Conditions(alias); where != nil {
+		return query.Where(where)
+	}
+	return query
+}
+
+// Scope returns condition `alias.key IN (from ...)` where from selecting key of base table is filtered
+// with joined relations, so UPDATE and DELETE affect the same rows SELECT enriched with filters returns.
+// Limit and offset aren't applied.
+func (f *filters) Scope(from sq.SelectBuilder, alias, key string) sq.Sqlizer {
+	return sq.Expr(alias+"."+key+" IN (?)", Where(f.Join(from, alias), f, alias))
+}
 
-	and := sq.And{}
-	for _, where := range f.fields {
-		var e sq.Sqlizer
+// Without returns copy of filters without top-level AND operands which only refer to fields with given names.
+// Operands combining excluded and other fields are kept as is.
+func (f *filters) Without(names ...string) *filters {
+	var operands []filter.Node
+	if and, ok := f.expression.(*filter.And); ok {
+		operands = and.Nodes
+	} else if f.expression != nil {
+		operands = []filter.Node{f.expression}
+	}
 
-		field := fmt.Sprintf("%s.%s", alias, where.Name)
-		if alias == "" {
-			field = where.Name
+	kept := make([]filter.Node, 0, len(operands))
+	for _, operand := range operands {
+		if !onlyRefersTo(operand, names) {
+			kept = append(kept, operand)
 		}
-		if where.Type == "date" {
-			field = fmt.Sprintf("%s::date", field)
+	}
+
+	var expression filter.Node
+	switch len(kept) {
+	case 0:
+	case 1:
+		expression = kept[0]
+	default:
+		expression = &filter.And{Nodes: kept}
+	}
+
+	return &filters{
+		limit:       f.limit,
+		offset:      f.offset,
+		expression:  expression,
+		expressions: f.expressions,
+		relations:   f.relations,
+	}
+}
+
+// Join adds LEFT JOIN of every relation used by filter conditions or by fields, e.g. selected `category.name`,
+// to query with base table alias. Each relation is joined once however many conditions use it.
+func (f *filters) Join(query sq.SelectBuilder, alias string, fields ...string) sq.SelectBuilder {
+	fields = slices.Clip(fields)
+	for _, c := range filter.Conditions(f.expression) {
+		fields = append(fields, c.Name)
+	}
+
+	var names []string
+	for _, field := range fields {
+		if _, ok := f.expressions[field]; ok {
+			continue
 		}
-		value := where.Value
-		switch where.Operator {
-		case "eq":
-			e = sq.Eq{field: value}
-		case "neq":
-			e = sq.NotEq{field: value}
-		case "like":
-			e = sq.ILike{field: fmt.Sprintf("%%%s%%", value)}
-		case "gt":
-			e = sq.Gt{field: value}
-		case "lt":
-			e = sq.Lt{field: value}
-		case "gte":
-			e = sq.GtOrEq{field: value}
-		case "lte":
-			e = sq.LtOrEq{field: value}
-		case "between":
-			e = sq.Expr(fmt.Sprintf("'[%s]'::daterange @> %s", value, field))
-		default:
-			e = sq.Expr(fmt.Sprintf("%s %s %s", field, where.Operator, where.Value))
+		if name, _, ok := strings.Cut(field, relationSep); ok {
+			names = append(names, name)
 		}
-		and = append(and, e)
 	}
 
-	query = query.Where(and)
-	if f.limit == 0 {
-		return query
+	joined := make(map[string]bool)
+	for _, name := range names {
+		if joined[name] {
+			continue
+		}
+		relation, ok := f.relations[name]
+		if !ok {
+			// condition fails query building with unsupported path
+			continue
+		}
+		joined[name] = true
+
+		key := relation.Key
+		if key == "" {
+			key = "id"
+		}
+		foreignKey := relation.ForeignKey
+		if alias != "" {
+			foreignKey = alias + "." + foreignKey
+		}
+		query = query.LeftJoin(fmt.Sprintf("%s %s ON %s.%s = %s",
+			relation.Table, relation.Alias, relation.Alias, key, foreignKey))
 	}
-	return query.Limit(f.limit).Offset(f.offset)
+	return query
+}
+
+// Column returns SQL expression of filter field, see WithExpressions and WithRelations
+func (f *filters) Column(name, alias string) (string, error) {
+	return f.column(name, alias)
+}
+
+func onlyRefersTo(node filter.Node, names []string) bool {
+	for _, c := range filter.Conditions(node) {
+		if !slices.Contains(names, c.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// Conditions returns WHERE conditions of filter expression, or nil when there are no conditions
+func (f *filters) Conditions(alias string) sq.Sqlizer {
+	if f.expression == nil {
+		return nil
+	}
+	return f.compile(f.expression, alias)
+}
+
+// compile converts filter expression tree into squirrel conditions
+func (f *filters) compile(node filter.Node, alias string) sq.Sqlizer {
+	switch n := node.(type) {
+	case *filter.And:
+		and := make(sq.And, 0, len(n.Nodes))
+		for _, child := range n.Nodes {
+			and = append(and, f.compile(child, alias))
+		}
+		return and
+	case *filter.Or:
+		or := make(sq.Or, 0, len(n.Nodes))
+		for _, child := range n.Nodes {
+			or = append(or, f.compile(child, alias))
+		}
+		return or
+	case *filter.Not:
+		return sq.Expr("NOT (?)", f.compile(n.Node, alias))
+	case *filter.Condition:
+		return f.condition(Field{
+			Name:     n.Name,
+			Operator: n.Operator,
+			Value:    n.Value,
+			Values:   n.Values,
+			Args:     n.Args,
+			Type:     n.Type,
+		}, alias)
+	}
+	return errSqlizer{fmt.Errorf("unknown filter node %T", node)}
+}
+
+func (f *filters) condition(where Field, alias string) sq.Sqlizer {
+	field, err := f.column(where.Name, alias)
+	if err != nil {
+		return errSqlizer{err}
+	}
+	if where.Type == filter.DataTypeDate {
+		field = fmt.Sprintf("%s::date", field)
+	}
+
+	build, ok := operators[where.Operator]
+	if !ok {
+		return errSqlizer{fmt.Errorf("%w: `%s`", filter.ErrBadOperator, where.Operator)}
+	}
+	return build(field, where.Args)
+}
+
+// column returns SQL expression of filter field: mapped expression, column of joined relation
+// or column of base table
+func (f *filters) column(name, alias string) (string, error) {
+	if expr, ok := f.expressions[name]; ok {
+		return expr, nil
+	}
+
+	if relationName, column, ok := strings.Cut(name, relationSep); ok {
+		relation, ok := f.relations[relationName]
+		if !ok || !columnName.MatchString(column) {
+			return "", fmt.Errorf("unsupported filter path:`%s`", name)
+		}
+		return relation.Alias + "." + column, nil
+	}
+
+	if !columnName.MatchString(name) {
+		return "", fmt.Errorf("unsupported filter field:`%s`", name)
+	}
+	if alias == "" {
+		return name, nil
+	}
+	return alias + "." + name, nil
+}
+
+// operators builds SQL condition for each operator registered in filter package.
+// Values are always passed as typed arguments, operators missing here are rejected.
+var operators = map[string]func(field string, args []interface{}) sq.Sqlizer{
+	filter.OperatorEq: func(field string, args []interface{}) sq.Sqlizer {
+		return sq.Eq{field: args[0]}
+	},
+	filter.OperatorNotEq: func(field string, args []interface{}) sq.Sqlizer {
+		return sq.NotEq{field: args[0]}
+	},
+	filter.OperatorLowerThan: func(field string, args []interface{}) sq.Sqlizer {
+		return sq.Lt{field: args[0]}
+	},
+	filter.OperatorLowerThanEq: func(field string, args []interface{}) sq.Sqlizer {
+		return sq.LtOrEq{field: args[0]}
+	},
+	filter.OperatorGreaterThan: func(field string, args []interface{}) sq.Sqlizer {
+		return sq.Gt{field: args[0]}
+	},
+	filter.OperatorGreaterThanEq: func(field string, args []interface{}) sq.Sqlizer {
+		return sq.GtOrEq{field: args[0]}
+	},
+	filter.OperatorIn: func(field string, args []interface{}) sq.Sqlizer {
+		return sq.Eq{field: args}
+	},
+	filter.OperatorNotIn: func(field string, args []interface{}) sq.Sqlizer {
+		return sq.NotEq{field: args}
+	},
+	filter.OperatorBetween: func(field string, args []interface{}) sq.Sqlizer {
+		return sq.Expr(fmt.Sprintf("%s BETWEEN ? AND ?", field), args[0], args[1])
+	},
+	filter.OperatorIsNull: func(field string, _ []interface{}) sq.Sqlizer {
+		return sq.Eq{field: nil}
+	},
+	filter.OperatorIsNotNull: func(field string, _ []interface{}) sq.Sqlizer {
+		return sq.NotEq{field: nil}
+	},
+	filter.OperatorLike: func(field string, args []interface{}) sq.Sqlizer {
+		return sq.ILike{field: fmt.Sprintf("%%%s%%", escapeLike(fmt.Sprint(args[0])))}
+	},
+	filter.OperatorCaseSensitiveLike: func(field string, args []interface{}) sq.Sqlizer {
+		return sq.Like{field: fmt.Sprintf("%%%s%%", escapeLike(fmt.Sprint(args[0])))}
+	},
+	filter.OperatorStartsWith: func(field string, args []interface{}) sq.Sqlizer {
+		return sq.ILike{field: fmt.Sprintf("%s%%", escapeLike(fmt.Sprint(args[0])))}
+	},
+}
+
+// escapeLike escapes LIKE wildcards so value is matched literally
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// errSqlizer fails query building with err
+type errSqlizer struct {
+	err error
+}
+
+func (e errSqlizer) ToSql() (string, []interface{}, error) {
+	return "", nil, e.err
 }
 
 type Field struct {
 	Name     string
 	Value    string
+	Values   []string
+	Args     []interface{}
 	Operator string
 	Type     string
 }
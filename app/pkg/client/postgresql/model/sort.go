@@ -2,34 +2,112 @@ package model
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/HollyEllmo/my-first-go-project/pkg/api/sort"
 	sq "github.com/Masterminds/squirrel"
 )
 
+const (
+	tieBreakerField = "id"
+	jsonPathSep     = "."
+)
+
 type Sortable interface {
 	Sort(query sq.SelectBuilder, alias string) sq.SelectBuilder
 }
 
 type sorts struct {
-	field string
-	order string
+	keys        []sort.Key
+	expressions map[string]string
+	jsonPaths   map[string]string
 }
 
 func NewSortOptions(options sort.Sortable) *sorts {
 	return &sorts{
-		field: options.Field(),
-		order: options.Order(),
+		keys: options.Keys(),
 	}
 }
 
+// WithExpressions sets SQL expressions of sortable fields. Only these fields and
+// JSON paths can be sorted by, other fields fail query building.
+func (s *sorts) WithExpressions(expressions map[string]string) *sorts {
+	s.expressions = expressions
+	return s
+}
+
+// WithJSONPaths allows sorting by paths inside JSONB columns, e.g. field `specification`
+// mapped to `p.specification` makes `specification.size.width` sortable.
+// Values are compared as JSONB, so numbers are ordered numerically.
+func (s *sorts) WithJSONPaths(columns map[string]string) *sorts {
+	s.jsonPaths = columns
+	return s
+}
+
+// Sort adds ORDER BY of all keys followed by `id` when it is not sorted by yet,
+// so rows with equal keys are always returned in the same order
 func (s *sorts) Sort(query sq.SelectBuilder, alias string) sq.SelectBuilder {
-	if s.field == "" {
-		return query
+	tieBreaker := true
+	for _, key := range s.keys {
+		query = query.OrderByClause(s.orderBy(key))
+		if key.Field == tieBreakerField {
+			tieBreaker = false
+		}
+	}
+
+	if tieBreaker {
+		expr, ok := s.expressions[tieBreakerField]
+		if !ok {
+			expr = tieBreakerField
+			if alias != "" {
+				expr = fmt.Sprintf("%s.%s", alias, tieBreakerField)
+			}
+		}
+		query = query.OrderBy(expr)
+	}
+
+	return query
+}
+
+func (s *sorts) orderBy(key sort.Key) sq.Sqlizer {
+	suffix, err := orderSuffix(key)
+	if err != nil {
+		return errSqlizer{err}
+	}
+
+	if expr, ok := s.expressions[key.Field]; ok {
+		return sq.Expr(expr + suffix)
 	}
-	field := s.field
-	if alias != "" {
-		field = fmt.Sprintf("%s.%s", alias, field)
+
+	if field, path, ok := strings.Cut(key.Field, jsonPathSep); ok {
+		if column, ok := s.jsonPaths[field]; ok {
+			return sq.Expr(column+" #> ?::text[]"+suffix, strings.Split(path, jsonPathSep))
+		}
 	}
-	return query.OrderBy(fmt.Sprintf("%s %s", field, s.order))
+
+	return errSqlizer{fmt.Errorf("unknown sort field:`%s`", key.Field)}
+}
+
+func orderSuffix(key sort.Key) (string, error) {
+	var suffix string
+	switch sort.Order(strings.ToUpper(string(key.Order))) {
+	case sort.OrderASC, "":
+		suffix = " ASC"
+	case sort.OrderDESC:
+		suffix = " DESC"
+	default:
+		return "", fmt.Errorf("wrong sort order:`%s`", key.Order)
+	}
+
+	switch sort.Nulls(strings.ToUpper(string(key.Nulls))) {
+	case "":
+	case sort.NullsFirst:
+		suffix += " NULLS FIRST"
+	case sort.NullsLast:
+		suffix += " NULLS LAST"
+	default:
+		return "", fmt.Errorf("wrong nulls placement:`%s`", key.Nulls)
+	}
+
+	return suffix, nil
 }
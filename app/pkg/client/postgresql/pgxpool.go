@@ -3,9 +3,14 @@ package postgresql
 import (
 	"context"
 	"fmt"
-	"log"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
 	"time"
 
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -68,12 +73,51 @@ func (c *pgClient) Exec(ctx context.Context, sql string, arguments ...interface{
 	return c.pool.Exec(ctx, sql, arguments...)
 }
 
+// PoolConfig sizes connection pool, zero values keep pgxpool defaults
+type PoolConfig struct {
+	MinConns          int32
+	MaxConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+}
+
+// TLSConfig sets libpq `sslmode` and certificate files, empty Mode keeps libpq default `prefer`
+type TLSConfig struct {
+	Mode     string
+	RootCert string
+	Cert     string
+	Key      string
+}
+
+// SessionConfig sets run-time parameters of every connection
+type SessionConfig struct {
+	ApplicationName  string
+	SearchPath       string
+	StatementTimeout time.Duration
+}
+
+// ConnectConfig controls connection attempts on start.
+// Delay between attempts doubles from InitialDelay up to MaxDelay and is jittered.
+type ConnectConfig struct {
+	Attempts     int
+	Timeout      time.Duration
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
 type pgConfig struct {
 	Username string
 	Password string
 	Host     string
 	Port     string
 	Database string
+	hooks    []QueryHook
+	pool     PoolConfig
+	tls      TLSConfig
+	session  SessionConfig
+	connect  ConnectConfig
+	lazy     bool
 }
 
 // NewPgConfig creates new pg config instance
@@ -84,60 +128,212 @@ func NewPgConfig(username string, password string, host string, port string, dat
 		Host:     host,
 		Port:     port,
 		Database: database,
+		connect: ConnectConfig{
+			Attempts:     5,
+			Timeout:      5 * time.Second,
+			InitialDelay: 500 * time.Millisecond,
+			MaxDelay:     5 * time.Second,
+		},
 	}
 }
 
-// NewClient
-func NewClient(ctx context.Context, maxAttempts int, maxDelay time.Duration, cfg *pgConfig) (Client, error) {
-	dsn := fmt.Sprintf(
-		"postgresql://%s:%s@%s:%s/%s",
-		cfg.Username, cfg.Password,
-		cfg.Host, cfg.Port, cfg.Database,
-	)
+// WithQueryHooks adds hooks notified after every statement of client
+func (c *pgConfig) WithQueryHooks(hooks ...QueryHook) *pgConfig {
+	c.hooks = append(c.hooks, hooks...)
+	return c
+}
 
-	var pool *pgxpool.Pool
-	err := DoWithAttempts(func() error {
-		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		defer cancel()
+// WithPool sets pool size, connection lifetime and health-check period
+func (c *pgConfig) WithPool(pool PoolConfig) *pgConfig {
+	c.pool = pool
+	return c
+}
 
-		pgxCfg, err := pgxpool.ParseConfig(dsn)
-		if err != nil {
-			log.Fatalf("Unable to parse config: %v\n", err)
-		}
+// WithTLS sets sslmode and certificate files
+func (c *pgConfig) WithTLS(tls TLSConfig) *pgConfig {
+	c.tls = tls
+	return c
+}
+
+// WithSession sets application_name, search_path and statement_timeout of connections
+func (c *pgConfig) WithSession(session SessionConfig) *pgConfig {
+	c.session = session
+	return c
+}
 
-		// pgxCfg.ConnConfig.Logger = logrusadapter.NewLogger(logger)
+// WithConnect replaces default 5 attempts with delay from 500ms to 5s
+func (c *pgConfig) WithConnect(connect ConnectConfig) *pgConfig {
+	c.connect = connect
+	return c
+}
 
-		pool, err = pgxpool.ConnectConfig(ctx, pgxCfg)
-		if err != nil {
-			log.Println("Failed to connect to postgres... Going to do the next attempt")
+// Replica copies config for replica on host and port.
+// Replica pool connects lazily, so unavailable replica doesn't fail start and is reported by health checks.
+func (c *pgConfig) Replica(host, port string) *pgConfig {
+	replica := *c
+	replica.Host = host
+	replica.Port = port
+	replica.hooks = slices.Clip(c.hooks)
+	replica.lazy = true
+	return &replica
+}
 
-			return err
+// dsn builds connection string, credentials and TLS files are escaped
+func (c *pgConfig) dsn() string {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"sslmode":     c.tls.Mode,
+		"sslrootcert": c.tls.RootCert,
+		"sslcert":     c.tls.Cert,
+		"sslkey":      c.tls.Key,
+	} {
+		if value != "" {
+			query.Set(key, value)
 		}
+	}
 
-		return nil
-	}, maxAttempts, maxDelay)
+	u := url.URL{
+		Scheme:   "postgresql",
+		User:     url.UserPassword(c.Username, c.Password),
+		Host:     net.JoinHostPort(c.Host, c.Port),
+		Path:     "/" + c.Database,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
 
+// poolConfig parses DSN and applies pool, session and hooks settings
+func (c *pgConfig) poolConfig() (*pgxpool.Config, error) {
+	pgxCfg, err := pgxpool.ParseConfig(c.dsn())
 	if err != nil {
-		log.Fatal("All attempts are exceeded. Unable to connect to postgres")
+		return nil, fmt.Errorf("parse postgres config: %w", err)
 	}
 
+	if c.pool.MaxConns > 0 {
+		pgxCfg.MaxConns = c.pool.MaxConns
+	}
+	if c.pool.MinConns > 0 {
+		pgxCfg.MinConns = c.pool.MinConns
+	}
+	if pgxCfg.MinConns > pgxCfg.MaxConns {
+		return nil, fmt.Errorf("postgres pool min conns %d exceed max conns %d", pgxCfg.MinConns, pgxCfg.MaxConns)
+	}
+	if c.pool.MaxConnLifetime > 0 {
+		pgxCfg.MaxConnLifetime = c.pool.MaxConnLifetime
+	}
+	if c.pool.MaxConnIdleTime > 0 {
+		pgxCfg.MaxConnIdleTime = c.pool.MaxConnIdleTime
+	}
+	if c.pool.HealthCheckPeriod > 0 {
+		pgxCfg.HealthCheckPeriod = c.pool.HealthCheckPeriod
+	}
+
+	params := pgxCfg.ConnConfig.RuntimeParams
+	if c.session.ApplicationName != "" {
+		params["application_name"] = c.session.ApplicationName
+	}
+	if c.session.SearchPath != "" {
+		params["search_path"] = c.session.SearchPath
+	}
+	if c.session.StatementTimeout > 0 {
+		params["statement_timeout"] = strconv.FormatInt(c.session.StatementTimeout.Milliseconds(), 10)
+	}
+
+	pgxCfg.LazyConnect = c.lazy
+
+	if len(c.hooks) > 0 {
+		// События Query и Exec pgx пишет на уровне Info
+		pgxCfg.ConnConfig.Logger = &hooksLogger{hooks: c.hooks}
+		pgxCfg.ConnConfig.LogLevel = pgx.LogLevelInfo
+	}
+
+	return pgxCfg, nil
+}
+
+// NewClient connects to PostgreSQL retrying failed attempts with jittered exponential backoff.
+// Invalid config isn't retried.
+func NewClient(ctx context.Context, cfg *pgConfig) (Client, error) {
+	pgxCfg, err := cfg.poolConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := connectWithBackoff(ctx, cfg.connect, func(ctx context.Context) (*pgxpool.Pool, error) {
+		return pgxpool.ConnectConfig(ctx, pgxCfg)
+	})
+	if err != nil {
+		return nil, err
+	}
 	return &pgClient{pool: pool}, nil
 }
 
-func DoWithAttempts(fn func() error, maxAttempts int, delay time.Duration) error {
-	var err error
+// Connect opens single connection, e.g. to hold session advisory lock.
+// Pool settings of config are ignored.
+func Connect(ctx context.Context, cfg *pgConfig) (*pgx.Conn, error) {
+	pgxCfg, err := cfg.poolConfig()
+	if err != nil {
+		return nil, err
+	}
 
-	for maxAttempts > 0 {
-		if err = fn(); err != nil {
-			time.Sleep(delay)
-			maxAttempts--
+	return connectWithBackoff(ctx, cfg.connect, func(ctx context.Context) (*pgx.Conn, error) {
+		return pgx.ConnectConfig(ctx, pgxCfg.ConnConfig)
+	})
+}
 
-			continue
+// connectWithBackoff calls connect until it succeeds or attempts are exceeded, each attempt is limited by timeout
+func connectWithBackoff[T any](ctx context.Context, cfg ConnectConfig, connect func(ctx context.Context) (T, error)) (T, error) {
+	var (
+		conn T
+		err  error
+	)
+	attempts := max(cfg.Attempts, 1)
+	for attempt := 1; ; attempt++ {
+		if conn, err = connectAttempt(ctx, cfg.Timeout, connect); err == nil {
+			return conn, nil
+		}
+		if attempt == attempts {
+			break
 		}
 
-		return nil
+		delay := backoff(attempt, cfg.InitialDelay, cfg.MaxDelay)
+		logging.WithError(ctx, err).Warnf("failed to connect to postgres, attempt %d of %d, next in %s", attempt, attempts, delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return conn, fmt.Errorf("connect to postgres: %w", ctx.Err())
+		case <-timer.C:
+		}
 	}
 
-	return err
+	return conn, fmt.Errorf("connect to postgres, %d attempts are exceeded: %w", attempts, err)
+}
+
+func connectAttempt[T any](ctx context.Context, timeout time.Duration, connect func(ctx context.Context) (T, error)) (T, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return connect(ctx)
 }
 
+// backoff returns delay before the next attempt: initial doubled per attempt, capped by maxDelay,
+// with random jitter in [delay/2, delay] so instances don't reconnect simultaneously
+func backoff(attempt int, initial, maxDelay time.Duration) time.Duration {
+	if initial <= 0 {
+		return 0
+	}
+
+	delay := initial
+	for i := 1; i < attempt && (maxDelay <= 0 || delay < maxDelay); i++ {
+		delay *= 2
+	}
+	if maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
@@ -0,0 +1,405 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	routePrimary = "primary"
+	routeReplica = "replica"
+
+	replicaPingTimeout       = 2 * time.Second
+	defaultHealthCheckPeriod = 5 * time.Second
+)
+
+type ctxReadReplica struct{}
+
+// ReadReplica marks statements of context as reads which may be served by replica
+// lagging behind primary, see ReplicatedClient
+func ReadReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxReadReplica{}, true)
+}
+
+func readsReplica(ctx context.Context) bool {
+	ok, _ := ctx.Value(ctxReadReplica{}).(bool)
+	return ok
+}
+
+// Replica is read-only client of replica, Name is used in logs and stats
+type Replica struct {
+	Name   string
+	Client Client
+}
+
+// RoutingOptions configures ReplicatedClient
+type RoutingOptions struct {
+	// ReadYourWrites pins reads of session to primary for this period after it changed data
+	ReadYourWrites time.Duration
+	// HealthCheckPeriod is interval of replica pings, unhealthy replicas get no reads
+	HealthCheckPeriod time.Duration
+	// SessionKey identifies session of context for ReadYourWrites, e.g. authenticated user.
+	// Writes of contexts without key don't pin reads, reads without key are counted as Unkeyed.
+	SessionKey func(ctx context.Context) string
+}
+
+// RoutingStats counts routing decisions of ReplicatedClient
+type RoutingStats struct {
+	Writes       uint64
+	PrimaryReads uint64
+	ReplicaReads uint64
+	// Pinned is number of reads sent to primary by read-your-writes window
+	Pinned uint64
+	// Failovers is number of reads sent to primary because no replica was healthy
+	Failovers uint64
+	// Unkeyed is number of reads without session key while ReadYourWrites is on,
+	// they can't be pinned and may miss writes of their user
+	Unkeyed  uint64
+	Replicas []ReplicaStatus
+}
+
+type ReplicaStatus struct {
+	Name      string
+	Healthy   bool
+	Reads     uint64
+	Errors    uint64
+	CheckedAt time.Time
+	LastError string
+}
+
+// ReplicatedClient sends statements of contexts marked by ReadReplica to healthy replicas
+// in round-robin, everything else goes to primary.
+// Transactions always run on primary except read-only ones of ReadReplica context.
+type ReplicatedClient struct {
+	primary  Client
+	replicas []*replica
+	options  RoutingOptions
+	next     atomic.Uint64
+
+	mu     sync.Mutex
+	writes map[string]time.Time
+
+	writesCount  atomic.Uint64
+	primaryReads atomic.Uint64
+	pinnedReads  atomic.Uint64
+	failovers    atomic.Uint64
+	unkeyedReads atomic.Uint64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type replica struct {
+	Replica
+	healthy atomic.Bool
+	reads   atomic.Uint64
+	errors  atomic.Uint64
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	lastError string
+}
+
+// NewReplicatedClient checks replicas once and keeps checking them in background until Close
+func NewReplicatedClient(ctx context.Context, primary Client, replicas []Replica, options RoutingOptions) *ReplicatedClient {
+	c := &ReplicatedClient{
+		primary:  primary,
+		replicas: make([]*replica, len(replicas)),
+		options:  options,
+		writes:   make(map[string]time.Time),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for i, r := range replicas {
+		c.replicas[i] = &replica{Replica: r}
+	}
+
+	c.checkReplicas(ctx)
+	go c.run(context.WithoutCancel(ctx))
+
+	return c
+}
+
+func (c *ReplicatedClient) run(ctx context.Context) {
+	defer close(c.done)
+	if len(c.replicas) == 0 {
+		return
+	}
+
+	period := c.options.HealthCheckPeriod
+	if period <= 0 {
+		period = defaultHealthCheckPeriod
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.checkReplicas(ctx)
+			c.forgetWrites()
+		}
+	}
+}
+
+func (c *ReplicatedClient) checkReplicas(ctx context.Context) {
+	for _, r := range c.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, replicaPingTimeout)
+		err := r.Client.Ping(pingCtx)
+		cancel()
+		c.setHealth(ctx, r, err)
+	}
+}
+
+func (c *ReplicatedClient) setHealth(ctx context.Context, r *replica, err error) {
+	r.mu.Lock()
+	r.checkedAt = time.Now()
+	r.lastError = ""
+	if err != nil {
+		r.lastError = err.Error()
+	}
+	r.mu.Unlock()
+
+	if healthy := err == nil; r.healthy.Swap(healthy) != healthy {
+		logger := logging.WithFields(ctx, map[string]interface{}{"replica": r.Name})
+		if healthy {
+			logger.Info("database replica is healthy")
+		} else {
+			logger.WithError(err).Warn("database replica is unhealthy, its reads go to other replicas or primary")
+		}
+	}
+}
+
+// forgetWrites drops sessions which are out of read-your-writes window
+func (c *ReplicatedClient) forgetWrites() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, at := range c.writes {
+		if time.Since(at) > c.options.ReadYourWrites {
+			delete(c.writes, key)
+		}
+	}
+}
+
+func (c *ReplicatedClient) sessionKey(ctx context.Context) string {
+	if c.options.SessionKey == nil {
+		return ""
+	}
+	return c.options.SessionKey(ctx)
+}
+
+// write routes statement which may change data to primary and starts read-your-writes window of session
+func (c *ReplicatedClient) write(ctx context.Context) Client {
+	c.writesCount.Add(1)
+	if len(c.replicas) == 0 || c.options.ReadYourWrites <= 0 {
+		return c.primary
+	}
+	if key := c.sessionKey(ctx); key != "" {
+		c.mu.Lock()
+		c.writes[key] = time.Now()
+		c.mu.Unlock()
+	}
+	return c.primary
+}
+
+func (c *ReplicatedClient) pinned(ctx context.Context) bool {
+	if c.options.ReadYourWrites <= 0 {
+		return false
+	}
+	key := c.sessionKey(ctx)
+	if key == "" {
+		c.unkeyedReads.Add(1)
+		return false
+	}
+
+	c.mu.Lock()
+	at, ok := c.writes[key]
+	c.mu.Unlock()
+	return ok && time.Since(at) <= c.options.ReadYourWrites
+}
+
+// read chooses client for read statement, replica is nil when it goes to primary
+func (c *ReplicatedClient) read(ctx context.Context) (Client, *replica) {
+	reason := ""
+	switch {
+	case !readsReplica(ctx):
+		reason = "not marked for replica"
+	case len(c.replicas) == 0:
+		reason = "no replicas"
+	case c.pinned(ctx):
+		c.pinnedReads.Add(1)
+		reason = "read your writes"
+	default:
+		start := c.next.Add(1)
+		for i := range c.replicas {
+			r := c.replicas[(start+uint64(i))%uint64(len(c.replicas))]
+			if r.healthy.Load() {
+				r.reads.Add(1)
+				c.trace(ctx, routeReplica, r.Name)
+				return r.Client, r
+			}
+		}
+		c.failovers.Add(1)
+		reason = "no healthy replicas"
+	}
+
+	c.primaryReads.Add(1)
+	c.trace(ctx, routePrimary, reason)
+	return c.primary, nil
+}
+
+func (c *ReplicatedClient) trace(ctx context.Context, route, reason string) {
+	logging.WithFields(ctx, map[string]interface{}{
+		"db_route": route,
+		"reason":   reason,
+	}).Trace("database read routed")
+}
+
+// failed marks replica unhealthy when it lost connection, so next reads go elsewhere until health check
+func (c *ReplicatedClient) failed(ctx context.Context, r *replica, err error) bool {
+	if r == nil || ctx.Err() != nil || !isConnectionError(err) {
+		return false
+	}
+	r.errors.Add(1)
+	c.setHealth(ctx, r, err)
+	return true
+}
+
+// isConnectionError reports whether err is caused by connection rather than by statement or context
+func isConnectionError(err error) bool {
+	var pgErr *pgconn.PgError
+	if err == nil || errors.As(err, &pgErr) || errors.Is(err, pgx.ErrNoRows) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return pgconn.SafeToRetry(err) || pgconn.Timeout(err) || errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isRead reports whether statement is plain SELECT, anything else may change data
+func isRead(sql string) bool {
+	sql = strings.TrimLeft(sql, " \t\r\n(")
+	return len(sql) >= 6 && strings.EqualFold(sql[:6], "select")
+}
+
+// Stats returns counters of routing decisions and replicas health
+func (c *ReplicatedClient) Stats() RoutingStats {
+	stats := RoutingStats{
+		Writes:       c.writesCount.Load(),
+		PrimaryReads: c.primaryReads.Load(),
+		Pinned:       c.pinnedReads.Load(),
+		Failovers:    c.failovers.Load(),
+		Unkeyed:      c.unkeyedReads.Load(),
+		Replicas:     make([]ReplicaStatus, len(c.replicas)),
+	}
+	for i, r := range c.replicas {
+		r.mu.Lock()
+		stats.Replicas[i] = ReplicaStatus{
+			Name:      r.Name,
+			Healthy:   r.healthy.Load(),
+			Reads:     r.reads.Load(),
+			Errors:    r.errors.Load(),
+			CheckedAt: r.checkedAt,
+			LastError: r.lastError,
+		}
+		r.mu.Unlock()
+		stats.ReplicaReads += stats.Replicas[i].Reads
+	}
+	return stats
+}
+
+// Close stops health checks and closes primary and replicas
+func (c *ReplicatedClient) Close() {
+	close(c.stop)
+	<-c.done
+	for _, r := range c.replicas {
+		r.Client.Close()
+	}
+	c.primary.Close()
+}
+
+// Ping проверяет соединение с primary
+func (c *ReplicatedClient) Ping(ctx context.Context) error {
+	return c.primary.Ping(ctx)
+}
+
+func (c *ReplicatedClient) Begin(ctx context.Context) (pgx.Tx, error) {
+	return c.write(ctx).Begin(ctx)
+}
+
+func (c *ReplicatedClient) BeginFunc(ctx context.Context, f func(pgx.Tx) error) error {
+	return c.write(ctx).BeginFunc(ctx, f)
+}
+
+// BeginTxFunc runs read-only transaction of ReadReplica context on replica
+func (c *ReplicatedClient) BeginTxFunc(ctx context.Context, txOptions pgx.TxOptions, f func(pgx.Tx) error) error {
+	if txOptions.AccessMode != pgx.ReadOnly {
+		return c.write(ctx).BeginTxFunc(ctx, txOptions, f)
+	}
+	client, _ := c.read(ctx)
+	return client.BeginTxFunc(ctx, txOptions, f)
+}
+
+// Query reading from replica which lost connection is repeated on primary.
+// Like retryClient.Query it sees only errors returned by Query itself, connection lost
+// while rows are read is reported by rows.Err() and isn't repeated.
+func (c *ReplicatedClient) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if !isRead(sql) {
+		return c.write(ctx).Query(ctx, sql, args...)
+	}
+
+	client, r := c.read(ctx)
+	rows, err := client.Query(ctx, sql, args...)
+	if err != nil && c.failed(ctx, r, err) {
+		c.failovers.Add(1)
+		c.trace(ctx, routePrimary, "replica failed")
+		return c.primary.Query(ctx, sql, args...)
+	}
+	return rows, err
+}
+
+func (c *ReplicatedClient) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if !isRead(sql) {
+		return c.write(ctx).QueryRow(ctx, sql, args...)
+	}
+
+	client, r := c.read(ctx)
+	return &replicaRow{Row: client.QueryRow(ctx, sql, args...), ctx: ctx, client: c, replica: r, sql: sql, args: args}
+}
+
+func (c *ReplicatedClient) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	return c.write(ctx).Exec(ctx, sql, arguments...)
+}
+
+// replicaRow marks replica unhealthy when scan fails because of connection and repeats query on primary,
+// as Query does
+type replicaRow struct {
+	pgx.Row
+	ctx     context.Context
+	client  *ReplicatedClient
+	replica *replica
+	sql     string
+	args    []interface{}
+}
+
+func (r *replicaRow) Scan(dest ...interface{}) error {
+	err := r.Row.Scan(dest...)
+	if err != nil && r.client.failed(r.ctx, r.replica, err) {
+		r.client.failovers.Add(1)
+		r.client.trace(r.ctx, routePrimary, "replica failed")
+		return r.client.primary.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	}
+	return err
+}
@@ -0,0 +1,278 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// Querier runs statements, Client and transaction of context are Queriers
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+}
+
+// Scope narrows select of Repository, e.g. adds filter conditions, sorting and pagination
+type Scope func(query sq.SelectBuilder) sq.SelectBuilder
+
+// identifier matches plain column name which is qualified by table alias in selects
+var identifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// Table describes table read into T
+type Table[T any] struct {
+	// Name is schema qualified table, Alias qualifies its columns in selects
+	Name       string
+	Alias      string
+	PrimaryKey string
+	// Columns are selected in order of Scan, plain column names are qualified by Alias,
+	// other expressions are selected as is
+	Columns []string
+	// Join adds tables which Columns refer to, e.g. translations of request locales. It is optional.
+	Join func(ctx context.Context, query sq.SelectBuilder) sq.SelectBuilder
+	// Scan reads selected Columns of row, pgx.Rows is scanned the same way
+	Scan func(row pgx.Row) (T, error)
+}
+
+// Repository provides CRUD of table rows read into T. Writes take columns with values,
+// so write models don't have to match T.
+type Repository[T any] struct {
+	table        Table[T]
+	client       Querier
+	queryBuilder sq.StatementBuilderType
+}
+
+func NewRepository[T any](client Querier, table Table[T]) *Repository[T] {
+	return &Repository[T]{
+		table:        table,
+		client:       client,
+		queryBuilder: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+// Select builds select of Columns with joins, it is base of List and One
+func (r *Repository[T]) Select(ctx context.Context) sq.SelectBuilder {
+	columns := make([]string, len(r.table.Columns))
+	for i, column := range r.table.Columns {
+		columns[i] = r.qualify(column)
+	}
+
+	query := r.queryBuilder.Select(columns...).From(r.from())
+	if r.table.Join != nil {
+		query = r.table.Join(ctx, query)
+	}
+	return query
+}
+
+// List returns rows of scope, all rows when scope is nil
+func (r *Repository[T]) List(ctx context.Context, scope Scope) ([]T, error) {
+	query := r.Select(ctx)
+	if scope != nil {
+		query = scope(query)
+	}
+	return Fetch(ctx, r.client, r.table.Name, query, r.table.Scan)
+}
+
+// One returns row by primary key, error wraps ErrNotFound when there is no such row
+func (r *Repository[T]) One(ctx context.Context, id interface{}) (T, error) {
+	query := r.Select(ctx).Where(sq.Eq{r.qualify(r.table.PrimaryKey): id})
+	return FetchOne(ctx, r.client, r.table.Name, query, r.table.Scan)
+}
+
+// Count counts rows of scope, scope may add joins and conditions. Joins of Table aren't added.
+func (r *Repository[T]) Count(ctx context.Context, scope Scope) (uint64, error) {
+	query := r.queryBuilder.Select("count(*)").From(r.from())
+	if scope != nil {
+		query = scope(query)
+	}
+	return FetchOne(ctx, r.client, r.table.Name, query, func(row pgx.Row) (count uint64, err error) {
+		err = row.Scan(&count)
+		return count, err
+	})
+}
+
+// Insert inserts row of column values
+func (r *Repository[T]) Insert(ctx context.Context, values map[string]interface{}) error {
+	columns, args := columnValues(values)
+	query := r.queryBuilder.Insert(r.table.Name).Columns(columns...).Values(args...)
+
+	return r.exec(ctx, query, func(tag pgconn.CommandTag) error {
+		if tag.RowsAffected() == 0 || !tag.Insert() {
+			return ErrDoQuery(fmt.Errorf("%s row was not created. 0 rows were affected", r.table.Name))
+		}
+		return nil
+	})
+}
+
+// Upsert inserts row or updates other columns of existing row with the same conflict columns,
+// primary key is conflict target by default
+func (r *Repository[T]) Upsert(ctx context.Context, values map[string]interface{}, conflict ...string) error {
+	if len(conflict) == 0 {
+		conflict = []string{r.table.PrimaryKey}
+	}
+
+	columns, args := columnValues(values)
+	var set []string
+	for _, column := range columns {
+		if !slices.Contains(conflict, column) {
+			set = append(set, column+" = EXCLUDED."+column)
+		}
+	}
+	action := "DO NOTHING"
+	if len(set) > 0 {
+		action = "DO UPDATE SET " + strings.Join(set, ", ")
+	}
+
+	query := r.queryBuilder.Insert(r.table.Name).Columns(columns...).Values(args...).
+		Suffix("ON CONFLICT (" + strings.Join(conflict, ", ") + ") " + action)
+
+	return r.exec(ctx, query, nil)
+}
+
+// Update sets column values of row by primary key, error wraps ErrNotFound when there is no such row
+func (r *Repository[T]) Update(ctx context.Context, id interface{}, values map[string]interface{}) error {
+	query := r.queryBuilder.Update(r.table.Name).SetMap(values).Where(sq.Eq{r.table.PrimaryKey: id})
+
+	return r.exec(ctx, query, func(tag pgconn.CommandTag) error {
+		if tag.RowsAffected() == 0 || !tag.Update() {
+			return ErrDoQuery(fmt.Errorf("%s row was not updated. 0 rows were affected: %w", r.table.Name, ErrNotFound))
+		}
+		return nil
+	})
+}
+
+// Delete deletes row by primary key, error wraps ErrNotFound when there is no such row
+func (r *Repository[T]) Delete(ctx context.Context, id interface{}) error {
+	query := r.queryBuilder.Delete(r.table.Name).Where(sq.Eq{r.table.PrimaryKey: id})
+
+	return r.exec(ctx, query, func(tag pgconn.CommandTag) error {
+		if tag.RowsAffected() == 0 || !tag.Delete() {
+			return ErrDoQuery(fmt.Errorf("%s row was not deleted. 0 rows were affected: %w", r.table.Name, ErrNotFound))
+		}
+		return nil
+	})
+}
+
+func (r *Repository[T]) from() string {
+	if r.table.Alias == "" {
+		return r.table.Name
+	}
+	return r.table.Name + " " + r.table.Alias
+}
+
+func (r *Repository[T]) qualify(column string) string {
+	if r.table.Alias == "" || !identifier.MatchString(column) {
+		return column
+	}
+	return r.table.Alias + "." + column
+}
+
+// exec runs statement and checks its command tag, check may be nil
+func (r *Repository[T]) exec(ctx context.Context, query sq.Sqlizer, check func(pgconn.CommandTag) error) error {
+	sql, args, err := query.ToSql()
+	logger := queryLogger(ctx, r.table.Name, sql, args)
+	if err != nil {
+		err = ErrCreateQuery(err)
+		logger.Error(err)
+		return err
+	}
+
+	tag, err := r.client.Exec(ctx, sql, args...)
+	if err != nil {
+		err = ErrDoQuery(err)
+	} else if check != nil {
+		err = check(tag)
+	}
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// Fetch runs select and scans all its rows, table is logged with the statement
+func Fetch[V any](ctx context.Context, client Querier, table string, query sq.Sqlizer, scan func(row pgx.Row) (V, error)) ([]V, error) {
+	sql, args, err := query.ToSql()
+	logger := queryLogger(ctx, table, sql, args)
+	if err != nil {
+		err = ErrCreateQuery(err)
+		logger.Error(err)
+		return nil, err
+	}
+
+	rows, err := client.Query(ctx, sql, args...)
+	if err != nil {
+		err = ErrDoQuery(err)
+		logger.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]V, 0)
+	for rows.Next() {
+		v, err := scan(rows)
+		if err != nil {
+			err = ErrScan(err)
+			logger.Error(err)
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	if err = rows.Err(); err != nil {
+		err = ErrDoQuery(err)
+		logger.Error(err)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// FetchOne runs select of single row, error wraps ErrNotFound when there is no row
+func FetchOne[V any](ctx context.Context, client Querier, table string, query sq.Sqlizer, scan func(row pgx.Row) (V, error)) (V, error) {
+	var v V
+
+	sql, args, err := query.ToSql()
+	logger := queryLogger(ctx, table, sql, args)
+	if err != nil {
+		err = ErrCreateQuery(err)
+		logger.Error(err)
+		return v, err
+	}
+
+	if v, err = scan(client.QueryRow(ctx, sql, args...)); err != nil {
+		err = ErrDoQuery(err)
+		logger.Error(err)
+		return v, err
+	}
+
+	return v, nil
+}
+
+// queryLogger logs statement of failed query, args are redacted as in query hooks
+func queryLogger(ctx context.Context, table, sql string, args []interface{}) *logrus.Entry {
+	return logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": table,
+		"args":  RedactArgs(args),
+	})
+}
+
+// columnValues splits values to sorted columns and their values, so statements of the same columns are equal
+func columnValues(values map[string]interface{}) ([]string, []interface{}) {
+	columns := slices.Sorted(maps.Keys(values))
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		args[i] = values[column]
+	}
+	return columns, args
+}
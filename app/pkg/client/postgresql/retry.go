@@ -0,0 +1,186 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	RetrySerializationFailure = "serialization_failure"
+	RetryDeadlock             = "deadlock_detected"
+	RetryConnection           = "connection"
+	RetryNotSent              = "not_sent"
+)
+
+// RetryPolicy re-runs operations failed with transient errors.
+// Attempts includes the first one, delay doubles from InitialDelay up to MaxDelay and is jittered.
+type RetryPolicy struct {
+	Attempts     int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// RetryReason returns metrics label of transient error which is worth to retry:
+// serialization failure (40001), deadlock (40P01) or lost connection (class 08, 57P01-57P03)
+func RetryReason(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == "40001":
+			return RetrySerializationFailure, true
+		case pgErr.Code == "40P01":
+			return RetryDeadlock, true
+		case strings.HasPrefix(pgErr.Code, "08"), pgErr.Code == "57P01", pgErr.Code == "57P02", pgErr.Code == "57P03":
+			return RetryConnection, true
+		}
+		return "", false
+	}
+	if isConnectionError(err) {
+		return RetryConnection, true
+	}
+	return "", false
+}
+
+// retry runs fn until it succeeds, fails with error rejected by retryable, attempts are exceeded
+// or the next delay doesn't fit in context deadline
+func (p RetryPolicy) retry(ctx context.Context, stats *RetryStats, retryable func(error) (string, bool), fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		reason, ok := retryable(err)
+		if !ok {
+			return err
+		}
+
+		delay := backoff(attempt, p.InitialDelay, p.MaxDelay)
+		deadline, hasDeadline := ctx.Deadline()
+		if attempt >= p.Attempts || hasDeadline && time.Until(deadline) < delay {
+			stats.add(reason, false)
+			return err
+		}
+		stats.add(reason, true)
+
+		logging.WithFields(ctx, map[string]interface{}{
+			"reason":  reason,
+			"attempt": attempt,
+			"delay":   delay.String(),
+		}).WithError(err).Warn("retrying database operation")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// RetryStats counts retries by reason, nil RetryStats counts nothing
+type RetryStats struct {
+	mu        sync.Mutex
+	retries   map[string]uint64
+	exhausted map[string]uint64
+}
+
+func NewRetryStats() *RetryStats {
+	return &RetryStats{
+		retries:   make(map[string]uint64),
+		exhausted: make(map[string]uint64),
+	}
+}
+
+func (s *RetryStats) add(reason string, retried bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if retried {
+		s.retries[reason]++
+	} else {
+		s.exhausted[reason]++
+	}
+}
+
+// Snapshot returns number of retries and of operations failed after all attempts by reason
+func (s *RetryStats) Snapshot() (retries, exhausted map[string]uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.retries), maps.Clone(s.exhausted)
+}
+
+// WithRetry wraps client to retry statements failed with transient errors.
+// Statement which wasn't sent is always retried, failed SELECT is retried on RetryReason errors.
+// Query is retried only when it fails before returning rows, see retryClient.Query.
+// Statements of transaction aren't retried, TxManager.WithRetry re-runs whole transaction.
+func WithRetry(client Client, policy RetryPolicy, stats *RetryStats) Client {
+	return &retryClient{Client: client, policy: policy, stats: stats}
+}
+
+// retryClient повторяет запросы вне транзакции
+type retryClient struct {
+	Client
+	policy RetryPolicy
+	stats  *RetryStats
+}
+
+func (c *retryClient) retryable(sql string) func(error) (string, bool) {
+	read := isRead(sql)
+	return func(err error) (string, bool) {
+		if pgconn.SafeToRetry(err) {
+			return RetryNotSent, true
+		}
+		if read {
+			return RetryReason(err)
+		}
+		return "", false
+	}
+}
+
+// Query repeats statement only when error is returned by Query itself: connection wasn't acquired
+// or statement wasn't sent. Errors of reading rows come from rows.Err() after some rows may be already
+// consumed by caller, so they aren't retried; use QueryRow or TxManager.WithRetry for such reads.
+func (c *retryClient) Query(ctx context.Context, sql string, args ...interface{}) (rows pgx.Rows, err error) {
+	err = c.policy.retry(ctx, c.stats, c.retryable(sql), func() error {
+		rows, err = c.Client.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow repeats statement when Scan fails
+func (c *retryClient) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return &retryRow{ctx: ctx, client: c, sql: sql, args: args}
+}
+
+func (c *retryClient) Exec(ctx context.Context, sql string, arguments ...interface{}) (tag pgconn.CommandTag, err error) {
+	err = c.policy.retry(ctx, c.stats, c.retryable(sql), func() error {
+		tag, err = c.Client.Exec(ctx, sql, arguments...)
+		return err
+	})
+	return tag, err
+}
+
+type retryRow struct {
+	ctx    context.Context
+	client *retryClient
+	sql    string
+	args   []interface{}
+}
+
+func (r *retryRow) Scan(dest ...interface{}) error {
+	return r.client.policy.retry(r.ctx, r.client.stats, r.client.retryable(r.sql), func() error {
+		return r.client.Client.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	})
+}
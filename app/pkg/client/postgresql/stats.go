@@ -0,0 +1,122 @@
+package postgresql
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxQueryFingerprints ограничивает память статистики, запросы новых отпечатков сверх лимита не учитываются
+const maxQueryFingerprints = 1000
+
+var (
+	placeholderPattern = regexp.MustCompile(`\$\d+`)
+	placeholderList    = regexp.MustCompile(`\(\s*\?(\s*,\s*\?)*\s*\)`)
+	spacePattern       = regexp.MustCompile(`\s+`)
+)
+
+// QueryStat is aggregated statistics of statements with the same fingerprint
+type QueryStat struct {
+	Fingerprint string
+	// Query is normalized statement, placeholders are replaced with `?` and lists of them, e.g. `IN ($1, $2)`, with `(?, ...)`
+	Query    string
+	Calls    uint64
+	Errors   uint64
+	Rows     int64
+	Total    time.Duration
+	Max      time.Duration
+	LastCall time.Time
+}
+
+// Mean is average duration of statement
+func (s QueryStat) Mean() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Calls)
+}
+
+// QueryStats is QueryHook collecting statistics per query fingerprint
+type QueryStats struct {
+	mu      sync.Mutex
+	stats   map[string]*QueryStat
+	dropped uint64
+}
+
+func NewQueryStats() *QueryStats {
+	return &QueryStats{stats: make(map[string]*QueryStat)}
+}
+
+func (s *QueryStats) AfterQuery(_ context.Context, event QueryEvent) {
+	query := NormalizeQuery(event.SQL)
+	fingerprint := Fingerprint(query)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stat, ok := s.stats[fingerprint]
+	if !ok {
+		if len(s.stats) >= maxQueryFingerprints {
+			s.dropped++
+			return
+		}
+		stat = &QueryStat{Fingerprint: fingerprint, Query: query}
+		s.stats[fingerprint] = stat
+	}
+
+	stat.Calls++
+	if event.Err != nil {
+		stat.Errors++
+	}
+	stat.Rows += event.Rows
+	stat.Total += event.Duration
+	stat.Max = max(stat.Max, event.Duration)
+	stat.LastCall = time.Now()
+}
+
+// Snapshot returns copy of statistics sorted by total duration, slowest first,
+// and number of statements not counted because of fingerprints limit
+func (s *QueryStats) Snapshot() ([]QueryStat, uint64) {
+	s.mu.Lock()
+	list := make([]QueryStat, 0, len(s.stats))
+	for _, stat := range s.stats {
+		list = append(list, *stat)
+	}
+	dropped := s.dropped
+	s.mu.Unlock()
+
+	slices.SortFunc(list, func(a, b QueryStat) int {
+		if c := cmp.Compare(b.Total, a.Total); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Fingerprint, b.Fingerprint)
+	})
+	return list, dropped
+}
+
+// Reset clears statistics
+func (s *QueryStats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats = make(map[string]*QueryStat)
+	s.dropped = 0
+}
+
+// NormalizeQuery makes statements differing only in placeholders, length of placeholder lists and spaces equal
+func NormalizeQuery(sql string) string {
+	query := placeholderPattern.ReplaceAllString(sql, "?")
+	query = placeholderList.ReplaceAllString(query, "(?, ...)")
+	return strings.TrimSpace(spacePattern.ReplaceAllString(query, " "))
+}
+
+// Fingerprint identifies normalized query
+func Fingerprint(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,160 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// ErrTxOptions is returned when nested transaction asks for options its outer transaction doesn't have
+var ErrTxOptions = errors.New("nested transaction options conflict with outer transaction")
+
+type ctxTx struct{}
+
+type txState struct {
+	tx      pgx.Tx
+	options pgx.TxOptions
+	// afterCommit are functions registered by AfterCommit in this transaction or savepoint
+	afterCommit *[]func()
+}
+
+// TxFromContext returns transaction started by TxManager, see WithContextTx
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	state, ok := ctx.Value(ctxTx{}).(txState)
+	return state.tx, ok
+}
+
+// AfterCommit runs fn when transaction of context commits, at once when context has no transaction.
+// fn isn't run when transaction or savepoint it was registered in is rolled back.
+func AfterCommit(ctx context.Context, fn func()) {
+	state, ok := ctx.Value(ctxTx{}).(txState)
+	if !ok {
+		fn()
+		return
+	}
+	*state.afterCommit = append(*state.afterCommit, fn)
+}
+
+// TxManager runs functions in transaction carried by context.
+// Queries of client wrapped with WithContextTx run in that transaction.
+type TxManager struct {
+	client Client
+	retry  RetryPolicy
+	stats  *RetryStats
+}
+
+func NewTxManager(client Client) *TxManager {
+	return &TxManager{client: client}
+}
+
+// WithRetry re-runs outermost transactions failed with RetryReason errors, fn must be safe to run again
+func (m *TxManager) WithRetry(policy RetryPolicy, stats *RetryStats) *TxManager {
+	m.retry = policy
+	m.stats = stats
+	return m
+}
+
+// Do runs fn in read-write transaction with default isolation level, see DoTx
+func (m *TxManager) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return m.DoTx(ctx, pgx.TxOptions{}, fn)
+}
+
+// ReadOnly runs fn in read-only transaction, see DoTx
+func (m *TxManager) ReadOnly(ctx context.Context, fn func(ctx context.Context) error) error {
+	return m.DoTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly}, fn)
+}
+
+// DoTx runs fn in transaction which is committed when fn returns nil and rolled back otherwise.
+// Called inside another transaction it runs fn in savepoint, so only changes of fn are rolled back,
+// and failed savepoint isn't retried: transient error aborts the whole transaction.
+// Nested call can't change isolation level or make read-only transaction read-write.
+func (m *TxManager) DoTx(ctx context.Context, options pgx.TxOptions, fn func(ctx context.Context) error) error {
+	outer, ok := ctx.Value(ctxTx{}).(txState)
+	if !ok {
+		return m.retry.retry(ctx, m.stats, RetryReason, func() error {
+			var afterCommit []func()
+			err := m.client.BeginTxFunc(ctx, options, func(tx pgx.Tx) error {
+				return fn(context.WithValue(ctx, ctxTx{}, txState{tx: tx, options: options, afterCommit: &afterCommit}))
+			})
+			if err == nil {
+				for _, f := range afterCommit {
+					f()
+				}
+			}
+			return err
+		})
+	}
+
+	if options.IsoLevel != "" && options.IsoLevel != outer.options.IsoLevel {
+		return fmt.Errorf("%w: isolation level %s", ErrTxOptions, options.IsoLevel)
+	}
+	if options.AccessMode == pgx.ReadWrite && outer.options.AccessMode == pgx.ReadOnly {
+		return fmt.Errorf("%w: read-write in read-only", ErrTxOptions)
+	}
+
+	// functions of savepoint wait for commit of outer transaction
+	var afterCommit []func()
+	err := outer.tx.BeginFunc(ctx, func(savepoint pgx.Tx) error {
+		return fn(context.WithValue(ctx, ctxTx{}, txState{tx: savepoint, options: outer.options, afterCommit: &afterCommit}))
+	})
+	if err == nil {
+		*outer.afterCommit = append(*outer.afterCommit, afterCommit...)
+	}
+	return err
+}
+
+// WithContextTx wraps client to run queries in transaction of context when there is one
+func WithContextTx(client Client) Client {
+	return &ctxTxClient{Client: client}
+}
+
+// ctxTxClient выполняет запросы в транзакции из контекста, иначе в пуле
+type ctxTxClient struct {
+	Client
+}
+
+func (c *ctxTxClient) Begin(ctx context.Context) (pgx.Tx, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.Begin(ctx)
+	}
+	return c.Client.Begin(ctx)
+}
+
+func (c *ctxTxClient) BeginFunc(ctx context.Context, f func(pgx.Tx) error) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.BeginFunc(ctx, f)
+	}
+	return c.Client.BeginFunc(ctx, f)
+}
+
+// BeginTxFunc в транзакции из контекста создает savepoint, опции транзакции не меняются
+func (c *ctxTxClient) BeginTxFunc(ctx context.Context, txOptions pgx.TxOptions, f func(pgx.Tx) error) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.BeginFunc(ctx, f)
+	}
+	return c.Client.BeginTxFunc(ctx, txOptions, f)
+}
+
+func (c *ctxTxClient) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.Query(ctx, sql, args...)
+	}
+	return c.Client.Query(ctx, sql, args...)
+}
+
+func (c *ctxTxClient) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.QueryRow(ctx, sql, args...)
+	}
+	return c.Client.QueryRow(ctx, sql, args...)
+}
+
+func (c *ctxTxClient) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.Exec(ctx, sql, arguments...)
+	}
+	return c.Client.Exec(ctx, sql, arguments...)
+}
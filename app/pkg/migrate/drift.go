@@ -0,0 +1,154 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Column is column expected by code with type as printed by format_type, e.g. `timestamp with time zone`
+type Column struct {
+	Name string
+	Type string
+}
+
+// Table is schema qualified table with columns code reads and writes, other columns are ignored
+type Table struct {
+	Name    string
+	Columns []Column
+}
+
+// ColumnDrift is expected column which is missing or has other type, Actual is empty for missing column
+type ColumnDrift struct {
+	Table    string
+	Column   string
+	Expected string
+	Actual   string
+}
+
+// Drift is difference between database and binary, empty when schema is what code expects
+type Drift struct {
+	// Version is the newest applied migration, Latest is the newest migration of binary
+	Version uint64
+	Latest  uint64
+	// Migrations are not cleanly applied: pending, dirty, modified or missing
+	Migrations []Status
+	Columns    []ColumnDrift
+}
+
+// Clean reports whether database schema is what code expects
+func (d Drift) Clean() bool {
+	return len(d.Migrations) == 0 && len(d.Columns) == 0
+}
+
+// Pending reports whether drift is only pending migrations, so applying them fixes it
+func (d Drift) Pending() bool {
+	for _, m := range d.Migrations {
+		if m.State != StatePending {
+			return false
+		}
+	}
+	return len(d.Migrations) > 0
+}
+
+// Err describes drift, nil when schema is clean
+func (d Drift) Err() error {
+	var errs []error
+	for _, m := range d.Migrations {
+		errs = append(errs, fmt.Errorf("migration %d %s is %s", m.Version, m.Name, m.State))
+	}
+	for _, c := range d.Columns {
+		if c.Actual == "" {
+			errs = append(errs, fmt.Errorf("column %s.%s of type %s is missing", c.Table, c.Column, c.Expected))
+			continue
+		}
+		errs = append(errs, fmt.Errorf("column %s.%s is %s, expected %s", c.Table, c.Column, c.Actual, c.Expected))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("schema version %d, binary expects %d: %w", d.Version, d.Latest, errors.Join(errs...))
+}
+
+// Checker compares database with migrations and tables of binary. It only reads, so conn may be pool.
+type Checker struct {
+	runner *Runner
+	conn   Conn
+	tables []Table
+}
+
+func NewChecker(conn Conn, migrations []Migration, tables ...Table) *Checker {
+	return &Checker{
+		runner: NewRunner(conn, migrations, io.Discard),
+		conn:   conn,
+		tables: tables,
+	}
+}
+
+// Check returns current drift of database
+func (c *Checker) Check(ctx context.Context) (Drift, error) {
+	drift := Drift{Latest: c.runner.Latest()}
+
+	version, _, err := c.runner.Version(ctx)
+	if err != nil {
+		return Drift{}, err
+	}
+	drift.Version = version
+
+	statuses, err := c.runner.Status(ctx)
+	if err != nil {
+		return Drift{}, err
+	}
+	for _, s := range statuses {
+		if s.State != StateApplied {
+			drift.Migrations = append(drift.Migrations, s)
+		}
+	}
+
+	for _, t := range c.tables {
+		columns, err := c.columns(ctx, t.Name)
+		if err != nil {
+			return Drift{}, err
+		}
+		for _, expected := range t.Columns {
+			if actual := columns[expected.Name]; actual != expected.Type {
+				drift.Columns = append(drift.Columns, ColumnDrift{
+					Table:    t.Name,
+					Column:   expected.Name,
+					Expected: expected.Type,
+					Actual:   actual,
+				})
+			}
+		}
+	}
+
+	return drift, nil
+}
+
+// columns returns types of table columns by names, none when table doesn't exist
+func (c *Checker) columns(ctx context.Context, table string) (map[string]string, error) {
+	rows, err := c.conn.Query(ctx,
+		"SELECT a.attname, format_type(a.atttypid, a.atttypmod) FROM pg_attribute a "+
+			"WHERE a.attrelid = to_regclass($1) AND a.attnum > 0 AND NOT a.attisdropped",
+		table,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]string)
+	for rows.Next() {
+		var name, typ string
+		if err = rows.Scan(&name, &typ); err != nil {
+			return nil, fmt.Errorf("read columns of %s: %w", table, err)
+		}
+		columns[name] = typ
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("read columns of %s: %w", table, err)
+	}
+
+	return columns, nil
+}
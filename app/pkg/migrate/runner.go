@@ -0,0 +1,424 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	table = "public.schema_migrations"
+	// lockID is key of session advisory lock held while migrations are applied
+	lockID int64 = 7_268_151_734
+)
+
+var (
+	ErrDirty          = errors.New("database is dirty, fix it manually and run force")
+	ErrChecksum       = errors.New("applied migration was changed")
+	ErrMissing        = errors.New("applied migration is missing")
+	ErrOutOfOrder     = errors.New("pending migration is older than applied one")
+	ErrUnknownVersion = errors.New("unknown migration version")
+	ErrNoDown         = errors.New("migration has no down script")
+)
+
+// Conn is single connection, session advisory lock is held by it, e.g. *pgx.Conn
+type Conn interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+type State string
+
+const (
+	StateApplied  State = "applied"
+	StatePending  State = "pending"
+	StateDirty    State = "dirty"
+	StateModified State = "modified"
+	StateMissing  State = "missing"
+)
+
+// Status is state of migration known by files or by schema_migrations
+type Status struct {
+	Version   uint64
+	Name      string
+	State     State
+	AppliedAt time.Time
+}
+
+type applied struct {
+	Version   uint64
+	Name      string
+	Checksum  string
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+// Runner applies migrations one by one. Every migration is recorded as dirty before its script runs
+// and as clean after, so failed migration blocks runner until it is fixed and forced.
+type Runner struct {
+	conn       Conn
+	migrations []Migration
+	out        io.Writer
+	dryRun     bool
+}
+
+func NewRunner(conn Conn, migrations []Migration, out io.Writer) *Runner {
+	return &Runner{
+		conn:       conn,
+		migrations: migrations,
+		out:        out,
+	}
+}
+
+// DryRun makes runner print SQL of migrations instead of applying them
+func (r *Runner) DryRun(dryRun bool) *Runner {
+	r.dryRun = dryRun
+	return r
+}
+
+// Latest returns version of the newest migration file, 0 when there are none
+func (r *Runner) Latest() uint64 {
+	if len(r.migrations) == 0 {
+		return 0
+	}
+	return r.migrations[len(r.migrations)-1].Version
+}
+
+// Version returns the newest applied version and whether it is dirty
+func (r *Runner) Version(ctx context.Context) (uint64, bool, error) {
+	list, err := r.applied(ctx)
+	if err != nil || len(list) == 0 {
+		return 0, false, err
+	}
+	last := list[len(list)-1]
+	return last.Version, last.Dirty, nil
+}
+
+// Status lists migration files and applied migrations which have no file
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	list, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[uint64]applied, len(list))
+	for _, a := range list {
+		byVersion[a.Version] = a
+	}
+
+	statuses := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		status := Status{Version: m.Version, Name: m.Name, State: StatePending}
+		if a, ok := byVersion[m.Version]; ok {
+			status.AppliedAt = a.AppliedAt
+			switch {
+			case a.Dirty:
+				status.State = StateDirty
+			case a.Checksum != m.Checksum:
+				status.State = StateModified
+			default:
+				status.State = StateApplied
+			}
+			delete(byVersion, m.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, a := range list {
+		if _, ok := byVersion[a.Version]; ok {
+			statuses = append(statuses, Status{Version: a.Version, Name: a.Name, State: StateMissing, AppliedAt: a.AppliedAt})
+		}
+	}
+
+	return statuses, nil
+}
+
+// Up applies steps pending migrations, all of them when steps <= 0
+func (r *Runner) Up(ctx context.Context, steps int) error {
+	return r.run(ctx, func(list []applied) ([]Migration, []Migration) {
+		pending := r.pending(list)
+		if steps > 0 && steps < len(pending) {
+			pending = pending[:steps]
+		}
+		return pending, nil
+	})
+}
+
+// Down reverts steps newest applied migrations, all of them when steps <= 0
+func (r *Runner) Down(ctx context.Context, steps int) error {
+	return r.run(ctx, func(list []applied) ([]Migration, []Migration) {
+		revert := r.reverse(list)
+		if steps > 0 && steps < len(revert) {
+			revert = revert[:steps]
+		}
+		return nil, revert
+	})
+}
+
+// To applies or reverts migrations so that version is the newest applied one, 0 reverts all
+func (r *Runner) To(ctx context.Context, version uint64) error {
+	if _, err := r.find(version); err != nil {
+		return err
+	}
+	return r.run(ctx, func(list []applied) ([]Migration, []Migration) {
+		var up, down []Migration
+		for _, m := range r.pending(list) {
+			if m.Version <= version {
+				up = append(up, m)
+			}
+		}
+		for _, m := range r.reverse(list) {
+			if m.Version > version {
+				down = append(down, m)
+			}
+		}
+		return up, down
+	})
+}
+
+// Force records migrations up to version as applied with current checksums and removes newer records
+// without running scripts. It is used to clear dirty state or to adopt database migrated by other tool.
+func (r *Runner) Force(ctx context.Context, version uint64) error {
+	if _, err := r.find(version); err != nil {
+		return err
+	}
+	if r.dryRun {
+		fmt.Fprintf(r.out, "-- force version %d\n", version)
+		return nil
+	}
+
+	return r.locked(ctx, func() error {
+		if _, err := r.conn.Exec(ctx, "DELETE FROM "+table+" WHERE version > $1", version); err != nil {
+			return fmt.Errorf("force version %d: %w", version, err)
+		}
+		for _, m := range r.migrations {
+			if m.Version > version {
+				break
+			}
+			if _, err := r.conn.Exec(ctx,
+				"INSERT INTO "+table+" (version, name, checksum, dirty) VALUES ($1, $2, $3, FALSE) "+
+					"ON CONFLICT (version) DO UPDATE SET name = EXCLUDED.name, checksum = EXCLUDED.checksum, dirty = FALSE",
+				m.Version, m.Name, m.Checksum,
+			); err != nil {
+				return fmt.Errorf("force version %d: %w", version, err)
+			}
+		}
+		fmt.Fprintf(r.out, "forced version %d\n", version)
+		return nil
+	})
+}
+
+// run validates applied migrations, plans what to apply and revert and runs it under advisory lock
+func (r *Runner) run(ctx context.Context, plan func([]applied) (up, down []Migration)) error {
+	if r.dryRun {
+		list, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+		return r.execute(ctx, list, plan)
+	}
+
+	return r.locked(ctx, func() error {
+		list, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+		return r.execute(ctx, list, plan)
+	})
+}
+
+func (r *Runner) execute(ctx context.Context, list []applied, plan func([]applied) (up, down []Migration)) error {
+	if err := r.validate(list); err != nil {
+		return err
+	}
+	up, down := plan(list)
+	if len(up) == 0 && len(down) == 0 {
+		fmt.Fprintln(r.out, "no change")
+		return nil
+	}
+	for _, m := range down {
+		if m.Down == "" {
+			return fmt.Errorf("%w: %d %s", ErrNoDown, m.Version, m.Name)
+		}
+	}
+
+	for _, m := range down {
+		if err := r.revert(ctx, m); err != nil {
+			return err
+		}
+	}
+	for _, m := range up {
+		if err := r.apply(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) apply(ctx context.Context, m Migration) error {
+	if r.dryRun {
+		fmt.Fprintf(r.out, "-- up %d %s\n%s\n", m.Version, m.Name, m.Up)
+		return nil
+	}
+
+	start := time.Now()
+	if _, err := r.conn.Exec(ctx,
+		"INSERT INTO "+table+" (version, name, checksum, dirty) VALUES ($1, $2, $3, TRUE)",
+		m.Version, m.Name, m.Checksum,
+	); err != nil {
+		return fmt.Errorf("record migration %d: %w", m.Version, err)
+	}
+	if _, err := r.conn.Exec(ctx, m.Up); err != nil {
+		return fmt.Errorf("apply migration %d %s, version is dirty: %w", m.Version, m.Name, err)
+	}
+	if _, err := r.conn.Exec(ctx, "UPDATE "+table+" SET dirty = FALSE WHERE version = $1", m.Version); err != nil {
+		return fmt.Errorf("record migration %d: %w", m.Version, err)
+	}
+
+	fmt.Fprintf(r.out, "applied %d %s in %s\n", m.Version, m.Name, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func (r *Runner) revert(ctx context.Context, m Migration) error {
+	if r.dryRun {
+		fmt.Fprintf(r.out, "-- down %d %s\n%s\n", m.Version, m.Name, m.Down)
+		return nil
+	}
+
+	start := time.Now()
+	if _, err := r.conn.Exec(ctx, "UPDATE "+table+" SET dirty = TRUE WHERE version = $1", m.Version); err != nil {
+		return fmt.Errorf("record migration %d: %w", m.Version, err)
+	}
+	if _, err := r.conn.Exec(ctx, m.Down); err != nil {
+		return fmt.Errorf("revert migration %d %s, version is dirty: %w", m.Version, m.Name, err)
+	}
+	if _, err := r.conn.Exec(ctx, "DELETE FROM "+table+" WHERE version = $1", m.Version); err != nil {
+		return fmt.Errorf("record migration %d: %w", m.Version, err)
+	}
+
+	fmt.Fprintf(r.out, "reverted %d %s in %s\n", m.Version, m.Name, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// locked runs fn holding session advisory lock, so concurrent runners wait for each other
+func (r *Runner) locked(ctx context.Context, fn func() error) (err error) {
+	if _, err = r.conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("take migrations lock: %w", err)
+	}
+	defer func() {
+		if _, unlockErr := r.conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockID); unlockErr != nil && err == nil {
+			err = fmt.Errorf("release migrations lock: %w", unlockErr)
+		}
+	}()
+
+	if _, err = r.conn.Exec(ctx, "CREATE TABLE IF NOT EXISTS "+table+` (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		dirty BOOLEAN NOT NULL DEFAULT FALSE,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return fmt.Errorf("create %s: %w", table, err)
+	}
+
+	return fn()
+}
+
+// applied returns records of schema_migrations ordered by version, none when table doesn't exist yet
+func (r *Runner) applied(ctx context.Context) ([]applied, error) {
+	var exists bool
+	if err := r.conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("check %s: %w", table, err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	rows, err := r.conn.Query(ctx, "SELECT version, name, checksum, dirty, applied_at FROM "+table+" ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var list []applied
+	for rows.Next() {
+		var a applied
+		if err = rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.Dirty, &a.AppliedAt); err != nil {
+			return nil, fmt.Errorf("read %s: %w", table, err)
+		}
+		list = append(list, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", table, err)
+	}
+
+	return list, nil
+}
+
+// validate checks that applied migrations are clean, have files with the same checksums
+// and no pending migration is older than applied one
+func (r *Runner) validate(list []applied) error {
+	var errs []error
+	var last uint64
+	for _, a := range list {
+		last = a.Version
+		m, err := r.find(a.Version)
+		switch {
+		case a.Dirty:
+			errs = append(errs, fmt.Errorf("%w: version %d", ErrDirty, a.Version))
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%w: %d %s", ErrMissing, a.Version, a.Name))
+		case m.Checksum != a.Checksum:
+			errs = append(errs, fmt.Errorf("%w: %d %s", ErrChecksum, a.Version, a.Name))
+		}
+	}
+	for _, m := range r.pending(list) {
+		if m.Version < last {
+			errs = append(errs, fmt.Errorf("%w: %d %s", ErrOutOfOrder, m.Version, m.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pending returns not applied migrations in order of versions
+func (r *Runner) pending(list []applied) []Migration {
+	done := make(map[uint64]bool, len(list))
+	for _, a := range list {
+		done[a.Version] = true
+	}
+
+	var pending []Migration
+	for _, m := range r.migrations {
+		if !done[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// reverse returns applied migrations from the newest
+func (r *Runner) reverse(list []applied) []Migration {
+	revert := make([]Migration, 0, len(list))
+	for i := len(list) - 1; i >= 0; i-- {
+		m, _ := r.find(list[i].Version)
+		revert = append(revert, m)
+	}
+	return revert
+}
+
+// find returns migration of version, version 0 means empty schema
+func (r *Runner) find(version uint64) (Migration, error) {
+	if version == 0 {
+		return Migration{}, nil
+	}
+	for _, m := range r.migrations {
+		if m.Version == version {
+			return m, nil
+		}
+	}
+	return Migration{}, fmt.Errorf("%w %d", ErrUnknownVersion, version)
+}
@@ -0,0 +1,107 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{spec: "* * * *", want: "cron `* * * *` must have 5 fields, got 4"},
+		{spec: "@every 5m", want: "cron `@every 5m` must have 5 fields, got 2"},
+		{spec: "60 * * * *", want: "minute of cron `60 * * * *`: `60` is out of range 0-59"},
+		{spec: "* 24 * * *", want: "hour of cron `* 24 * * *`: `24` is out of range 0-23"},
+		{spec: "* * 0 * *", want: "day of month of cron `* * 0 * *`: `0` is out of range 1-31"},
+		{spec: "* * * 13 *", want: "month of cron `* * * 13 *`: `13` is out of range 1-12"},
+		{spec: "* * * * 8", want: "day of week of cron `* * * * 8`: `8` is out of range 0-7"},
+		{spec: "5-1 * * * *", want: "minute of cron `5-1 * * * *`: `5-1` is out of range 0-59"},
+		{spec: "a * * * *", want: "minute of cron `a * * * *`: bad value `a`"},
+		{spec: "1-x * * * *", want: "minute of cron `1-x * * * *`: bad value `1-x`"},
+		{spec: "*/0 * * * *", want: "minute of cron `*/0 * * * *`: bad step `*/0`"},
+		{spec: "1,,2 * * * *", want: "minute of cron `1,,2 * * * *`: bad value ``"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := ParseCron(tt.spec)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("ParseCron() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// 2024-03-01 is Friday
+	from := time.Date(2024, 3, 1, 10, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{spec: "* * * * *", from: from, want: time.Date(2024, 3, 1, 10, 31, 0, 0, time.UTC)},
+		{spec: "30 10 * * *", from: from, want: time.Date(2024, 3, 2, 10, 30, 0, 0, time.UTC)},
+		{spec: "30 10 * * *", from: time.Date(2024, 3, 1, 10, 29, 59, 0, time.UTC), want: time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", from: from, want: time.Date(2024, 3, 1, 10, 45, 0, 0, time.UTC)},
+		{spec: "0 9-17/4 * * *", from: from, want: time.Date(2024, 3, 1, 13, 0, 0, 0, time.UTC)},
+		{spec: "0,20 23 * * *", from: from, want: time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)},
+		{spec: "@hourly", from: from, want: time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC)},
+		{spec: "@daily", from: from, want: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{spec: "@weekly", from: from, want: time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{spec: "@monthly", from: from, want: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "@yearly", from: from, want: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// 7 - тоже воскресенье
+		{spec: "0 0 * * 7", from: from, want: time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 * * 1-5", from: from, want: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)},
+		// ограничены оба дня: подходит любой из них
+		{spec: "0 0 15 * 1", from: from, want: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 2 * 1", from: from, want: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 31 * *", from: from, want: time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 29 2 *", from: from, want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 12 * 12 *", from: time.Date(2024, 12, 31, 12, 0, 0, 0, time.UTC), want: time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			c, err := ParseCron(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseCron() error = %v", err)
+			}
+			if got := c.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNextNever(t *testing.T) {
+	c, err := ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("ParseCron() error = %v", err)
+	}
+	if got := c.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("Next() = %s, want zero time", got)
+	}
+}
+
+func TestCronNextLocation(t *testing.T) {
+	c, err := ParseCron("0 3 * * *")
+	if err != nil {
+		t.Fatalf("ParseCron() error = %v", err)
+	}
+
+	msk := time.FixedZone("MSK", 3*60*60)
+	got := c.Next(time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC))
+	if want := time.Date(2024, 3, 1, 3, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next() in UTC = %s, want %s", got, want)
+	}
+
+	got = c.Next(time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC).In(msk))
+	if want := time.Date(2024, 3, 2, 3, 0, 0, 0, msk); !got.Equal(want) || got.Location() != msk {
+		t.Errorf("Next() in MSK = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+// Package queue is durable job queue in PostgreSQL. Workers claim due jobs with FOR UPDATE SKIP LOCKED,
+// so any number of instances share the queue without claiming the same job twice.
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+var (
+	ErrUnknownType = errors.New("unknown job type")
+	ErrDuplicate   = errors.New("job with the same unique key is already queued")
+	ErrState       = errors.New("job status doesn't allow operation")
+	// ErrPermanent returned by handler fails job without retries, e.g. webhook endpoint answered 4xx
+	ErrPermanent = errors.New("permanent job failure")
+)
+
+// Handler runs job, job is retried with backoff when handler returns error.
+// ctx is cancelled on timeout of job type and on shutdown, then job is returned to queue.
+type Handler func(ctx context.Context, job Job) error
+
+// Type is named kind of jobs with handler, types are registered before queue runs
+type Type struct {
+	Name   string
+	Handle Handler
+	// MaxAttempts and Timeout override Options when positive
+	MaxAttempts int
+	Timeout     time.Duration
+	// Cron makes type recurring: the next occurrence is queued when previous one finishes,
+	// see ParseCron for syntax. Schedule is evaluated in UTC, payload of occurrences is empty.
+	Cron string
+}
+
+type Job struct {
+	ID          string
+	Type        string
+	Payload     json.RawMessage
+	Status      Status
+	UniqueKey   sql.NullString
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	LockedBy    sql.NullString
+	LockedUntil sql.NullTime
+	LastError   sql.NullString
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	FinishedAt  sql.NullTime
+}
+
+// Decode unmarshals payload of job into v
+func (j Job) Decode(v interface{}) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+type EnqueueOptions struct {
+	// RunAt is the earliest time job runs at, now by default
+	RunAt time.Time
+	// UniqueKey rejects job with ErrDuplicate while job of the same type and key is pending or running
+	UniqueKey string
+	// MaxAttempts overrides attempts of job type when positive
+	MaxAttempts int
+}
+
+// ListFilter selects jobs for admin, empty fields match any job
+type ListFilter struct {
+	Status Status
+	Type   string
+	Limit  uint64
+	Offset uint64
+}
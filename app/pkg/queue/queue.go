@@ -0,0 +1,322 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	table = "public.job"
+
+	defaultListLimit = 50
+	maxListLimit     = 500
+
+	// cronUniqueKey keeps single queued occurrence of recurring type
+	cronUniqueKey = "cron"
+)
+
+// activeConflict is conflict target of partial unique index of pending and running jobs
+const activeConflict = "ON CONFLICT (type, unique_key) WHERE unique_key IS NOT NULL AND status IN ('pending', 'running')"
+
+var jobColumns = []string{
+	"id",
+	"type",
+	"payload",
+	"status",
+	"unique_key",
+	"attempts",
+	"max_attempts",
+	"run_at",
+	"locked_by",
+	"locked_until",
+	"last_error",
+	"created_at",
+	"updated_at",
+	"finished_at",
+}
+
+type Options struct {
+	Workers      int
+	PollInterval time.Duration
+	// Timeout and MaxAttempts are defaults of types
+	Timeout     time.Duration
+	MaxAttempts int
+	// Failed attempt is retried after RetryDelay doubled by every attempt up to MaxRetryDelay
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+}
+
+type jobType struct {
+	Type
+	cron *Cron
+}
+
+// Queue enqueues jobs and runs them by workers of Run. Client may be bound to transaction of context,
+// then job is enqueued only if transaction commits.
+type Queue struct {
+	client  postgresql.Querier
+	jobs    *postgresql.Repository[Job]
+	options Options
+	types   map[string]jobType
+	worker  string
+	// wake lets idle worker claim job enqueued by this instance without waiting for poll, see notify
+	wake chan struct{}
+}
+
+func NewQueue(client postgresql.Querier, options Options) *Queue {
+	options.Workers = max(options.Workers, 1)
+	options.MaxAttempts = max(options.MaxAttempts, 1)
+	if options.PollInterval <= 0 {
+		options.PollInterval = time.Second
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 5 * time.Minute
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = 10 * time.Second
+	}
+	if options.MaxRetryDelay < options.RetryDelay {
+		options.MaxRetryDelay = options.RetryDelay
+	}
+
+	host, _ := os.Hostname()
+
+	return &Queue{
+		client: client,
+		jobs: postgresql.NewRepository(client, postgresql.Table[Job]{
+			Name:       table,
+			PrimaryKey: "id",
+			Columns:    jobColumns,
+			Scan:       scanJob,
+		}),
+		options: options,
+		types:   make(map[string]jobType),
+		worker:  fmt.Sprintf("%s-%d", host, os.Getpid()),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Register adds job type, it must be called before Run
+func (q *Queue) Register(t Type) error {
+	if t.Name == "" || t.Handle == nil {
+		return errors.New("job type requires name and handler")
+	}
+	if _, ok := q.types[t.Name]; ok {
+		return fmt.Errorf("job type %s is already registered", t.Name)
+	}
+
+	registered := jobType{Type: t}
+	if t.Cron != "" {
+		cron, err := ParseCron(t.Cron)
+		if err != nil {
+			return fmt.Errorf("job type %s: %w", t.Name, err)
+		}
+		registered.cron = cron
+	}
+	if registered.MaxAttempts <= 0 {
+		registered.MaxAttempts = q.options.MaxAttempts
+	}
+	if registered.Timeout <= 0 {
+		registered.Timeout = q.options.Timeout
+	}
+
+	q.types[t.Name] = registered
+	return nil
+}
+
+// Enqueue queues job of registered type with payload marshaled to JSON and returns its id
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}, opts EnqueueOptions) (string, error) {
+	t, ok := q.types[jobType]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownType, jobType)
+	}
+
+	data := []byte("{}")
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return "", fmt.Errorf("marshal payload of %s job: %w", jobType, err)
+		}
+	}
+
+	values := map[string]interface{}{
+		"type":         jobType,
+		"payload":      string(data),
+		"max_attempts": t.MaxAttempts,
+	}
+	if opts.MaxAttempts > 0 {
+		values["max_attempts"] = opts.MaxAttempts
+	}
+	if !opts.RunAt.IsZero() {
+		values["run_at"] = opts.RunAt
+	}
+	if opts.UniqueKey != "" {
+		values["unique_key"] = opts.UniqueKey
+	}
+
+	query := sq.Insert(table).PlaceholderFormat(sq.Dollar).SetMap(values).Suffix(activeConflict + " DO NOTHING RETURNING id")
+	sql, args, err := query.ToSql()
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"sql":   sql,
+		"table": table,
+		"args":  postgresql.RedactArgs(args),
+	})
+	if err != nil {
+		err = postgresql.ErrCreateQuery(err)
+		logger.Error(err)
+		return "", err
+	}
+
+	var id string
+	if err = q.client.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("%w: %s %s", ErrDuplicate, jobType, opts.UniqueKey)
+		}
+		err = postgresql.ErrDoQuery(err)
+		logger.Error(err)
+		return "", err
+	}
+
+	postgresql.AfterCommit(ctx, q.notify)
+
+	return id, nil
+}
+
+// List returns jobs of filter from the newest
+func (q *Queue) List(ctx context.Context, filter ListFilter) ([]Job, error) {
+	limit := filter.Limit
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+	limit = min(limit, maxListLimit)
+
+	return q.jobs.List(ctx, func(query sq.SelectBuilder) sq.SelectBuilder {
+		if filter.Status != "" {
+			query = query.Where(sq.Eq{"status": string(filter.Status)})
+		}
+		if filter.Type != "" {
+			query = query.Where(sq.Eq{"type": filter.Type})
+		}
+		return query.OrderBy("created_at DESC", "id").Limit(limit).Offset(filter.Offset)
+	})
+}
+
+// One returns job by id, error wraps postgresql.ErrNotFound when there is no such job
+func (q *Queue) One(ctx context.Context, id string) (Job, error) {
+	return q.jobs.One(ctx, id)
+}
+
+// Retry queues failed or cancelled job again with reset attempts
+func (q *Queue) Retry(ctx context.Context, id string) (Job, error) {
+	job, err := q.transition(ctx, id,
+		"status = 'pending', attempts = 0, run_at = now(), finished_at = NULL, updated_at = now()",
+		StatusFailed, StatusCancelled,
+	)
+	if errors.Is(err, postgresql.ErrAlreadyExists) {
+		return Job{}, fmt.Errorf("%w: %v", ErrDuplicate, err)
+	}
+	if err == nil {
+		postgresql.AfterCommit(ctx, q.notify)
+	}
+	return job, err
+}
+
+// Cancel cancels pending job, running job can't be cancelled
+func (q *Queue) Cancel(ctx context.Context, id string) (Job, error) {
+	return q.transition(ctx, id,
+		"status = 'cancelled', finished_at = now(), updated_at = now()",
+		StatusPending,
+	)
+}
+
+// Purge deletes jobs finished before olderThan and returns their number
+func (q *Queue) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	return q.exec(ctx,
+		"DELETE FROM "+table+" WHERE finished_at < now() - make_interval(secs => $1)",
+		olderThan.Seconds(),
+	)
+}
+
+// transition updates job in one of from statuses, error wraps ErrState when job has other status
+func (q *Queue) transition(ctx context.Context, id, set string, from ...Status) (Job, error) {
+	statuses := make([]string, len(from))
+	for i, s := range from {
+		statuses[i] = string(s)
+	}
+
+	query := sq.Expr(
+		"UPDATE "+table+" SET "+set+" WHERE id = $1 AND status = ANY($2::text[]) RETURNING "+strings.Join(jobColumns, ", "),
+		id, statuses,
+	)
+	job, err := postgresql.FetchOne(ctx, q.client, table, query, scanJob)
+	if !errors.Is(err, postgresql.ErrNotFound) {
+		return job, err
+	}
+
+	// job either doesn't exist or has other status
+	if job, err = q.One(ctx, id); err != nil {
+		return Job{}, err
+	}
+	return Job{}, fmt.Errorf("%w: job %s is %s, expected %v", ErrState, id, job.Status, statuses)
+}
+
+// notify wakes idle worker. Job enqueued in transaction of context is visible to workers after commit only,
+// so notify is run by postgresql.AfterCommit.
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// exec runs statement and returns number of affected rows
+func (q *Queue) exec(ctx context.Context, sql string, args ...interface{}) (int64, error) {
+	tag, err := q.client.Exec(ctx, sql, args...)
+	if err != nil {
+		err = postgresql.ErrDoQuery(err)
+		logging.WithFields(ctx, map[string]interface{}{
+			"sql":   sql,
+			"table": table,
+			"args":  postgresql.RedactArgs(args),
+		}).Error(err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanJob(row pgx.Row) (Job, error) {
+	var (
+		job     Job
+		payload []byte
+		status  string
+	)
+	err := row.Scan(
+		&job.ID,
+		&job.Type,
+		&payload,
+		&status,
+		&job.UniqueKey,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.RunAt,
+		&job.LockedBy,
+		&job.LockedUntil,
+		&job.LastError,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.FinishedAt,
+	)
+	job.Payload = payload
+	job.Status = Status(status)
+	return job, err
+}
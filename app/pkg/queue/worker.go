@@ -0,0 +1,227 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HollyEllmo/my-first-go-project/pkg/client/postgresql"
+	"github.com/HollyEllmo/my-first-go-project/pkg/logging"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// lockGrace is added to timeout of type, so lock outlives handler which respects its context
+	lockGrace = 30 * time.Second
+	// maintainInterval is period of requeueing jobs of lost workers and scheduling recurring types
+	maintainInterval = time.Minute
+)
+
+// claimSQL locks the oldest due job of given types and marks it running by worker $1 until its timeout
+// of $3 in seconds passes. Jobs locked by other workers are skipped, not waited for.
+var claimSQL = `WITH next AS (
+	SELECT j.id, t.timeout FROM ` + table + ` j
+	JOIN unnest($2::text[], $3::float8[]) AS t(type, timeout) ON t.type = j.type
+	WHERE j.status = 'pending' AND j.run_at <= now()
+	ORDER BY j.run_at
+	LIMIT 1
+	FOR UPDATE OF j SKIP LOCKED
+)
+UPDATE ` + table + ` j SET status = 'running', attempts = j.attempts + 1, locked_by = $1,
+	locked_until = now() + make_interval(secs => next.timeout), updated_at = now()
+FROM next WHERE j.id = next.id
+RETURNING j.` + strings.Join(jobColumns, ", j.")
+
+// reapSQL returns running jobs with expired locks to queue, their workers are gone
+const reapSQL = `UPDATE ` + table + ` SET
+	status = CASE WHEN attempts >= max_attempts THEN 'failed' ELSE 'pending' END,
+	finished_at = CASE WHEN attempts >= max_attempts THEN now() END,
+	last_error = 'lock of worker ' || COALESCE(locked_by, '') || ' expired',
+	locked_by = NULL, locked_until = NULL, run_at = now(), updated_at = now()
+WHERE status = 'running' AND locked_until < now()`
+
+// Run runs pool of workers until ctx is done. Job running on shutdown is returned to queue
+// without spending its attempt.
+func (q *Queue) Run(ctx context.Context) error {
+	names := make([]string, 0, len(q.types))
+	timeouts := make([]float64, 0, len(q.types))
+	for name, t := range q.types {
+		names = append(names, name)
+		timeouts = append(timeouts, (t.Timeout + lockGrace).Seconds())
+	}
+
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"workers": q.options.Workers,
+		"types":   names,
+		"worker":  q.worker,
+	})
+	logger.Println("job queue started")
+
+	var wg sync.WaitGroup
+	for range q.options.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx, names, timeouts)
+		}()
+	}
+
+	ticker := time.NewTicker(maintainInterval)
+	defer ticker.Stop()
+
+	q.maintain(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			logger.Println("job queue stopped")
+			return nil
+		case <-ticker.C:
+			q.maintain(ctx)
+		}
+	}
+}
+
+// maintain requeues jobs of lost workers and queues next occurrences of recurring types
+func (q *Queue) maintain(ctx context.Context) {
+	if n, err := q.exec(ctx, reapSQL); err == nil && n > 0 {
+		logging.Warnf(ctx, "requeued %d jobs with expired locks", n)
+	}
+
+	for _, t := range q.types {
+		if t.cron != nil {
+			q.scheduleNext(ctx, t)
+		}
+	}
+}
+
+// scheduleNext queues the next occurrence of recurring type unless one is already queued
+func (q *Queue) scheduleNext(ctx context.Context, t jobType) {
+	next := t.cron.Next(time.Now().UTC())
+	if next.IsZero() {
+		logging.Warnf(ctx, "cron `%s` of job type %s never occurs", t.Cron, t.Name)
+		return
+	}
+
+	_, err := q.Enqueue(ctx, t.Name, nil, EnqueueOptions{RunAt: next, UniqueKey: cronUniqueKey})
+	if err != nil && !errors.Is(err, ErrDuplicate) {
+		logging.WithError(ctx, err).Errorf("failed to schedule job type %s", t.Name)
+	}
+}
+
+func (q *Queue) work(ctx context.Context, names []string, timeouts []float64) {
+	for ctx.Err() == nil {
+		job, ok, err := q.claim(ctx, names, timeouts)
+		if err != nil && ctx.Err() == nil {
+			logging.WithError(ctx, err).Error("failed to claim job")
+		}
+		if ok {
+			q.process(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-q.wake:
+		case <-time.After(q.options.PollInterval):
+		}
+	}
+}
+
+// claim locks due job for this worker, ok is false when there is none
+func (q *Queue) claim(ctx context.Context, names []string, timeouts []float64) (Job, bool, error) {
+	if len(names) == 0 {
+		return Job{}, false, nil
+	}
+
+	job, err := scanJob(q.client.QueryRow(ctx, claimSQL, q.worker, names, timeouts))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Job{}, false, nil
+	}
+	if err != nil {
+		return Job{}, false, postgresql.ErrDoQuery(err)
+	}
+	return job, true, nil
+}
+
+func (q *Queue) process(ctx context.Context, job Job) {
+	t := q.types[job.Type]
+	logger := logging.WithFields(ctx, map[string]interface{}{
+		"job_id":   job.ID,
+		"job_type": job.Type,
+		"attempt":  job.Attempts,
+	})
+
+	start := time.Now()
+	err := q.handle(ctx, t, job)
+	logger = logger.WithField("duration", time.Since(start).Round(time.Millisecond))
+
+	// statuses are recorded even when ctx is cancelled by shutdown
+	updateCtx := context.WithoutCancel(ctx)
+	switch {
+	case err == nil:
+		q.finish(updateCtx, job, "status = 'succeeded', finished_at = now()")
+		logger.Info("job succeeded")
+	case ctx.Err() != nil:
+		q.finish(updateCtx, job, "status = 'pending', attempts = attempts - 1, run_at = now()")
+		logger.WithError(err).Warn("job interrupted by shutdown, returned to queue")
+		return
+	case errors.Is(err, ErrPermanent) || job.Attempts >= job.MaxAttempts:
+		q.finish(updateCtx, job, "status = 'failed', finished_at = now(), last_error = $3", err.Error())
+		logger.WithError(err).Error("job failed")
+	default:
+		delay := q.backoff(job.Attempts)
+		q.finish(updateCtx, job, "status = 'pending', run_at = now() + make_interval(secs => $3), last_error = $4",
+			delay.Seconds(), err.Error())
+		logger.WithError(err).WithField("retry_in", delay.Round(time.Millisecond)).Warn("job failed, will be retried")
+	}
+
+	if t.cron != nil {
+		q.scheduleNext(updateCtx, t)
+	}
+}
+
+// handle runs handler within timeout of type, panic of handler fails attempt
+func (q *Queue) handle(ctx context.Context, t jobType, job Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panicked: %v", r)
+		}
+	}()
+
+	return t.Handle(ctx, job)
+}
+
+// finish sets columns of job still locked by this worker and releases lock, set may use args from $3
+func (q *Queue) finish(ctx context.Context, job Job, set string, args ...interface{}) {
+	n, err := q.exec(ctx,
+		"UPDATE "+table+" SET "+set+", locked_by = NULL, locked_until = NULL, updated_at = now() "+
+			"WHERE id = $1 AND locked_by = $2 AND status = 'running'",
+		append([]interface{}{job.ID, q.worker}, args...)...,
+	)
+	if err == nil && n == 0 {
+		logging.WithField(ctx, "job_id", job.ID).Warn("lock of job expired before it finished, job may run again")
+	}
+}
+
+// backoff doubles RetryDelay by attempt up to MaxRetryDelay with jitter in [d/2, d]
+func (q *Queue) backoff(attempt int) time.Duration {
+	d := q.options.RetryDelay
+	for i := 1; i < attempt && d < q.options.MaxRetryDelay; i++ {
+		d *= 2
+	}
+	if q.options.MaxRetryDelay > 0 {
+		d = min(d, q.options.MaxRetryDelay)
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}